@@ -0,0 +1,522 @@
+package ast
+
+import "fmt"
+
+// An ApplyFunc is invoked by Apply for each non-nil node n before and/or
+// after the node's children, using a Cursor describing the current node
+// and providing operations on it.
+//
+// The return value of ApplyFunc controls the syntax tree traversal.
+// See Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses a syntax tree recursively, starting with root,
+// and calling pre and post for each node as described below.
+// Apply returns the syntax tree, possibly modified.
+//
+// If pre is not nil, it is called for each node before the node's
+// children are traversed (pre-order). If pre returns false, no
+// children are traversed, and post is not called for that node.
+//
+// If post is not nil, and a prior call of pre didn't return false,
+// post is called for each node after its children are traversed
+// (post-order). If post returns false, traversal is terminated and
+// Apply returns immediately.
+//
+// Only nodes are traversed, not nil children. Children are visited in the
+// same order as Walk visits them. A node replaced by pre has the children
+// of the replacement traversed; a node replaced by post doesn't.
+//
+// Replacing a node with one of the wrong type for its position, for example
+// a Statement where an Expression is expected, panics.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	a := &application{pre: pre, post: post}
+	result = root
+
+	defer func() {
+		if r := recover(); r != nil && r != abort {
+			panic(r)
+		}
+	}()
+
+	a.apply(nil, "Root", nil, root, func(n Node) { result = n })
+	return result
+}
+
+var abort = new(int) // singleton, to signal termination of Apply
+
+// A Cursor describes a node encountered during Apply.
+// Information about the node and its parent is available
+// from the Node, Parent, Name, and Index methods.
+//
+// The methods Replace, Delete, InsertBefore, and InsertAfter
+// can be used to change the syntax tree.
+// The Cursor is only valid during the call of the ApplyFunc.
+type Cursor struct {
+	parent Node
+	name   string
+	iter   *iterator // valid if the node is part of a list
+	node   Node
+	set    func(Node)
+}
+
+// Node returns the current Node.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current Node, or nil for the root.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the parent Node field that contains the current
+// Node. If the parent is a *HashLiteral, the name is "Keys" for a key and
+// "Pairs" for a value.
+func (c *Cursor) Name() string { return c.name }
+
+// Index reports the index >= 0 of the current Node in the list of nodes
+// that contains it, or a value < 0 if the current Node is not part of a
+// list. Hash literal values report the index of their key.
+func (c *Cursor) Index() int {
+	if c.iter != nil {
+		return c.iter.index
+	}
+	return -1
+}
+
+// Replace replaces the current Node with n. When called from pre, Apply
+// goes on to traverse the children of n instead of those of the old node.
+func (c *Cursor) Replace(n Node) {
+	c.set(n)
+	c.node = n
+}
+
+// Delete deletes the current Node from its containing list. If the current
+// Node is a hash literal key, its pair is deleted. If the current Node is
+// not part of a list, Delete panics.
+func (c *Cursor) Delete() {
+	l := c.list("Delete")
+	l.delete(c.iter.index)
+	c.iter.step--
+}
+
+// InsertAfter inserts n after the current Node in its containing list.
+// If the current Node is not part of a list, InsertAfter panics.
+// Apply does not walk n.
+func (c *Cursor) InsertAfter(n Node) {
+	l := c.list("InsertAfter")
+	l.insert(c.iter.index+1, n)
+	c.iter.step++
+}
+
+// InsertBefore inserts n before the current Node in its containing list.
+// If the current Node is not part of a list, InsertBefore panics.
+// Apply will not walk n.
+func (c *Cursor) InsertBefore(n Node) {
+	l := c.list("InsertBefore")
+	l.insert(c.iter.index, n)
+	c.iter.index++
+}
+
+func (c *Cursor) list(op string) nodeList {
+	if c.iter == nil || c.iter.list == nil {
+		panic(fmt.Sprintf("%s node not contained in a list", op))
+	}
+	return c.iter.list
+}
+
+// An iterator tracks the position of the current node in a list while
+// the list is edited.
+type iterator struct {
+	list        nodeList
+	index, step int
+}
+
+type application struct {
+	pre, post ApplyFunc
+	cursor    Cursor
+}
+
+func (a *application) apply(parent Node, name string, iter *iterator, n Node, set func(Node)) {
+	saved := a.cursor
+	a.cursor = Cursor{parent: parent, name: name, iter: iter, node: n, set: set}
+	defer func() { a.cursor = saved }()
+
+	if a.pre != nil && !a.pre(&a.cursor) {
+		return
+	}
+
+	switch n := a.cursor.node.(type) {
+	// Leaves
+	case nil, *IntegerLiteral, *StringLiteral, *Boolean, *NamedType:
+
+	case *Identifier:
+		a.applyType(n, "Type", &n.Type)
+		a.applyExpression(n, "Default", &n.Default)
+
+	// Expressions
+	case *PrefixExpression:
+		a.applyExpression(n, "Right", &n.Right)
+
+	case *InfixExpression:
+		a.applyExpression(n, "Left", &n.Left)
+		a.applyExpression(n, "Right", &n.Right)
+
+	case *IfExpression:
+		a.applyExpression(n, "Condition", &n.Condition)
+		a.applyBlock(n, "Consequence", &n.Consequence)
+		a.applyBlock(n, "Alternative", &n.Alternative)
+
+	case *MatchExpression:
+		a.applyExpression(n, "Value", &n.Value)
+		a.applyList(n, "Arms", matchArmList{&n.Arms})
+
+	case *MatchArm:
+		a.applyPattern(n, "Pattern", &n.Pattern)
+		a.applyExpression(n, "Guard", &n.Guard)
+		a.applyExpression(n, "Body", &n.Body)
+
+	case *FunctionLiteral:
+		a.applyIdentifier(n, "Name", &n.Name)
+		a.applyList(n, "Parameters", patternList{&n.Parameters})
+		a.applyIdentifier(n, "Rest", &n.Rest)
+		a.applyType(n, "ReturnType", &n.ReturnType)
+		a.applyBlock(n, "Body", &n.Body)
+
+	case *MacroLiteral:
+		a.applyList(n, "Parameters", identifierList{&n.Parameters})
+		a.applyBlock(n, "Body", &n.Body)
+
+	case *CallExpression:
+		a.applyExpression(n, "Function", &n.Function)
+		a.applyList(n, "Arguments", expressionList{&n.Arguments})
+
+	case *SpreadExpression:
+		a.applyExpression(n, "Value", &n.Value)
+
+	case *ArrayLiteral:
+		a.applyList(n, "Elements", expressionList{&n.Elements})
+
+	case *IndexExpression:
+		a.applyExpression(n, "Left", &n.Left)
+		a.applyExpression(n, "Index", &n.Index)
+
+	case *MemberExpression:
+		a.applyExpression(n, "Object", &n.Object)
+		a.applyIdentifier(n, "Property", &n.Property)
+
+	case *HashLiteral:
+		a.applyHashPairs(n)
+
+	// Patterns
+	case *ArrayPattern:
+		a.applyList(n, "Elements", patternList{&n.Elements})
+		a.applyIdentifier(n, "Rest", &n.Rest)
+		a.applyExpression(n, "Default", &n.Default)
+
+	case *HashPattern:
+		a.applyList(n, "Pairs", keyPatternList{&n.Pairs})
+		a.applyIdentifier(n, "Rest", &n.Rest)
+		a.applyExpression(n, "Default", &n.Default)
+
+	case *LiteralPattern:
+		a.applyExpression(n, "Value", &n.Value)
+
+	case *KeyPattern:
+		a.applyExpression(n, "Key", &n.Key)
+		a.applyPattern(n, "Value", &n.Value)
+
+	// Statements
+	case *Program:
+		a.applyList(n, "Statements", statementList{&n.Statements})
+
+	case *BlockStatement:
+		a.applyList(n, "Statements", statementList{&n.Statements})
+
+	case *LetStatement:
+		a.applyPattern(n, "Name", &n.Name)
+		a.applyExpression(n, "Value", &n.Value)
+
+	case *ReturnStatement:
+		a.applyExpression(n, "ReturnValue", &n.ReturnValue)
+
+	case *ExpressionStatement:
+		a.applyExpression(n, "Expression", &n.Expression)
+
+	case *ImportStatement:
+		if n.Path != nil {
+			a.apply(n, "Path", nil, n.Path, func(r Node) { n.Path = r.(*StringLiteral) })
+		}
+		a.applyIdentifier(n, "Alias", &n.Alias)
+
+	case *ExportStatement:
+		if n.Statement != nil {
+			a.apply(n, "Statement", nil, n.Statement, func(r Node) { n.Statement = r.(*LetStatement) })
+		}
+
+	case *TestStatement:
+		if n.Name != nil {
+			a.apply(n, "Name", nil, n.Name, func(r Node) { n.Name = r.(*StringLiteral) })
+		}
+		a.applyBlock(n, "Body", &n.Body)
+
+	// Types
+	case *ArrayType:
+		a.applyType(n, "Element", &n.Element)
+
+	case *HashType:
+		a.applyType(n, "Key", &n.Key)
+		a.applyType(n, "Value", &n.Value)
+
+	case *FunctionType:
+		a.applyList(n, "Parameters", typeList{&n.Parameters})
+		a.applyType(n, "ReturnType", &n.ReturnType)
+
+	default:
+		panic(fmt.Sprintf("Apply: unexpected node type %T", n))
+	}
+
+	if a.post != nil && !a.post(&a.cursor) {
+		panic(abort)
+	}
+}
+
+func (a *application) applyExpression(parent Node, name string, field *Expression) {
+	if *field == nil {
+		return
+	}
+	a.apply(parent, name, nil, *field, func(r Node) { *field = asExpression(r) })
+}
+
+func (a *application) applyBlock(parent Node, name string, field **BlockStatement) {
+	if *field == nil {
+		return
+	}
+	a.apply(parent, name, nil, *field, func(r Node) { *field = r.(*BlockStatement) })
+}
+
+func (a *application) applyIdentifier(parent Node, name string, field **Identifier) {
+	if *field == nil {
+		return
+	}
+	a.apply(parent, name, nil, *field, func(r Node) { *field = r.(*Identifier) })
+}
+
+func (a *application) applyPattern(parent Node, name string, field *Pattern) {
+	if *field == nil {
+		return
+	}
+	a.apply(parent, name, nil, *field, func(r Node) { *field = asPattern(r) })
+}
+
+func (a *application) applyType(parent Node, name string, field *TypeExpression) {
+	if *field == nil {
+		return
+	}
+	a.apply(parent, name, nil, *field, func(r Node) { *field = asType(r) })
+}
+
+func (a *application) applyList(parent Node, name string, list nodeList) {
+	iter := &iterator{list: list}
+	for iter.index = 0; iter.index < list.len(); iter.index += iter.step {
+		iter.step = 1
+		i := iter.index
+		if n := list.at(i); n != nil {
+			a.apply(parent, name, iter, n, func(r Node) { list.set(iter.index, r) })
+		}
+	}
+}
+
+// applyHashPairs visits each key and then its value. Keys form a list,
+// so deleting a key deletes its pair; values are single fields.
+func (a *application) applyHashPairs(hash *HashLiteral) {
+	keys := hashKeyList{hash}
+	iter := &iterator{list: keys}
+	for iter.index = 0; iter.index < len(hash.Keys); iter.index += iter.step {
+		iter.step = 1
+		key := hash.Keys[iter.index]
+		if key != nil {
+			a.apply(hash, "Keys", iter, key, func(r Node) { keys.set(iter.index, r) })
+		}
+
+		if iter.step <= 0 {
+			continue // the pair was deleted
+		}
+		key = hash.Keys[iter.index]
+		if value := hash.Pairs[key]; value != nil {
+			a.apply(hash, "Pairs", iter, value, func(r Node) { hash.Pairs[key] = asExpression(r) })
+		}
+	}
+}
+
+func asExpression(n Node) Expression {
+	if n == nil {
+		return nil
+	}
+	return n.(Expression)
+}
+
+func asPattern(n Node) Pattern {
+	if n == nil {
+		return nil
+	}
+	return n.(Pattern)
+}
+
+func asType(n Node) TypeExpression {
+	if n == nil {
+		return nil
+	}
+	return n.(TypeExpression)
+}
+
+// nodeList is a list of child nodes that Cursor can edit in place.
+type nodeList interface {
+	len() int
+	at(i int) Node
+	set(i int, n Node)
+	delete(i int)
+	insert(i int, n Node)
+}
+
+type statementList struct{ s *[]Statement }
+
+func (l statementList) len() int { return len(*l.s) }
+func (l statementList) at(i int) Node {
+	if (*l.s)[i] == nil {
+		return nil
+	}
+	return (*l.s)[i]
+}
+func (l statementList) set(i int, n Node) { (*l.s)[i] = n.(Statement) }
+func (l statementList) delete(i int)      { *l.s = append((*l.s)[:i], (*l.s)[i+1:]...) }
+func (l statementList) insert(i int, n Node) {
+	*l.s = append(*l.s, nil)
+	copy((*l.s)[i+1:], (*l.s)[i:])
+	(*l.s)[i] = n.(Statement)
+}
+
+type expressionList struct{ s *[]Expression }
+
+func (l expressionList) len() int { return len(*l.s) }
+func (l expressionList) at(i int) Node {
+	if (*l.s)[i] == nil {
+		return nil
+	}
+	return (*l.s)[i]
+}
+func (l expressionList) set(i int, n Node) { (*l.s)[i] = asExpression(n) }
+func (l expressionList) delete(i int)      { *l.s = append((*l.s)[:i], (*l.s)[i+1:]...) }
+func (l expressionList) insert(i int, n Node) {
+	*l.s = append(*l.s, nil)
+	copy((*l.s)[i+1:], (*l.s)[i:])
+	(*l.s)[i] = asExpression(n)
+}
+
+type identifierList struct{ s *[]*Identifier }
+
+func (l identifierList) len() int { return len(*l.s) }
+func (l identifierList) at(i int) Node {
+	if (*l.s)[i] == nil {
+		return nil
+	}
+	return (*l.s)[i]
+}
+func (l identifierList) set(i int, n Node) { (*l.s)[i] = n.(*Identifier) }
+func (l identifierList) delete(i int)      { *l.s = append((*l.s)[:i], (*l.s)[i+1:]...) }
+func (l identifierList) insert(i int, n Node) {
+	*l.s = append(*l.s, nil)
+	copy((*l.s)[i+1:], (*l.s)[i:])
+	(*l.s)[i] = n.(*Identifier)
+}
+
+type patternList struct{ s *[]Pattern }
+
+func (l patternList) len() int { return len(*l.s) }
+func (l patternList) at(i int) Node {
+	if (*l.s)[i] == nil {
+		return nil
+	}
+	return (*l.s)[i]
+}
+func (l patternList) set(i int, n Node) { (*l.s)[i] = asPattern(n) }
+func (l patternList) delete(i int)      { *l.s = append((*l.s)[:i], (*l.s)[i+1:]...) }
+func (l patternList) insert(i int, n Node) {
+	*l.s = append(*l.s, nil)
+	copy((*l.s)[i+1:], (*l.s)[i:])
+	(*l.s)[i] = asPattern(n)
+}
+
+type keyPatternList struct{ s *[]*KeyPattern }
+
+func (l keyPatternList) len() int { return len(*l.s) }
+func (l keyPatternList) at(i int) Node {
+	if (*l.s)[i] == nil {
+		return nil
+	}
+	return (*l.s)[i]
+}
+func (l keyPatternList) set(i int, n Node) { (*l.s)[i] = n.(*KeyPattern) }
+func (l keyPatternList) delete(i int)      { *l.s = append((*l.s)[:i], (*l.s)[i+1:]...) }
+func (l keyPatternList) insert(i int, n Node) {
+	*l.s = append(*l.s, nil)
+	copy((*l.s)[i+1:], (*l.s)[i:])
+	(*l.s)[i] = n.(*KeyPattern)
+}
+
+type matchArmList struct{ s *[]*MatchArm }
+
+func (l matchArmList) len() int { return len(*l.s) }
+func (l matchArmList) at(i int) Node {
+	if (*l.s)[i] == nil {
+		return nil
+	}
+	return (*l.s)[i]
+}
+func (l matchArmList) set(i int, n Node) { (*l.s)[i] = n.(*MatchArm) }
+func (l matchArmList) delete(i int)      { *l.s = append((*l.s)[:i], (*l.s)[i+1:]...) }
+func (l matchArmList) insert(i int, n Node) {
+	*l.s = append(*l.s, nil)
+	copy((*l.s)[i+1:], (*l.s)[i:])
+	(*l.s)[i] = n.(*MatchArm)
+}
+
+type typeList struct{ s *[]TypeExpression }
+
+func (l typeList) len() int { return len(*l.s) }
+func (l typeList) at(i int) Node {
+	if (*l.s)[i] == nil {
+		return nil
+	}
+	return (*l.s)[i]
+}
+func (l typeList) set(i int, n Node) { (*l.s)[i] = asType(n) }
+func (l typeList) delete(i int)      { *l.s = append((*l.s)[:i], (*l.s)[i+1:]...) }
+func (l typeList) insert(i int, n Node) {
+	*l.s = append(*l.s, nil)
+	copy((*l.s)[i+1:], (*l.s)[i:])
+	(*l.s)[i] = asType(n)
+}
+
+// hashKeyList edits the keys of a hash literal, keeping Pairs in step.
+// Inserted keys get a nil value.
+type hashKeyList struct{ h *HashLiteral }
+
+func (l hashKeyList) len() int     { return len(l.h.Keys) }
+func (l hashKeyList) at(i int) Node { return l.h.Keys[i] }
+func (l hashKeyList) set(i int, n Node) {
+	old := l.h.Keys[i]
+	key := asExpression(n)
+	value := l.h.Pairs[old]
+	delete(l.h.Pairs, old)
+	l.h.Pairs[key] = value
+	l.h.Keys[i] = key
+}
+func (l hashKeyList) delete(i int) {
+	delete(l.h.Pairs, l.h.Keys[i])
+	l.h.Keys = append(l.h.Keys[:i], l.h.Keys[i+1:]...)
+}
+func (l hashKeyList) insert(i int, n Node) {
+	key := asExpression(n)
+	l.h.Keys = append(l.h.Keys, nil)
+	copy(l.h.Keys[i+1:], l.h.Keys[i:])
+	l.h.Keys[i] = key
+	l.h.Pairs[key] = nil
+}
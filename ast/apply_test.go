@@ -0,0 +1,187 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyCursor(t *testing.T) {
+	type visit struct {
+		node, parent, name string
+		index              int
+	}
+	var visits []visit
+	
+	Apply(testTree(), func(c *Cursor) bool {
+		parent := "<nil>"
+		if c.Parent() != nil {
+			parent = describe(c.Parent())
+		}
+		visits = append(visits, visit{describe(c.Node()), parent, c.Name(), c.Index()})
+		return true
+	}, nil)
+	
+	expected := []visit{
+		{"Program", "<nil>", "Root", -1},
+		{"LetStatement", "Program", "Statements", 0},
+		{"f", "LetStatement", "Name", -1},
+		{"FunctionLiteral", "LetStatement", "Value", -1},
+		{"x", "FunctionLiteral", "Parameters", 0},
+		{"BlockStatement", "FunctionLiteral", "Body", -1},
+		{"ExpressionStatement", "BlockStatement", "Statements", 0},
+		{"IfExpression", "ExpressionStatement", "Expression", -1},
+		{"x", "IfExpression", "Condition", -1},
+		{"BlockStatement", "IfExpression", "Consequence", -1},
+		{"ExpressionStatement", "BlockStatement", "Statements", 0},
+		{"HashLiteral", "ExpressionStatement", "Expression", -1},
+		{`"k"`, "HashLiteral", "Keys", 0},
+		{"x", "HashLiteral", "Pairs", 0},
+		{"BlockStatement", "IfExpression", "Alternative", -1},
+		{"ExpressionStatement", "BlockStatement", "Statements", 0},
+		{"ArrayLiteral", "ExpressionStatement", "Expression", -1},
+		{"x", "ArrayLiteral", "Elements", 0},
+		{"1", "ArrayLiteral", "Elements", 1},
+		{"ExpressionStatement", "Program", "Statements", 1},
+		{"MemberExpression", "ExpressionStatement", "Expression", -1},
+		{"CallExpression", "MemberExpression", "Object", -1},
+		{"f", "CallExpression", "Function", -1},
+		{"2", "CallExpression", "Arguments", 0},
+		{"y", "MemberExpression", "Property", -1},
+	}
+	
+	if !reflect.DeepEqual(visits, expected) {
+		t.Errorf("wrong visits.\nwant=%v\ngot= %v", expected, visits)
+	}
+}
+
+func TestApplyReplace(t *testing.T) {
+	program := testTree()
+	
+	// rename x to y everywhere, including parameters
+	result := Apply(program, func(c *Cursor) bool {
+		if id, ok := c.Node().(*Identifier); ok && id.Value == "x" {
+			c.Replace(ident("y"))
+		}
+		return true
+	}, nil)
+	
+	if result != program {
+		t.Fatalf("Apply returned a different root")
+	}
+	
+	var names []string
+	Inspect(result, func(n Node) bool {
+		if id, ok := n.(*Identifier); ok {
+			names = append(names, id.Value)
+		}
+		return true
+	})
+	
+	expected := []string{"f", "y", "y", "y", "y", "f", "y"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong identifiers after Replace.\nwant=%v\ngot= %v", expected, names)
+	}
+	
+	hash := program.Statements[0].(*LetStatement).Value.(*FunctionLiteral).Body.
+		Statements[0].(*ExpressionStatement).Expression.(*IfExpression).Consequence.
+		Statements[0].(*ExpressionStatement).Expression.(*HashLiteral)
+	if value := hash.Pairs[hash.Keys[0]]; value.(*Identifier).Value != "y" {
+		t.Errorf("hash value not replaced. got=%s", value)
+	}
+}
+
+func TestApplyReplaceRootAndHashKeys(t *testing.T) {
+	key := integer(1)
+	hash := &HashLiteral{Pairs: map[Expression]Expression{key: integer(10)}, Keys: []Expression{key}}
+	
+	result := Apply(hash, nil, func(c *Cursor) bool {
+		if n, ok := c.Node().(*IntegerLiteral); ok {
+			c.Replace(integer(n.Value + 1))
+		}
+		if _, ok := c.Node().(*HashLiteral); ok {
+			c.Replace(&ArrayLiteral{Elements: hash.Keys})
+		}
+		return true
+	})
+	
+	array, ok := result.(*ArrayLiteral)
+	if !ok {
+		t.Fatalf("root not replaced. got=%T", result)
+	}
+	if array.Elements[0].(*IntegerLiteral).Value != 2 {
+		t.Errorf("key not replaced. got=%s", array.Elements[0])
+	}
+	if len(hash.Pairs) != 1 || hash.Pairs[hash.Keys[0]].(*IntegerLiteral).Value != 11 {
+		t.Errorf("pairs not kept in step with keys. got=%v", hash.Pairs)
+	}
+}
+
+func TestApplyDeleteAndInsert(t *testing.T) {
+	array := &ArrayLiteral{Elements: []Expression{integer(1), integer(2), integer(3), integer(4)}}
+	
+	Apply(array, func(c *Cursor) bool {
+		n, ok := c.Node().(*IntegerLiteral)
+		if !ok {
+			return true
+		}
+		switch n.Value {
+		case 1:
+			c.InsertBefore(integer(0))
+		case 2:
+			c.Delete()
+		case 3:
+			c.InsertAfter(integer(5))
+		}
+		return true
+	}, nil)
+	
+	if array.String() != "[0, 1, 3, 5, 4]" {
+		t.Errorf("wrong elements. got=%s", array.String())
+	}
+	
+	first, second := integer(1), integer(2)
+	hash := &HashLiteral{
+		Pairs: map[Expression]Expression{first: integer(10), second: integer(20)},
+		Keys:  []Expression{first, second},
+	}
+	Apply(hash, func(c *Cursor) bool {
+		if c.Node() == first {
+			c.Delete()
+		}
+		return true
+	}, nil)
+	
+	if hash.String() != "{2:20}" {
+		t.Errorf("wrong pairs. got=%s", hash.String())
+	}
+}
+
+func TestApplyStopsWhenPostReturnsFalse(t *testing.T) {
+	var visited []string
+	Apply(testTree(), nil, func(c *Cursor) bool {
+		visited = append(visited, describe(c.Node()))
+		return describe(c.Node()) != "FunctionLiteral"
+	})
+	
+	expected := []string{"f", "x", "x", `"k"`, "x", "HashLiteral", "ExpressionStatement",
+		"BlockStatement", "x", "1", "ArrayLiteral", "ExpressionStatement", "BlockStatement",
+		"IfExpression", "ExpressionStatement", "BlockStatement", "FunctionLiteral"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visits.\nwant=%v\ngot= %v", expected, visited)
+	}
+}
+
+func TestApplyPanicsOnWrongReplacementType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	
+	Apply(&LetStatement{Name: ident("a"), Value: integer(1)}, func(c *Cursor) bool {
+		if _, ok := c.Node().(*IntegerLiteral); ok {
+			c.Replace(&ReturnStatement{})
+		}
+		return true
+	}, nil)
+}
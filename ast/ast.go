@@ -22,6 +22,21 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern is what a let statement or a parameter binds a value to: an
+// Identifier naming the whole value, or an ArrayPattern or HashPattern
+// naming its parts.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// TypeExpression is a type annotation. Annotations are only read by the
+// type checker; the evaluator ignores them.
+type TypeExpression interface {
+	Node
+	typeNode()
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -35,12 +50,19 @@ func (p *Program) TokenLiteral() string {
 }
 
 func (p *Program) String() string {
+	return statementsString(p.Statements)
+}
+
+// statementsString prints stmts one after another, with a semicolon after
+// each expression statement followed by another statement, since the next
+// one could otherwise continue it, as a call for instance.
+func statementsString(stmts []Statement) string {
 	var out strings.Builder
 	
-	for _, s := range p.Statements {
-		_, err := out.WriteString(s.String())
-		if err != nil {
-			fmt.Printf("Error: %s", err)
+	for i, s := range stmts {
+		out.WriteString(s.String())
+		if _, ok := s.(*ExpressionStatement); ok && i < len(stmts)-1 {
+			out.WriteString(";")
 		}
 	}
 	
@@ -49,32 +71,38 @@ func (p *Program) String() string {
 
 // Block statement
 type BlockStatement struct {
-	Token token.Token // the } token
+	Token token.Token // the { token
 	Statements []Statement
+	Close token.Token // the } token
 }
 
 func (bs *BlockStatement) statementNode() {} 
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
 func (bs *BlockStatement) String() string {
-	var out strings.Builder
-	
-	for _, s := range bs.Statements {
-		out.WriteString(s.String())
-	}
-	
-	return out.String()
+	return statementsString(bs.Statements)
  }
 
+// braced prints a block with the braces around it.
+func braced(bs *BlockStatement) string {
+	if len(bs.Statements) == 0 {
+		return "{}"
+	}
+	return "{ " + bs.String() + " }"
+}
+
 // Let
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name *Identifier
+	Token token.Token // the token.LET or token.CONST token
+	Name Pattern
 	Value Expression
  }
  
  func (ls *LetStatement) statementNode() {} 
  func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
  func (ls *LetStatement) String() string {
+ 	 if ls.IsDeclaration() {
+ 	 	return ls.Value.String()
+ 	 }
  	 var out strings.Builder
  	 
  	 out.WriteString(ls.TokenLiteral() + " ")
@@ -89,15 +117,40 @@ type LetStatement struct {
  	 return out.String()
  }
  
+// IsDeclaration reports whether ls was written as a function declaration,
+// fn name() {}, which is short for binding name to a function literal of
+// that name. let name = fn name() {}; binds the same but is not one.
+func (ls *LetStatement) IsDeclaration() bool {
+	return ls.Token.Type == token.FUNCTION
+}
+
+// IsConst reports whether ls is const name = value, whose names cannot be
+// bound again in the same scope.
+func (ls *LetStatement) IsConst() bool {
+	return ls.Token.Type == token.CONST
+}
+
  // Identifier
  type Identifier struct {
  	Token token.Token
  	Value string
+ 	Type  TypeExpression // annotation of a let name or parameter, if any
+ 	Default Expression // value of a parameter left out of a call, if any
  }
  
 func (i *Identifier) expressionNode() {}  
+func (i *Identifier) patternNode() {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string { return i.Value }
+func (i *Identifier) String() string {
+	out := i.Value
+	if i.Type != nil {
+		out += ": " + i.Type.String()
+	}
+	if i.Default != nil {
+		out += " = " + i.Default.String()
+	}
+	return out
+}
 
 // Integer Literal
 type IntegerLiteral struct {
@@ -119,7 +172,7 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode() {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
-func (sl *StringLiteral) String() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string { return `"` + sl.Value + `"` }
 
 
 // Prefix operators
@@ -225,23 +278,61 @@ func (ie *IfExpression) TokenLiteral() string {return ie.Token.Literal}
 func (ie *IfExpression) String() string {
 	var out strings.Builder
 	
-	out.WriteString("if")
+	out.WriteString("if (")
 	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
-	out.WriteString(ie.Consequence.String())
+	out.WriteString(") ")
+	out.WriteString(braced(ie.Consequence))
 	
 	if ie.Alternative != nil {
-		out.WriteString("else ")
-		out.WriteString(ie.Alternative.String())
+		out.WriteString(" else ")
+		out.WriteString(braced(ie.Alternative))
 	}
 	
 	return out.String()
 }
 
+// MatchExpression evaluates to the body of the first of its arms whose
+// pattern matches Value and whose guard, if any, holds.
+type MatchExpression struct {
+	Token token.Token // the 'match' token
+	Value Expression
+	Arms  []*MatchArm
+	Close token.Token // the } token after the arms
+}
+
+func (me *MatchExpression) expressionNode() {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	return "match (" + me.Value.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
+// MatchArm is one case of a match: pattern [if guard] => body.
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // nil unless written with if
+	Body    Expression
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Pattern.TokenLiteral() }
+func (ma *MatchArm) String() string {
+	out := ma.Pattern.String()
+	if ma.Guard != nil {
+		out += " if " + ma.Guard.String()
+	}
+	return out + " => " + ma.Body.String()
+}
+
 // Function literal
 type FunctionLiteral struct {
 	Token token.Token
-	Parameters []*Identifier
+	Name *Identifier // nil for an anonymous function
+	Parameters []Pattern
+	Rest *Identifier // collects the arguments past Parameters, if declared with ...
+	ReturnType TypeExpression // nil unless annotated with ->
 	Body *BlockStatement
 }
 
@@ -250,25 +341,172 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out strings.Builder
 	
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
-	
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != nil {
+		out.WriteString(" " + fl.Name.Value)
+	}
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ","))
+	out.WriteString(ParameterList(fl.Parameters, fl.Rest))
 	out.WriteString(") ")
-	out.WriteString(fl.Body.String())
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
+	out.WriteString(braced(fl.Body))
 
 	return out.String()
 }
 
+// ParameterList prints the parameters of a function, or the elements of
+// an array pattern, separated by commas, with the rest parameter, if any,
+// last.
+func ParameterList(params []Pattern, rest *Identifier) string {
+	list := []string{}
+	for _, p := range params {
+		list = append(list, p.String())
+	}
+	if rest != nil {
+		list = append(list, "..."+rest.String())
+	}
+	return strings.Join(list, ", ")
+}
+
+// ArrayPattern binds the elements of an array, as in let [a, b] = pair.
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+	Rest     *Identifier // collects the elements past Elements, if any
+	Close    token.Token // the ']' token
+	Default  Expression  // value of a missing element or parameter, if any
+}
+
+func (ap *ArrayPattern) patternNode() {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	out := "[" + ParameterList(ap.Elements, ap.Rest) + "]"
+	if ap.Default != nil {
+		out += " = " + ap.Default.String()
+	}
+	return out
+}
+
+// HashPattern binds values of a hash by their keys, as in
+// let {name, age: years} = person.
+type HashPattern struct {
+	Token   token.Token // the '{' token
+	Pairs   []*KeyPattern
+	Rest    *Identifier // collects the pairs with other keys, if any
+	Close   token.Token // the '}' token
+	Default Expression  // value of a missing element or parameter, if any
+}
+
+func (hp *HashPattern) patternNode() {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		pairs = append(pairs, pair.String())
+	}
+	if hp.Rest != nil {
+		pairs = append(pairs, "..."+hp.Rest.String())
+	}
+	out := "{" + strings.Join(pairs, ", ") + "}"
+	if hp.Default != nil {
+		out += " = " + hp.Default.String()
+	}
+	return out
+}
+
+// KeyPattern binds the value under Key in a hash to Value. Key is an
+// Identifier standing for its name as a string, or a StringLiteral, and
+// {name} is short for {name: name}.
+type KeyPattern struct {
+	Key   Expression
+	Value Pattern
+}
+
+func (kp *KeyPattern) TokenLiteral() string { return kp.Key.TokenLiteral() }
+func (kp *KeyPattern) String() string {
+	if kp.IsShorthand() {
+		return kp.Value.String()
+	}
+	return kp.Key.String() + ": " + kp.Value.String()
+}
+
+// IsShorthand reports whether kp is written {name}, binding the value
+// under the key name to the same name.
+func (kp *KeyPattern) IsShorthand() bool {
+	key, ok := kp.Key.(*Identifier)
+	name, named := kp.Value.(*Identifier)
+	return ok && named && key.Value == name.Value && name.Type == nil
+}
+
+// LiteralPattern matches values equal to an integer, string or boolean
+// literal. It only appears in the arms of a match.
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) String() string {
+	return lp.Value.String()
+}
+
+// KeyName returns the string a key of a KeyPattern stands for.
+func KeyName(key Expression) string {
+	switch key := key.(type) {
+	case *Identifier:
+		return key.Value
+	case *StringLiteral:
+		return key.Value
+	}
+	return key.String()
+}
+
+// DefaultOf returns the default of p, what it binds when there is no
+// value for it, or nil if it has none.
+func DefaultOf(p Pattern) Expression {
+	switch p := p.(type) {
+	case *Identifier:
+		return p.Default
+	case *ArrayPattern:
+		return p.Default
+	case *HashPattern:
+		return p.Default
+	}
+	return nil
+}
+
+// PatternNames returns the identifiers p binds, in source order.
+func PatternNames(p Pattern) []*Identifier {
+	names := []*Identifier{}
+	switch p := p.(type) {
+	case *Identifier:
+		names = append(names, p)
+	case *ArrayPattern:
+		for _, el := range p.Elements {
+			names = append(names, PatternNames(el)...)
+		}
+		if p.Rest != nil {
+			names = append(names, p.Rest)
+		}
+	case *HashPattern:
+		for _, pair := range p.Pairs {
+			names = append(names, PatternNames(pair.Value)...)
+		}
+		if p.Rest != nil {
+			names = append(names, p.Rest)
+		}
+	}
+	return names
+}
+
 // Call Expressions
 type CallExpression struct {
 	Token token.Token
 	Function Expression // Identifier or FunctionLiteral
 	Arguments []Expression
+	Close token.Token // the ) token
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -289,11 +527,23 @@ func (ce *CallExpression) String() string {
 	return out.String()	
 }
 
+// SpreadExpression passes the elements of an array as separate arguments
+// of a call: f(...args).
+type SpreadExpression struct {
+	Token token.Token // the ... token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string { return "..." + se.Value.String() }
+
 
 // Array
 type ArrayLiteral struct {
 	Token token.Token
 	Elements []Expression
+	Close token.Token // the ] token
 }
 func (al *ArrayLiteral) expressionNode() {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
@@ -317,6 +567,7 @@ type IndexExpression struct {
 	Token token.Token
 	Left  Expression
 	Index Expression
+	Close token.Token // the ] token
 }
 func (ie *IndexExpression) expressionNode() {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
@@ -328,6 +579,8 @@ func (ie *IndexExpression) String() string {
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression // keys of Pairs in source order
+	Close token.Token  // the } token
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -336,8 +589,8 @@ func (hl *HashLiteral) String() string {
 	var out strings.Builder
 	
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String() + ":" + value.String() )
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String() + ":" + hl.Pairs[key].String())
 	}
 	
 	out.WriteString("{")
@@ -347,3 +600,142 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 }
 
+// Import
+type ImportStatement struct {
+	Token token.Token // the token.IMPORT token
+	Path  *StringLiteral
+	Alias *Identifier // nil unless the import uses "as"
+}
+
+func (is *ImportStatement) statementNode() {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	var out strings.Builder
+	
+	out.WriteString(is.TokenLiteral() + " ")
+	out.WriteString(`"` + is.Path.Value + `"`)
+	
+	if is.Alias != nil {
+		out.WriteString(" as " + is.Alias.String())
+	}
+	out.WriteString(";")
+	
+	return out.String()
+}
+
+// Test
+type TestStatement struct {
+	Token token.Token // the "test" identifier token
+	Name  *StringLiteral
+	Body  *BlockStatement
+}
+
+func (ts *TestStatement) statementNode() {}
+func (ts *TestStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TestStatement) String() string {
+	return ts.TokenLiteral() + ` "` + ts.Name.Value + `" ` + braced(ts.Body)
+}
+
+// Export
+type ExportStatement struct {
+	Token     token.Token // the token.EXPORT token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode() {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// Member access
+type MemberExpression struct {
+	Token    token.Token // the . token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return fmt.Sprintf("(%s.%s)", me.Object.String(), me.Property.String())
+}
+
+// Macro literal
+type MacroLiteral struct {
+	Token      token.Token // the 'macro' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out strings.Builder
+	
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+	
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(braced(ml.Body))
+	
+	return out.String()
+}
+
+// Named type, e.g. int or string
+type NamedType struct {
+	Token token.Token // the token.IDENT token
+	Name  string
+}
+
+func (nt *NamedType) typeNode() {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string { return nt.Name }
+
+// Array type, e.g. [int]
+type ArrayType struct {
+	Token   token.Token // the [ token
+	Element TypeExpression
+	Close   token.Token // the ] token
+}
+
+func (at *ArrayType) typeNode() {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string { return "[" + at.Element.String() + "]" }
+
+// Hash type, e.g. {string: int}
+type HashType struct {
+	Token token.Token // the { token
+	Key   TypeExpression
+	Value TypeExpression
+	Close token.Token // the } token
+}
+
+func (ht *HashType) typeNode() {}
+func (ht *HashType) TokenLiteral() string { return ht.Token.Literal }
+func (ht *HashType) String() string {
+	return "{" + ht.Key.String() + ": " + ht.Value.String() + "}"
+}
+
+// Function type, e.g. fn(int, string) -> bool
+type FunctionType struct {
+	Token      token.Token // the 'fn' token
+	Parameters []TypeExpression
+	ReturnType TypeExpression
+}
+
+func (ft *FunctionType) typeNode() {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	params := []string{}
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+	
+	return "fn(" + strings.Join(params, ", ") + ") -> " + ft.ReturnType.String()
+}
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strings"
 	"testing"
 	"github.com/OlyaIvanovs/interpreter_in_go/token"
 )
@@ -25,4 +26,72 @@ func TestString(t *testing.T) {
 	if program.String() != "let myVar = anotherVar;" {
 		t.Errorf("program.String() wrong.got=%q", program.String())
 	}
-}
\ No newline at end of file
+}
+func TestIsDeclaration(t *testing.T) {
+	named := func(name string) *FunctionLiteral {
+		return &FunctionLiteral{
+			Token: token.Token{Type: token.FUNCTION, Literal: "fn"},
+			Name:  &Identifier{Value: name},
+			Body:  &BlockStatement{},
+		}
+	}
+	let := token.Token{Type: token.LET, Literal: "let"}
+	fn := token.Token{Type: token.FUNCTION, Literal: "fn"}
+	tests := []struct {
+		token    token.Token
+		value    Expression
+		expected bool
+		str      string
+	}{
+		{fn, named("f"), true, "fn f() {}"},
+		{let, named("f"), false, "let f = fn f() {};"},
+		{let, named("g"), false, "let f = fn g() {};"},
+		{let, &FunctionLiteral{Token: fn, Body: &BlockStatement{}}, false, "let f = fn() {};"},
+		{let, &Identifier{Value: "g"}, false, "let f = g;"},
+	}
+
+	for _, tt := range tests {
+		let := &LetStatement{
+			Token: tt.token,
+			Name:  &Identifier{Value: "f"},
+			Value: tt.value,
+		}
+		if let.IsDeclaration() != tt.expected {
+			t.Errorf("IsDeclaration() of %q wrong. want=%t", tt.str, tt.expected)
+		}
+		if let.String() != tt.str {
+			t.Errorf("String() wrong. want=%q, got=%q", tt.str, let.String())
+		}
+	}
+}
+
+func TestPatternString(t *testing.T) {
+	ident := func(name string) *Identifier { return &Identifier{Value: name} }
+	pattern := &ArrayPattern{
+		Elements: []Pattern{
+			ident("a"),
+			&HashPattern{
+				Pairs: []*KeyPattern{
+					{Key: ident("name"), Value: ident("name")},
+					{Key: ident("age"), Value: &Identifier{Value: "years", Default: &IntegerLiteral{Token: token.Token{Literal: "0"}}}},
+					{Key: &StringLiteral{Token: token.Token{Literal: "x y"}, Value: "x y"}, Value: ident("xy")},
+				},
+				Rest: ident("other"),
+			},
+		},
+		Rest: ident("rest"),
+	}
+
+	expected := `[a, {name, age: years = 0, "x y": xy, ...other}, ...rest]`
+	if pattern.String() != expected {
+		t.Errorf("String() wrong. want=%q, got=%q", expected, pattern.String())
+	}
+
+	names := []string{}
+	for _, name := range PatternNames(pattern) {
+		names = append(names, name.Value)
+	}
+	if got := strings.Join(names, " "); got != "a name years xy other rest" {
+		t.Errorf("PatternNames() wrong. got=%q", got)
+	}
+}
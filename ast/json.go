@@ -0,0 +1,868 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
+
+// The JSON schema written by ToJSON and read by FromJSON represents each
+// node as an object whose "kind" is the name of its Go type, e.g.
+//
+//	{"kind": "InfixExpression", "pos": {"line": 1, "column": 3},
+//	 "span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 6}},
+//	 "left": {...}, "operator": "+", "right": {...}}
+//
+// "pos" is the line and column where the node's token starts, and "span"
+// runs from the start of the node's first token to just after its last, as
+// StartOf and EndOf find them. Nodes closed by a bracket or brace have the
+// position of that token as "close", after their other fields. All three
+// are left out for Program and for nodes that weren't produced by the
+// parser. The
+// other fields are the node's fields in lowerCamelCase: child nodes are
+// objects, lists of nodes are arrays and missing children are null, except
+// for type annotations ("type" of an Identifier and "returnType" of a
+// FunctionLiteral), defaults ("default" of an Identifier, ArrayPattern or
+// HashPattern), function names ("name" of a FunctionLiteral) and rest
+// parameters ("rest" of a FunctionLiteral, ArrayPattern or HashPattern),
+// which only appear when written. Hash literal pairs are an array of
+// {"key": ..., "value": ...} objects in source order; the pairs of a
+// HashPattern are KeyPattern nodes and the arms of a MatchExpression are
+// MatchArm nodes, whose "guard" only appears when written. A LetStatement
+// written with const has "const": true, and one written as a function
+// declaration has "declaration": true.
+
+// ToJSON encodes the tree rooted at node.
+func ToJSON(node Node) ([]byte, error) {
+	e := &encoder{}
+	obj := e.node(node)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return json.Marshal(obj)
+}
+
+// FromJSON decodes a tree encoded by ToJSON. Tokens are rebuilt from the
+// node kinds and fields, so the String() of the result is the same as the
+// String() of the encoded tree.
+func FromJSON(data []byte) (Node, error) {
+	return decodeNode(data)
+}
+
+// jsonObject is a JSON object that keeps its fields in the order written.
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+	for i, f := range o {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString(strconv.Quote(f.name))
+		out.WriteString(":")
+		out.Write(value)
+	}
+	out.WriteString("}")
+
+	return out.Bytes(), nil
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPos `json:"start"`
+	End   jsonPos `json:"end"`
+}
+
+// encoder keeps the first error met so the node cases read as a list of
+// fields.
+type encoder struct {
+	err error
+}
+
+func (e *encoder) node(node Node) interface{} {
+	if isNilNode(node) {
+		return nil
+	}
+
+	obj := jsonObject{{"kind", kindOf(node)}}
+	if tok, ok := TokenOf(node); ok && tok.Line > 0 {
+		obj = append(obj, jsonField{"pos", jsonPos{tok.Line, tok.Column}})
+		startLine, startColumn, _ := StartOf(node)
+		endLine, endColumn, _ := EndOf(node)
+		obj = append(obj, jsonField{"span", jsonSpan{jsonPos{startLine, startColumn}, jsonPos{endLine, endColumn}}})
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		obj = append(obj, jsonField{"statements", e.statements(n.Statements)})
+	case *BlockStatement:
+		obj = append(obj, jsonField{"statements", e.statements(n.Statements)})
+	case *LetStatement:
+		if n.IsConst() {
+			obj = append(obj, jsonField{"const", true})
+		}
+		if n.IsDeclaration() {
+			obj = append(obj, jsonField{"declaration", true})
+		}
+		obj = append(obj, jsonField{"name", e.node(n.Name)}, jsonField{"value", e.node(n.Value)})
+	case *ArrayPattern:
+		obj = append(obj, jsonField{"elements", e.patterns(n.Elements)})
+		obj = e.restAndDefault(obj, n.Rest, n.Default)
+	case *HashPattern:
+		pairs := make([]interface{}, len(n.Pairs))
+		for i, pair := range n.Pairs {
+			pairs[i] = e.node(pair)
+		}
+		obj = append(obj, jsonField{"pairs", pairs})
+		obj = e.restAndDefault(obj, n.Rest, n.Default)
+	case *KeyPattern:
+		obj = append(obj, jsonField{"key", e.node(n.Key)}, jsonField{"value", e.node(n.Value)})
+	case *LiteralPattern:
+		obj = append(obj, jsonField{"value", e.node(n.Value)})
+	case *ReturnStatement:
+		obj = append(obj, jsonField{"returnValue", e.node(n.ReturnValue)})
+	case *ExpressionStatement:
+		obj = append(obj, jsonField{"expression", e.node(n.Expression)})
+	case *ImportStatement:
+		obj = append(obj, jsonField{"path", e.node(n.Path)}, jsonField{"alias", e.node(n.Alias)})
+	case *ExportStatement:
+		obj = append(obj, jsonField{"statement", e.node(n.Statement)})
+	case *TestStatement:
+		obj = append(obj, jsonField{"name", e.node(n.Name)}, jsonField{"body", e.node(n.Body)})
+	case *Identifier:
+		obj = append(obj, jsonField{"value", n.Value})
+		if n.Type != nil {
+			obj = append(obj, jsonField{"type", e.node(n.Type)})
+		}
+		if n.Default != nil {
+			obj = append(obj, jsonField{"default", e.node(n.Default)})
+		}
+	case *IntegerLiteral:
+		obj = append(obj, jsonField{"value", n.Value})
+	case *StringLiteral:
+		obj = append(obj, jsonField{"value", n.Value})
+	case *Boolean:
+		obj = append(obj, jsonField{"value", n.Value})
+	case *PrefixExpression:
+		obj = append(obj, jsonField{"operator", n.Operator}, jsonField{"right", e.node(n.Right)})
+	case *InfixExpression:
+		obj = append(obj, jsonField{"left", e.node(n.Left)}, jsonField{"operator", n.Operator},
+			jsonField{"right", e.node(n.Right)})
+	case *IfExpression:
+		obj = append(obj, jsonField{"condition", e.node(n.Condition)},
+			jsonField{"consequence", e.node(n.Consequence)}, jsonField{"alternative", e.node(n.Alternative)})
+	case *MatchExpression:
+		arms := make([]interface{}, len(n.Arms))
+		for i, arm := range n.Arms {
+			arms[i] = e.node(arm)
+		}
+		obj = append(obj, jsonField{"value", e.node(n.Value)}, jsonField{"arms", arms})
+	case *MatchArm:
+		obj = append(obj, jsonField{"pattern", e.node(n.Pattern)})
+		if n.Guard != nil {
+			obj = append(obj, jsonField{"guard", e.node(n.Guard)})
+		}
+		obj = append(obj, jsonField{"body", e.node(n.Body)})
+	case *FunctionLiteral:
+		if n.Name != nil {
+			obj = append(obj, jsonField{"name", e.node(n.Name)})
+		}
+		obj = append(obj, jsonField{"parameters", e.patterns(n.Parameters)})
+		if n.Rest != nil {
+			obj = append(obj, jsonField{"rest", e.node(n.Rest)})
+		}
+		if n.ReturnType != nil {
+			obj = append(obj, jsonField{"returnType", e.node(n.ReturnType)})
+		}
+		obj = append(obj, jsonField{"body", e.node(n.Body)})
+	case *MacroLiteral:
+		obj = append(obj, jsonField{"parameters", e.identifiers(n.Parameters)}, jsonField{"body", e.node(n.Body)})
+	case *CallExpression:
+		obj = append(obj, jsonField{"function", e.node(n.Function)}, jsonField{"arguments", e.expressions(n.Arguments)})
+	case *SpreadExpression:
+		obj = append(obj, jsonField{"value", e.node(n.Value)})
+	case *ArrayLiteral:
+		obj = append(obj, jsonField{"elements", e.expressions(n.Elements)})
+	case *IndexExpression:
+		obj = append(obj, jsonField{"left", e.node(n.Left)}, jsonField{"index", e.node(n.Index)})
+	case *MemberExpression:
+		obj = append(obj, jsonField{"object", e.node(n.Object)}, jsonField{"property", e.node(n.Property)})
+	case *HashLiteral:
+		pairs := []interface{}{}
+		for _, key := range n.Keys {
+			pairs = append(pairs, jsonObject{{"key", e.node(key)}, {"value", e.node(n.Pairs[key])}})
+		}
+		obj = append(obj, jsonField{"pairs", pairs})
+	case *NamedType:
+		obj = append(obj, jsonField{"name", n.Name})
+	case *ArrayType:
+		obj = append(obj, jsonField{"element", e.node(n.Element)})
+	case *HashType:
+		obj = append(obj, jsonField{"key", e.node(n.Key)}, jsonField{"value", e.node(n.Value)})
+	case *FunctionType:
+		params := make([]interface{}, len(n.Parameters))
+		for i, param := range n.Parameters {
+			params[i] = e.node(param)
+		}
+		obj = append(obj, jsonField{"parameters", params}, jsonField{"returnType", e.node(n.ReturnType)})
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("ast: cannot encode node of type %T", node)
+		}
+		return nil
+	}
+
+	if tok, ok := closeOf(node); ok && tok.Line > 0 {
+		obj = append(obj, jsonField{"close", jsonPos{tok.Line, tok.Column}})
+	}
+	return obj
+}
+
+func (e *encoder) statements(list []Statement) []interface{} {
+	out := make([]interface{}, len(list))
+	for i, stmt := range list {
+		out[i] = e.node(stmt)
+	}
+	return out
+}
+
+func (e *encoder) expressions(list []Expression) []interface{} {
+	out := make([]interface{}, len(list))
+	for i, exp := range list {
+		out[i] = e.node(exp)
+	}
+	return out
+}
+
+func (e *encoder) patterns(list []Pattern) []interface{} {
+	out := make([]interface{}, len(list))
+	for i, p := range list {
+		out[i] = e.node(p)
+	}
+	return out
+}
+
+// restAndDefault adds the fields a pattern only has when written.
+func (e *encoder) restAndDefault(obj jsonObject, rest *Identifier, def Expression) jsonObject {
+	if rest != nil {
+		obj = append(obj, jsonField{"rest", e.node(rest)})
+	}
+	if def != nil {
+		obj = append(obj, jsonField{"default", e.node(def)})
+	}
+	return obj
+}
+
+func (e *encoder) identifiers(list []*Identifier) []interface{} {
+	out := make([]interface{}, len(list))
+	for i, ident := range list {
+		out[i] = e.node(ident)
+	}
+	return out
+}
+
+func decodeNode(data []byte) (Node, error) {
+	if isJSONNull(data) {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("ast: %s", err)
+	}
+
+	d := &decoder{fields: fields}
+	kind := d.string("kind")
+	if d.err != nil {
+		return nil, fmt.Errorf("ast: %s", d.err)
+	}
+	if raw, ok := fields["pos"]; ok && !isJSONNull(raw) {
+		if err := json.Unmarshal(raw, &d.pos); err != nil {
+			return nil, fmt.Errorf("ast: %s: bad pos: %s", kind, err)
+		}
+	}
+	if raw, ok := fields["close"]; ok && !isJSONNull(raw) {
+		d.close = &jsonPos{}
+		if err := json.Unmarshal(raw, d.close); err != nil {
+			return nil, fmt.Errorf("ast: %s: bad close: %s", kind, err)
+		}
+	}
+
+	var node Node
+	switch kind {
+	case "Program":
+		node = &Program{Statements: d.statements("statements")}
+	case "BlockStatement":
+		node = &BlockStatement{Token: d.token(token.LBRACE, "{"), Statements: d.statements("statements"),
+			Close: d.closing(token.RBRACE, "}")}
+	case "LetStatement":
+		stmt := &LetStatement{Token: d.token(token.LET, "let"), Name: d.pattern("name"), Value: d.expression("value")}
+		if _, ok := d.fields["const"]; ok && d.boolean("const") {
+			stmt.Token = d.token(token.CONST, "const")
+		}
+		if _, ok := d.fields["declaration"]; ok && d.boolean("declaration") {
+			stmt.Token = d.token(token.FUNCTION, "fn")
+		}
+		node = stmt
+	case "ArrayPattern":
+		node = &ArrayPattern{Token: d.token(token.LBRACKET, "["), Elements: d.patterns("elements"),
+			Rest: d.identifier("rest"), Close: d.closing(token.RBRACKET, "]"), Default: d.expression("default")}
+	case "HashPattern":
+		hash := &HashPattern{Token: d.token(token.LBRACE, "{"), Rest: d.identifier("rest"),
+			Close: d.closing(token.RBRACE, "}"), Default: d.expression("default")}
+		for _, raw := range d.list("pairs") {
+			n := d.decode(raw)
+			pair, ok := n.(*KeyPattern)
+			if !ok {
+				d.fail("expected a KeyPattern, got %s", kindOf(n))
+				continue
+			}
+			hash.Pairs = append(hash.Pairs, pair)
+		}
+		node = hash
+	case "KeyPattern":
+		node = &KeyPattern{Key: d.expression("key"), Value: d.pattern("value")}
+	case "LiteralPattern":
+		node = &LiteralPattern{Value: d.expression("value")}
+	case "MatchExpression":
+		match := &MatchExpression{Token: d.token(token.MATCH, "match"), Value: d.expression("value"),
+			Close: d.closing(token.RBRACE, "}")}
+		for _, raw := range d.list("arms") {
+			n := d.decode(raw)
+			arm, ok := n.(*MatchArm)
+			if !ok {
+				d.fail("expected a MatchArm, got %s", kindOf(n))
+				continue
+			}
+			match.Arms = append(match.Arms, arm)
+		}
+		node = match
+	case "MatchArm":
+		node = &MatchArm{Pattern: d.pattern("pattern"), Guard: d.expression("guard"), Body: d.expression("body")}
+	case "ReturnStatement":
+		node = &ReturnStatement{Token: d.token(token.RETURN, "return"), ReturnValue: d.expression("returnValue")}
+	case "ExpressionStatement":
+		exp := d.expression("expression")
+		tok, _ := TokenOf(exp)
+		node = &ExpressionStatement{Token: d.token(tok.Type, tok.Literal), Expression: exp}
+	case "ImportStatement":
+		stmt := &ImportStatement{Token: d.token(token.IMPORT, "import"), Alias: d.identifier("alias")}
+		if path, ok := d.node("path").(*StringLiteral); ok {
+			stmt.Path = path
+		} else {
+			d.fail("path must be a StringLiteral")
+		}
+		node = stmt
+	case "ExportStatement":
+		stmt := &ExportStatement{Token: d.token(token.EXPORT, "export")}
+		if let, ok := d.node("statement").(*LetStatement); ok {
+			stmt.Statement = let
+		} else {
+			d.fail("statement must be a LetStatement")
+		}
+		node = stmt
+	case "TestStatement":
+		stmt := &TestStatement{Token: d.token(token.IDENT, "test"), Body: d.block("body")}
+		if name, ok := d.node("name").(*StringLiteral); ok {
+			stmt.Name = name
+		} else {
+			d.fail("name must be a StringLiteral")
+		}
+		node = stmt
+	case "Identifier":
+		value := d.string("value")
+		node = &Identifier{Token: d.token(token.IDENT, value), Value: value, Type: d.typ("type"),
+			Default: d.expression("default")}
+	case "IntegerLiteral":
+		value := d.integer("value")
+		node = &IntegerLiteral{Token: d.token(token.INT, strconv.FormatInt(value, 10)), Value: value}
+	case "StringLiteral":
+		value := d.string("value")
+		node = &StringLiteral{Token: d.token(token.STRING, value), Value: value}
+	case "Boolean":
+		value := d.boolean("value")
+		if value {
+			node = &Boolean{Token: d.token(token.TRUE, "true"), Value: true}
+		} else {
+			node = &Boolean{Token: d.token(token.FALSE, "false"), Value: false}
+		}
+	case "PrefixExpression":
+		operator := d.string("operator")
+		node = &PrefixExpression{Token: d.token(token.TokenType(operator), operator), Operator: operator,
+			Right: d.expression("right")}
+	case "InfixExpression":
+		operator := d.string("operator")
+		node = &InfixExpression{Token: d.token(token.TokenType(operator), operator), Left: d.expression("left"),
+			Operator: operator, Right: d.expression("right")}
+	case "IfExpression":
+		node = &IfExpression{Token: d.token(token.IF, "if"), Condition: d.expression("condition"),
+			Consequence: d.block("consequence"), Alternative: d.block("alternative")}
+	case "FunctionLiteral":
+		node = &FunctionLiteral{Token: d.token(token.FUNCTION, "fn"), Name: d.identifier("name"),
+			Parameters: d.patterns("parameters"),
+			Rest: d.identifier("rest"), ReturnType: d.typ("returnType"), Body: d.block("body")}
+	case "MacroLiteral":
+		node = &MacroLiteral{Token: d.token(token.MACRO, "macro"), Parameters: d.identifiers("parameters"),
+			Body: d.block("body")}
+	case "CallExpression":
+		node = &CallExpression{Token: d.token(token.LPAREN, "("), Function: d.expression("function"),
+			Arguments: d.expressions("arguments"), Close: d.closing(token.RPAREN, ")")}
+	case "SpreadExpression":
+		node = &SpreadExpression{Token: d.token(token.ELLIPSIS, "..."), Value: d.expression("value")}
+	case "ArrayLiteral":
+		node = &ArrayLiteral{Token: d.token(token.LBRACKET, "["), Elements: d.expressions("elements"),
+			Close: d.closing(token.RBRACKET, "]")}
+	case "IndexExpression":
+		node = &IndexExpression{Token: d.token(token.LBRACKET, "["), Left: d.expression("left"),
+			Index: d.expression("index"), Close: d.closing(token.RBRACKET, "]")}
+	case "MemberExpression":
+		node = &MemberExpression{Token: d.token(token.DOT, "."), Object: d.expression("object"),
+			Property: d.identifier("property")}
+	case "HashLiteral":
+		node = d.hash()
+	case "NamedType":
+		name := d.string("name")
+		node = &NamedType{Token: d.token(token.IDENT, name), Name: name}
+	case "ArrayType":
+		node = &ArrayType{Token: d.token(token.LBRACKET, "["), Element: d.typ("element"),
+			Close: d.closing(token.RBRACKET, "]")}
+	case "HashType":
+		node = &HashType{Token: d.token(token.LBRACE, "{"), Key: d.typ("key"), Value: d.typ("value"),
+			Close: d.closing(token.RBRACE, "}")}
+	case "FunctionType":
+		fn := &FunctionType{Token: d.token(token.FUNCTION, "fn"), ReturnType: d.typ("returnType")}
+		for _, raw := range d.list("parameters") {
+			fn.Parameters = append(fn.Parameters, d.asType(d.decode(raw)))
+		}
+		node = fn
+	default:
+		return nil, fmt.Errorf("ast: unknown node kind %q", kind)
+	}
+
+	if d.err != nil {
+		return nil, fmt.Errorf("ast: %s: %s", kind, d.err)
+	}
+	return node, nil
+}
+
+// decoder reads the fields of one node, keeping the first error met.
+type decoder struct {
+	fields map[string]json.RawMessage
+	pos    jsonPos
+	close  *jsonPos // nil if the node has no "close"
+	err    error
+}
+
+func (d *decoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (d *decoder) token(tokenType token.TokenType, literal string) token.Token {
+	return token.Token{Type: tokenType, Literal: literal, Line: d.pos.Line, Column: d.pos.Column}
+}
+
+// closing returns the token closing the node, which has no position
+// unless the node has a "close".
+func (d *decoder) closing(tokenType token.TokenType, literal string) token.Token {
+	if d.close == nil {
+		return token.Token{}
+	}
+	return token.Token{Type: tokenType, Literal: literal, Line: d.close.Line, Column: d.close.Column}
+}
+
+func (d *decoder) value(name string, v interface{}) {
+	raw, ok := d.fields[name]
+	if !ok {
+		d.fail("missing field %q", name)
+		return
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		d.fail("field %q: %s", name, err)
+	}
+}
+
+func (d *decoder) string(name string) string {
+	var s string
+	d.value(name, &s)
+	return s
+}
+
+func (d *decoder) integer(name string) int64 {
+	var i int64
+	d.value(name, &i)
+	return i
+}
+
+func (d *decoder) boolean(name string) bool {
+	var b bool
+	d.value(name, &b)
+	return b
+}
+
+func (d *decoder) node(name string) Node {
+	raw, ok := d.fields[name]
+	if !ok {
+		return nil
+	}
+	return d.decode(raw)
+}
+
+func (d *decoder) decode(raw json.RawMessage) Node {
+	n, err := decodeNode(raw)
+	if err != nil {
+		d.fail("%s", strings.TrimPrefix(err.Error(), "ast: "))
+		return nil
+	}
+	return n
+}
+
+func (d *decoder) list(name string) []json.RawMessage {
+	var list []json.RawMessage
+	if raw, ok := d.fields[name]; ok && !isJSONNull(raw) {
+		d.value(name, &list)
+	}
+	return list
+}
+
+func (d *decoder) expression(name string) Expression {
+	return d.asExpression(d.node(name))
+}
+
+func (d *decoder) asExpression(n Node) Expression {
+	if n == nil {
+		return nil
+	}
+	exp, ok := n.(Expression)
+	if !ok {
+		d.fail("expected an expression, got %s", kindOf(n))
+	}
+	return exp
+}
+
+func (d *decoder) typ(name string) TypeExpression {
+	return d.asType(d.node(name))
+}
+
+func (d *decoder) asType(n Node) TypeExpression {
+	if n == nil {
+		return nil
+	}
+	typ, ok := n.(TypeExpression)
+	if !ok {
+		d.fail("expected a type, got %s", kindOf(n))
+	}
+	return typ
+}
+
+func (d *decoder) identifier(name string) *Identifier {
+	n := d.node(name)
+	if n == nil {
+		return nil
+	}
+	ident, ok := n.(*Identifier)
+	if !ok {
+		d.fail("field %q must be an Identifier, got %s", name, kindOf(n))
+	}
+	return ident
+}
+
+func (d *decoder) pattern(name string) Pattern {
+	n := d.node(name)
+	if n == nil {
+		return nil
+	}
+	p, ok := n.(Pattern)
+	if !ok {
+		d.fail("field %q must be a pattern, got %s", name, kindOf(n))
+	}
+	return p
+}
+
+func (d *decoder) asPattern(n Node) Pattern {
+	if n == nil {
+		return nil
+	}
+	p, ok := n.(Pattern)
+	if !ok {
+		d.fail("expected a pattern, got %s", kindOf(n))
+	}
+	return p
+}
+
+func (d *decoder) block(name string) *BlockStatement {
+	n := d.node(name)
+	if n == nil {
+		return nil
+	}
+	block, ok := n.(*BlockStatement)
+	if !ok {
+		d.fail("field %q must be a BlockStatement, got %s", name, kindOf(n))
+	}
+	return block
+}
+
+func (d *decoder) statements(name string) []Statement {
+	list := []Statement{}
+	for _, raw := range d.list(name) {
+		n := d.decode(raw)
+		stmt, ok := n.(Statement)
+		if !ok {
+			d.fail("expected a statement, got %s", kindOf(n))
+			continue
+		}
+		list = append(list, stmt)
+	}
+	return list
+}
+
+func (d *decoder) expressions(name string) []Expression {
+	list := []Expression{}
+	for _, raw := range d.list(name) {
+		list = append(list, d.asExpression(d.decode(raw)))
+	}
+	return list
+}
+
+func (d *decoder) patterns(name string) []Pattern {
+	list := []Pattern{}
+	for _, raw := range d.list(name) {
+		list = append(list, d.asPattern(d.decode(raw)))
+	}
+	return list
+}
+
+func (d *decoder) identifiers(name string) []*Identifier {
+	list := []*Identifier{}
+	for _, raw := range d.list(name) {
+		n := d.decode(raw)
+		ident, ok := n.(*Identifier)
+		if !ok {
+			d.fail("expected an Identifier, got %s", kindOf(n))
+			continue
+		}
+		list = append(list, ident)
+	}
+	return list
+}
+
+func (d *decoder) hash() *HashLiteral {
+	hash := &HashLiteral{Token: d.token(token.LBRACE, "{"), Pairs: map[Expression]Expression{},
+		Close: d.closing(token.RBRACE, "}")}
+	for _, raw := range d.list("pairs") {
+		var pair map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &pair); err != nil {
+			d.fail("pairs: %s", err)
+			continue
+		}
+		key := d.asExpression(d.decode(pair["key"]))
+		if key == nil {
+			d.fail("pairs: missing key")
+			continue
+		}
+		hash.Keys = append(hash.Keys, key)
+		hash.Pairs[key] = d.asExpression(d.decode(pair["value"]))
+	}
+	return hash
+}
+
+// TokenOf returns the token a node was parsed from. Program has none.
+func TokenOf(node Node) (token.Token, bool) {
+	switch n := node.(type) {
+	case *BlockStatement:
+		return n.Token, true
+	case *LetStatement:
+		return n.Token, true
+	case *ReturnStatement:
+		return n.Token, true
+	case *ExpressionStatement:
+		return n.Token, true
+	case *ArrayPattern:
+		return n.Token, true
+	case *HashPattern:
+		return n.Token, true
+	case *KeyPattern:
+		if n.Key != nil {
+			return TokenOf(n.Key)
+		}
+	case *LiteralPattern:
+		if n.Value != nil {
+			return TokenOf(n.Value)
+		}
+	case *MatchExpression:
+		return n.Token, true
+	case *MatchArm:
+		if n.Pattern != nil {
+			return TokenOf(n.Pattern)
+		}
+	case *ImportStatement:
+		return n.Token, true
+	case *ExportStatement:
+		return n.Token, true
+	case *TestStatement:
+		return n.Token, true
+	case *Identifier:
+		return n.Token, true
+	case *IntegerLiteral:
+		return n.Token, true
+	case *StringLiteral:
+		return n.Token, true
+	case *Boolean:
+		return n.Token, true
+	case *PrefixExpression:
+		return n.Token, true
+	case *InfixExpression:
+		return n.Token, true
+	case *IfExpression:
+		return n.Token, true
+	case *FunctionLiteral:
+		return n.Token, true
+	case *MacroLiteral:
+		return n.Token, true
+	case *CallExpression:
+		return n.Token, true
+	case *SpreadExpression:
+		return n.Token, true
+	case *ArrayLiteral:
+		return n.Token, true
+	case *IndexExpression:
+		return n.Token, true
+	case *MemberExpression:
+		return n.Token, true
+	case *HashLiteral:
+		return n.Token, true
+	case *NamedType:
+		return n.Token, true
+	case *ArrayType:
+		return n.Token, true
+	case *HashType:
+		return n.Token, true
+	case *FunctionType:
+		return n.Token, true
+	}
+	return token.Token{}, false
+}
+
+// StartOf returns the line and column where the first token of node
+// starts, or false if none of its tokens has a position. That is before
+// the token of node itself when it sits between its children, as the
+// operator of an infix expression or the ( of a call does.
+func StartOf(node Node) (line, column int, ok bool) {
+	Inspect(node, func(n Node) bool {
+		if tok, has := TokenOf(n); has && tok.Line > 0 {
+			if !ok || tok.Line < line || tok.Line == line && tok.Column < column {
+				line, column, ok = tok.Line, tok.Column, true
+			}
+		}
+		return true
+	})
+	return line, column, ok
+}
+
+// EndOf returns the line and column just after the last token of node,
+// closing brackets and braces included, or false if none of its tokens
+// has a position. Parentheses around an expression and semicolons aren't
+// kept in the tree, so they are left out.
+func EndOf(node Node) (line, column int, ok bool) {
+	end := func(tok token.Token) {
+		if tok.Line == 0 {
+			return
+		}
+		l, c := tokenEnd(tok)
+		if !ok || l > line || l == line && c > column {
+			line, column, ok = l, c, true
+		}
+	}
+	Inspect(node, func(n Node) bool {
+		if tok, has := TokenOf(n); has {
+			end(tok)
+		}
+		if tok, has := closeOf(n); has {
+			end(tok)
+		}
+		return true
+	})
+	return line, column, ok
+}
+
+// tokenEnd returns the line and column just after tok, which for a string
+// includes its quotes and any lines it spans.
+func tokenEnd(tok token.Token) (int, int) {
+	if tok.Type != token.STRING {
+		return tok.Line, tok.Column + len(tok.Literal)
+	}
+	if i := strings.LastIndexByte(tok.Literal, '\n'); i >= 0 {
+		return tok.Line + strings.Count(tok.Literal, "\n"), len(tok.Literal) - i + 1
+	}
+	return tok.Line, tok.Column + len(tok.Literal) + 2
+}
+
+// closeOf returns the bracket or brace closing node, for the nodes that
+// have one.
+func closeOf(node Node) (token.Token, bool) {
+	switch n := node.(type) {
+	case *BlockStatement:
+		return n.Close, true
+	case *MatchExpression:
+		return n.Close, true
+	case *ArrayPattern:
+		return n.Close, true
+	case *HashPattern:
+		return n.Close, true
+	case *CallExpression:
+		return n.Close, true
+	case *ArrayLiteral:
+		return n.Close, true
+	case *IndexExpression:
+		return n.Close, true
+	case *HashLiteral:
+		return n.Close, true
+	case *ArrayType:
+		return n.Close, true
+	case *HashType:
+		return n.Close, true
+	}
+	return token.Token{}, false
+}
+
+func kindOf(node Node) string {
+	if node == nil {
+		return "null"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
+
+// isNilNode reports whether node is nil or a nil pointer, which the parser
+// can leave behind for a node it failed to parse.
+func isNilNode(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+func isJSONNull(data []byte) bool {
+	return len(data) == 0 || string(bytes.TrimSpace(data)) == "null"
+}
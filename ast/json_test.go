@@ -0,0 +1,104 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
+
+func TestJSONRoundTripWithoutPositions(t *testing.T) {
+	program := testTree()
+	program.Statements = append(program.Statements,
+		&ImportStatement{Path: &StringLiteral{Value: "lib"}, Alias: ident("l")},
+		&ExportStatement{Statement: &LetStatement{Name: ident("a"), Value: &Boolean{Value: true}}},
+		&TestStatement{Name: &StringLiteral{Value: "adds"}, Body: &BlockStatement{Statements: []Statement{
+			&ExpressionStatement{Expression: ident("a")},
+		}}},
+		&ReturnStatement{ReturnValue: &PrefixExpression{Operator: "-", Right: &IndexExpression{Left: ident("a"), Index: integer(0)}}},
+		&ExpressionStatement{Expression: &CallExpression{
+			Function: &FunctionLiteral{
+				Parameters: []Pattern{ident("a"), &Identifier{Value: "b", Default: integer(1)}},
+				Rest:       ident("r"),
+				Body:       &BlockStatement{},
+			},
+			Arguments: []Expression{&SpreadExpression{Value: ident("a")}},
+		}},
+		&LetStatement{Token: token.Token{Type: token.FUNCTION, Literal: "fn"}, Name: ident("f"), Value: &FunctionLiteral{Name: ident("f"), Body: &BlockStatement{}}},
+		&LetStatement{Token: token.Token{Type: token.CONST, Literal: "const"}, Name: ident("k"), Value: integer(1)},
+		&LetStatement{Name: &ArrayPattern{
+			Elements: []Pattern{ident("a"), &HashPattern{
+				Pairs: []*KeyPattern{
+					{Key: ident("b"), Value: ident("b")},
+					{Key: &StringLiteral{Value: "c d"}, Value: &Identifier{Value: "c", Default: integer(2)}},
+				},
+				Rest: ident("h"),
+			}},
+			Rest: ident("r"),
+		}, Value: ident("x")},
+	)
+
+	data, err := ToJSON(program)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+	if strings.Contains(string(data), `"pos"`) || strings.Contains(string(data), `"span"`) ||
+		strings.Contains(string(data), `"close"`) {
+		t.Errorf("nodes without tokens should have no positions. got=%s", data)
+	}
+
+	decoded, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %s", err)
+	}
+
+	// The decoded tree has real tokens, the original tree doesn't, so compare
+	// the JSON, which is built from the fields only.
+	again, err := ToJSON(decoded)
+	if err != nil {
+		t.Fatalf("ToJSON of decoded tree failed: %s", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("round trip changed the tree.\nwant=%s\ngot= %s", data, again)
+	}
+
+	expected := `import "lib" as l;export let a = true;test "adds" { a }return (-(a[0]));fn(a, b = 1, ...r) {}(...a);fn f() {}const k = 1;let [a, {b, "c d": c = 2, ...h}, ...r] = x;`
+	if !strings.HasSuffix(decoded.String(), expected) {
+		t.Errorf("wrong String(). want suffix %q, got=%q", expected, decoded.String())
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[]`, "ast: json: cannot unmarshal array"},
+		{`{"value": "x"}`, `ast: missing field "kind"`},
+		{`{"kind": "Loop"}`, `ast: unknown node kind "Loop"`},
+		{`{"kind": "IntegerLiteral", "value": "one"}`, `ast: IntegerLiteral: field "value"`},
+		{`{"kind": "Program", "statements": [{"kind": "Identifier", "value": "x"}]}`,
+			"ast: Program: expected a statement, got Identifier"},
+		{`{"kind": "LetStatement", "name": {"kind": "Boolean", "value": true}, "value": null}`,
+			`ast: LetStatement: field "name" must be a pattern, got Boolean`},
+		{`{"kind": "MemberExpression", "object": null, "property": {"kind": "IntegerLiteral", "value": 1}}`,
+			`ast: MemberExpression: field "property" must be an Identifier, got IntegerLiteral`},
+		{`{"kind": "HashPattern", "pairs": [{"kind": "Identifier", "value": "a"}]}`,
+			"ast: HashPattern: expected a KeyPattern, got Identifier"},
+		{`{"kind": "Identifier", "pos": {"line": "1"}, "value": "x"}`, "ast: Identifier: bad pos"},
+		{`{"kind": "ArrayLiteral", "elements": [], "close": 1}`, "ast: ArrayLiteral: bad close"},
+		{`{"kind": "ExpressionStatement", "expression": {"kind": "Foo"}}`,
+			`ast: ExpressionStatement: unknown node kind "Foo"`},
+	}
+
+	for _, tt := range tests {
+		_, err := FromJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %s", tt.input)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %s. want prefix %q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package ast
+
+// ModifierFunc returns the node that replaces node in the tree.
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree rooted at node depth-first, replacing every node,
+// children before parents, with the result of calling modifier on it.
+// It returns the modified root.
+func Modify(node Node, modifier ModifierFunc) Node {
+	return Apply(node, nil, func(c *Cursor) bool {
+		c.Replace(modifier(c.Node()))
+		return true
+	})
+}
@@ -0,0 +1,196 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+	
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+		
+		if integer.Value != 1 {
+			return node
+		}
+		
+		integer.Value = 2
+		return integer
+	}
+	
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: one()},
+				},
+			},
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: two()},
+				},
+			},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Value: one()},
+			&LetStatement{Value: two()},
+		},
+		{
+			&ExportStatement{Statement: &LetStatement{Value: one()}},
+			&ExportStatement{Statement: &LetStatement{Value: two()}},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []Pattern{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []Pattern{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{&SpreadExpression{Value: one()}}},
+			&CallExpression{Function: two(), Arguments: []Expression{&SpreadExpression{Value: two()}}},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []Pattern{&Identifier{Value: "a", Default: one()}},
+				Rest:       &Identifier{Value: "r"},
+				Body:       &BlockStatement{},
+			},
+			&FunctionLiteral{
+				Parameters: []Pattern{&Identifier{Value: "a", Default: two()}},
+				Rest:       &Identifier{Value: "r"},
+				Body:       &BlockStatement{},
+			},
+		},
+		{
+			&LetStatement{Name: &ArrayPattern{
+				Elements: []Pattern{&HashPattern{
+					Pairs:   []*KeyPattern{{Key: &Identifier{Value: "a"}, Value: &Identifier{Value: "a", Default: one()}}},
+					Default: one(),
+				}},
+				Default: one(),
+			}, Value: one()},
+			&LetStatement{Name: &ArrayPattern{
+				Elements: []Pattern{&HashPattern{
+					Pairs:   []*KeyPattern{{Key: &Identifier{Value: "a"}, Value: &Identifier{Value: "a", Default: two()}}},
+					Default: two(),
+				}},
+				Default: two(),
+			}, Value: two()},
+		},
+		{
+			&MemberExpression{Object: one(), Property: &Identifier{Value: "x"}},
+			&MemberExpression{Object: two(), Property: &Identifier{Value: "x"}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+	}
+	
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+		
+		equal := reflect.DeepEqual(modified, tt.expected)
+		if !equal {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+	
+	key := one()
+	hashLiteral := &HashLiteral{
+		Pairs: map[Expression]Expression{key: one()},
+		Keys:  []Expression{key},
+	}
+	
+	Modify(hashLiteral, turnOneIntoTwo)
+	
+	if len(hashLiteral.Keys) != 1 || len(hashLiteral.Pairs) != 1 {
+		t.Fatalf("wrong number of pairs after Modify. got=%d keys, %d pairs", len(hashLiteral.Keys), len(hashLiteral.Pairs))
+	}
+	for _, key := range hashLiteral.Keys {
+		key, _ := key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("key is not %d, got=%d", 2, key.Value)
+		}
+		val, _ := hashLiteral.Pairs[key].(*IntegerLiteral)
+		if val.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.Value)
+		}
+	}
+}
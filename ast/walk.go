@@ -0,0 +1,248 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order: it starts by
+// calling v.Visit(node); node must not be nil. If the visitor returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with that visitor
+// for each of the non-nil children of node, followed by a call of
+// w.Visit(nil). Hash literal pairs are visited key first, in source order.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Leaves
+	case *IntegerLiteral, *StringLiteral, *Boolean, *NamedType:
+
+	case *Identifier:
+		walkType(v, n.Type)
+		walkExpression(v, n.Default)
+
+	// Expressions
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *MatchExpression:
+		walkExpression(v, n.Value)
+		for _, arm := range n.Arms {
+			if arm != nil {
+				Walk(v, arm)
+			}
+		}
+
+	case *MatchArm:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		walkExpression(v, n.Guard)
+		walkExpression(v, n.Body)
+
+	case *FunctionLiteral:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkPatterns(v, n.Parameters)
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+		walkType(v, n.ReturnType)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *MacroLiteral:
+		walkIdentifiers(v, n.Parameters)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+
+	case *SpreadExpression:
+		walkExpression(v, n.Value)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+
+	case *MemberExpression:
+		walkExpression(v, n.Object)
+		if n.Property != nil {
+			Walk(v, n.Property)
+		}
+
+	case *HashLiteral:
+		for _, key := range n.Keys {
+			walkExpression(v, key)
+			walkExpression(v, n.Pairs[key])
+		}
+
+	// Patterns
+	case *ArrayPattern:
+		walkPatterns(v, n.Elements)
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+		walkExpression(v, n.Default)
+
+	case *HashPattern:
+		for _, pair := range n.Pairs {
+			if pair != nil {
+				Walk(v, pair)
+			}
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+		walkExpression(v, n.Default)
+
+	case *LiteralPattern:
+		walkExpression(v, n.Value)
+
+	case *KeyPattern:
+		walkExpression(v, n.Key)
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	// Statements
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkExpression(v, n.Value)
+
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+
+	case *ImportStatement:
+		if n.Path != nil {
+			Walk(v, n.Path)
+		}
+		if n.Alias != nil {
+			Walk(v, n.Alias)
+		}
+
+	case *ExportStatement:
+		if n.Statement != nil {
+			Walk(v, n.Statement)
+		}
+
+	case *TestStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	// Types
+	case *ArrayType:
+		walkType(v, n.Element)
+
+	case *HashType:
+		walkType(v, n.Key)
+		walkType(v, n.Value)
+
+	case *FunctionType:
+		for _, param := range n.Parameters {
+			walkType(v, param)
+		}
+		walkType(v, n.ReturnType)
+	}
+
+	v.Visit(nil)
+}
+
+func walkExpression(v Visitor, exp Expression) {
+	if exp != nil {
+		Walk(v, exp)
+	}
+}
+
+func walkType(v Visitor, typ TypeExpression) {
+	if typ != nil {
+		Walk(v, typ)
+	}
+}
+
+func walkExpressions(v Visitor, list []Expression) {
+	for _, exp := range list {
+		walkExpression(v, exp)
+	}
+}
+
+func walkStatements(v Visitor, list []Statement) {
+	for _, stmt := range list {
+		if stmt != nil {
+			Walk(v, stmt)
+		}
+	}
+}
+
+func walkPatterns(v Visitor, list []Pattern) {
+	for _, p := range list {
+		if p != nil {
+			Walk(v, p)
+		}
+	}
+}
+
+func walkIdentifiers(v Visitor, list []*Identifier) {
+	for _, ident := range list {
+		if ident != nil {
+			Walk(v, ident)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order: it starts
+// by calling f(node); node must not be nil. If f returns true, Inspect invokes
+// f recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,135 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ident(name string) *Identifier { return &Identifier{Value: name} }
+func integer(value int64) *IntegerLiteral { return &IntegerLiteral{Value: value} }
+
+// testTree returns the tree of:
+//
+//	let f = fn(x) { if (x) { {"k": x} } else { [x, 1] } };
+//	f(2).y;
+func testTree() *Program {
+	key := &StringLiteral{Value: "k"}
+	return &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: ident("f"),
+				Value: &FunctionLiteral{
+					Parameters: []Pattern{ident("x")},
+					Body: &BlockStatement{Statements: []Statement{
+						&ExpressionStatement{Expression: &IfExpression{
+							Condition: ident("x"),
+							Consequence: &BlockStatement{Statements: []Statement{
+								&ExpressionStatement{Expression: &HashLiteral{
+									Pairs: map[Expression]Expression{key: ident("x")},
+									Keys:  []Expression{key},
+								}},
+							}},
+							Alternative: &BlockStatement{Statements: []Statement{
+								&ExpressionStatement{Expression: &ArrayLiteral{
+									Elements: []Expression{ident("x"), integer(1)},
+								}},
+							}},
+						}},
+					}},
+				},
+			},
+			&ExpressionStatement{Expression: &MemberExpression{
+				Object: &CallExpression{
+					Function:  ident("f"),
+					Arguments: []Expression{integer(2)},
+				},
+				Property: ident("y"),
+			}},
+		},
+	}
+}
+
+func describe(n Node) string {
+	switch n := n.(type) {
+	case *Identifier:
+		return n.Value
+	case *IntegerLiteral:
+		return fmt.Sprint(n.Value)
+	case *StringLiteral:
+		return fmt.Sprintf("%q", n.Value)
+	default:
+		return strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var visited []string
+	Inspect(testTree(), func(n Node) bool {
+		if n != nil {
+			visited = append(visited, describe(n))
+		}
+		return true
+	})
+	
+	expected := []string{
+		"Program",
+		"LetStatement", "f", "FunctionLiteral", "x", "BlockStatement",
+		"ExpressionStatement", "IfExpression", "x",
+		"BlockStatement", "ExpressionStatement", "HashLiteral", `"k"`, "x",
+		"BlockStatement", "ExpressionStatement", "ArrayLiteral", "x", "1",
+		"ExpressionStatement", "MemberExpression", "CallExpression", "f", "2", "y",
+	}
+	
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visiting order.\nwant=%v\ngot= %v", expected, visited)
+	}
+}
+
+func TestInspectPrunes(t *testing.T) {
+	var visited []string
+	Inspect(testTree(), func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		visited = append(visited, describe(n))
+		_, isFunction := n.(*FunctionLiteral)
+		return !isFunction
+	})
+	
+	expected := []string{
+		"Program", "LetStatement", "f", "FunctionLiteral",
+		"ExpressionStatement", "MemberExpression", "CallExpression", "f", "2", "y",
+	}
+	
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visiting order.\nwant=%v\ngot= %v", expected, visited)
+	}
+}
+
+type depthVisitor struct {
+	depth int
+	lines *[]string
+}
+
+func (v depthVisitor) Visit(n Node) Visitor {
+	if n == nil {
+		*v.lines = append(*v.lines, strings.Repeat(" ", v.depth-1)+"end")
+		return nil
+	}
+	*v.lines = append(*v.lines, strings.Repeat(" ", v.depth)+describe(n))
+	return depthVisitor{depth: v.depth + 1, lines: v.lines}
+}
+
+func TestWalkCallsVisitWithNilAfterChildren(t *testing.T) {
+	var lines []string
+	node := &InfixExpression{Left: integer(1), Operator: "+", Right: integer(2)}
+	
+	Walk(depthVisitor{depth: 1, lines: &lines}, node)
+	
+	expected := []string{" InfixExpression", "  1", "  end", "  2", "  end", " end"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("wrong visits.\nwant=%q\ngot= %q", expected, lines)
+	}
+}
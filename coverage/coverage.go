@@ -0,0 +1,213 @@
+// Package coverage records which statements of Monkey programs run, and
+// which way each if expression goes, so reports can show the code tests
+// leave out. Profiles from separate runs can be saved and merged.
+package coverage
+
+import (
+	"os"
+	"sort"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+// Kinds of counter.
+const (
+	Statement = "stmt" // a statement ran
+	Then      = "then" // an if ran its consequence
+	Else      = "else" // an if ran its alternative, or nothing if it has none
+)
+
+var kindOrder = map[string]int{Statement: 0, Then: 1, Else: 2}
+
+// A Counter counts how often a statement ran or an if took one of its
+// branches. Statements and ifs are found by the position of their first
+// token.
+type Counter struct {
+	Line, Column int
+	Kind         string
+	Count        int64
+}
+
+type key struct {
+	line, column int
+	kind         string
+}
+
+// A File holds the counters of one source file.
+type File struct {
+	Name     string
+	counters map[key]*Counter
+}
+
+func newFile(name string) *File {
+	return &File{Name: name, counters: map[key]*Counter{}}
+}
+
+// counter returns the counter of kind at line and column, adding it if it
+// is new.
+func (f *File) counter(line, column int, kind string) *Counter {
+	k := key{line, column, kind}
+	c, ok := f.counters[k]
+	if !ok {
+		c = &Counter{Line: line, Column: column, Kind: kind}
+		f.counters[k] = c
+	}
+	return c
+}
+
+// Counters returns the counters of f in source order.
+func (f *File) Counters() []*Counter {
+	counters := make([]*Counter, 0, len(f.counters))
+	for _, c := range f.counters {
+		counters = append(counters, c)
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		a, b := counters[i], counters[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return kindOrder[a.Kind] < kindOrder[b.Kind]
+	})
+	return counters
+}
+
+// A Profile is the coverage of the source files of one or more runs.
+type Profile struct {
+	files map[string]*File
+	ifs   map[*ast.IfExpression]*File // where each if being run is from
+}
+
+func New() *Profile {
+	return &Profile{files: map[string]*File{}, ifs: map[*ast.IfExpression]*File{}}
+}
+
+// Files returns the files in p, ordered by name.
+func (p *Profile) Files() []*File {
+	files := make([]*File, 0, len(p.files))
+	for _, f := range p.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// Merge adds the counts of q to p.
+func (p *Profile) Merge(q *Profile) {
+	for name, qf := range q.files {
+		f, ok := p.files[name]
+		if !ok {
+			f = newFile(name)
+			p.files[name] = f
+		}
+		for k, c := range qf.counters {
+			f.counter(k.line, k.column, k.kind).Count += c.Count
+		}
+	}
+}
+
+// Run calls eval, which evaluates a program, recording its coverage, and
+// returns its result. Running several programs adds up their coverage.
+func (p *Profile) Run(eval func() object.Object) object.Object {
+	evaluator.SetTracer(p)
+	defer evaluator.SetTracer(nil)
+
+	return eval()
+}
+
+// source returns the file called name, reading the statements and ifs in
+// it the first time it is seen so that those never run are counted too.
+// Code that isn't from a file is not covered.
+func (p *Profile) source(name string) *File {
+	if name == "" {
+		return nil
+	}
+	if f, ok := p.files[name]; ok {
+		return f
+	}
+
+	f := newFile(name)
+	p.files[name] = f
+	text, err := os.ReadFile(name)
+	if err != nil {
+		return f
+	}
+	program := parser.New(lexer.New(string(text))).ParseProgram()
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.MacroLiteral:
+			return false
+		case *ast.LetStatement:
+			// macro definitions are taken out before the program runs
+			if _, ok := n.Value.(*ast.MacroLiteral); ok {
+				return false
+			}
+		case *ast.IfExpression:
+			f.counter(n.Token.Line, n.Token.Column, Then)
+			f.counter(n.Token.Line, n.Token.Column, Else)
+		}
+		if isStatement(n) {
+			tok, _ := ast.TokenOf(n)
+			f.counter(tok.Line, tok.Column, Statement)
+		}
+		return true
+	})
+	return f
+}
+
+func isStatement(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.LetStatement, *ast.ReturnStatement, *ast.ExpressionStatement,
+		*ast.ImportStatement, *ast.ExportStatement:
+		return true
+	}
+	return false
+}
+
+// count adds one to an existing counter. Code made by macros may have
+// positions that don't match any statement in the file, and is left out.
+func (f *File) count(line, column int, kind string) {
+	if c, ok := f.counters[key{line, column, kind}]; ok {
+		c.Count++
+	}
+}
+
+// Step implements evaluator.Tracer.
+func (p *Profile) Step(node ast.Node, env *object.Environment) {
+	if ie, ok := node.(*ast.IfExpression); ok {
+		p.ifs[ie] = p.source(env.File())
+		return
+	}
+	if !isStatement(node) {
+		return
+	}
+	if f := p.source(env.File()); f != nil {
+		tok, _ := ast.TokenOf(node)
+		f.count(tok.Line, tok.Column, Statement)
+	}
+}
+
+// Branch implements evaluator.BranchTracer.
+func (p *Profile) Branch(ie *ast.IfExpression, consequence bool) {
+	f := p.ifs[ie]
+	if f == nil {
+		return
+	}
+	kind := Else
+	if consequence {
+		kind = Then
+	}
+	f.count(ie.Token.Line, ie.Token.Column, kind)
+}
+
+// Enter implements evaluator.Tracer.
+func (p *Profile) Enter(call *ast.CallExpression, fn *object.Function) {}
+
+// Leave implements evaluator.Tracer.
+func (p *Profile) Leave(call *ast.CallExpression, result object.Object) {}
@@ -0,0 +1,112 @@
+package coverage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+const rules = `let discount = fn(total, member) {
+  if (member) {
+    if (total > 100) { return total / 10; }
+    return 5;
+  } else {
+    0
+  }
+};
+let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };
+unless(false, discount(200, true));
+discount(50, false);
+`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, source := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// counts shows the counters of f as "line.column kind count".
+func counts(f *File) string {
+	lines := []string{}
+	for _, c := range f.Counters() {
+		lines = append(lines, fmt.Sprintf("%d.%d %s %d", c.Line, c.Column, c.Kind, c.Count))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func run(t *testing.T, p *Profile, path string) {
+	t.Helper()
+
+	result := p.Run(func() object.Object { return evaluator.EvalFile(path) })
+	if errObj, ok := result.(*object.Error); ok {
+		t.Fatalf("%s failed: %s", path, errObj.Inspect())
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"rules.mk": rules})
+	p := New()
+	run(t, p, filepath.Join(dir, "rules.mk"))
+
+	files := p.Files()
+	if len(files) != 1 || files[0].Name != filepath.Join(dir, "rules.mk") {
+		t.Fatalf("wrong files covered. got=%+v", files)
+	}
+
+	expected := `1.1 stmt 1
+2.3 stmt 2
+2.3 then 1
+2.3 else 1
+3.5 stmt 1
+3.5 then 1
+3.5 else 0
+3.24 stmt 1
+4.5 stmt 0
+6.5 stmt 1
+10.1 stmt 1
+11.1 stmt 1`
+	if got := counts(files[0]); got != expected {
+		t.Errorf("wrong counts. expected=\n%s\ngot=\n%s", expected, got)
+	}
+
+	s := files[0].Summary()
+	if s.StatementCoverage() != "87.5% (7/8)" || s.BranchCoverage() != "75.0% (3/4)" {
+		t.Errorf("wrong summary. got=%s, %s", s.StatementCoverage(), s.BranchCoverage())
+	}
+}
+
+func TestRunModules(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib.mk":   "export let abs = fn(x) { if (x < 0) { -x } };\n",
+		"main.mk":  "import \"lib\";\nlib.abs(-1);\n",
+		"other.mk": "import \"lib\";\nlib.abs(1);\nlib.abs(2);\n",
+	})
+	p := New()
+	run(t, p, filepath.Join(dir, "main.mk"))
+	run(t, p, filepath.Join(dir, "other.mk"))
+
+	expected := map[string]string{
+		"lib.mk":   "1.1 stmt 1\n1.8 stmt 1\n1.26 stmt 3\n1.26 then 1\n1.26 else 2\n1.39 stmt 1",
+		"main.mk":  "1.1 stmt 1\n2.1 stmt 1",
+		"other.mk": "1.1 stmt 1\n2.1 stmt 1\n3.1 stmt 1",
+	}
+	for _, f := range p.Files() {
+		name := filepath.Base(f.Name)
+		if got := counts(f); got != expected[name] {
+			t.Errorf("wrong counts for %s. expected=\n%s\ngot=\n%s", name, expected[name], got)
+		}
+		delete(expected, name)
+	}
+	if len(expected) != 0 {
+		t.Errorf("files not covered: %v", expected)
+	}
+}
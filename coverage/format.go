@@ -0,0 +1,105 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const profileHeader = "mode: count"
+
+// WriteProfile saves p so that it can be read back and merged with other
+// runs. Each line holds one counter:
+//
+//	file:line.column kind count
+func (p *Profile) WriteProfile(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, profileHeader)
+	for _, f := range p.Files() {
+		for _, c := range f.Counters() {
+			fmt.Fprintf(bw, "%s:%d.%d %s %d\n", f.Name, c.Line, c.Column, c.Kind, c.Count)
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadProfile reads a profile saved by WriteProfile.
+func ReadProfile(r io.Reader) (*Profile, error) {
+	p := New()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if line == 1 {
+			if text != profileHeader {
+				return nil, fmt.Errorf("line 1: not a coverage profile, want %q", profileHeader)
+			}
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		if err := p.readCounter(text); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, fmt.Errorf("empty coverage profile")
+	}
+	return p, nil
+}
+
+// readCounter adds one line of a profile to p. File names may hold spaces
+// and colons, so the line is taken apart from the end.
+func (p *Profile) readCounter(text string) error {
+	bad := fmt.Errorf("malformed counter %q", text)
+
+	i := strings.LastIndexByte(text, ' ')
+	if i < 0 {
+		return bad
+	}
+	count, err := strconv.ParseInt(text[i+1:], 10, 64)
+	if err != nil || count < 0 {
+		return bad
+	}
+	text = text[:i]
+
+	i = strings.LastIndexByte(text, ' ')
+	if i < 0 {
+		return bad
+	}
+	kind := text[i+1:]
+	if _, ok := kindOrder[kind]; !ok {
+		return fmt.Errorf("unknown counter kind %q", kind)
+	}
+	text = text[:i]
+
+	i = strings.LastIndexByte(text, ':')
+	if i <= 0 {
+		return bad
+	}
+	name, position := text[:i], text[i+1:]
+	lineText, columnText, ok := strings.Cut(position, ".")
+	if !ok {
+		return bad
+	}
+	line, err1 := strconv.Atoi(lineText)
+	column, err2 := strconv.Atoi(columnText)
+	if err1 != nil || err2 != nil {
+		return bad
+	}
+
+	f, ok := p.files[name]
+	if !ok {
+		f = newFile(name)
+		p.files[name] = f
+	}
+	f.counter(line, column, kind).Count += count
+	return nil
+}
@@ -0,0 +1,62 @@
+package coverage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProfileRoundTrip(t *testing.T) {
+	input := `mode: count
+/src/a b.mk:1.1 stmt 2
+/src/a b.mk:2.3 then 0
+/src/a b.mk:2.3 else 2
+C:/src/c.mk:4.1 stmt 0
+`
+	p, err := ReadProfile(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := p.WriteProfile(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != input {
+		t.Errorf("profile did not round trip. expected=\n%s\ngot=\n%s", input, out.String())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first, _ := ReadProfile(strings.NewReader("mode: count\n/a.mk:1.1 stmt 1\n/a.mk:2.1 stmt 0\n"))
+	second, _ := ReadProfile(strings.NewReader("mode: count\n/a.mk:2.1 stmt 3\n/b.mk:1.1 stmt 1\n"))
+	first.Merge(second)
+
+	var out bytes.Buffer
+	first.WriteProfile(&out)
+	expected := "mode: count\n/a.mk:1.1 stmt 1\n/a.mk:2.1 stmt 3\n/b.mk:1.1 stmt 1\n"
+	if out.String() != expected {
+		t.Errorf("wrong merged profile. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestReadProfileErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "empty coverage profile"},
+		{"mode: set\n", `line 1: not a coverage profile, want "mode: count"`},
+		{"mode: count\n/a.mk:1.1 stmt\n", `line 2: malformed counter "/a.mk:1.1 stmt"`},
+		{"mode: count\n/a.mk:1.1 line 3\n", `line 2: unknown counter kind "line"`},
+		{"mode: count\n/a.mk:1 stmt 3\n", `line 2: malformed counter "/a.mk:1 stmt 3"`},
+		{"mode: count\n/a.mk:1.1 stmt -1\n", `line 2: malformed counter "/a.mk:1.1 stmt -1"`},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadProfile(strings.NewReader(tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+)
+
+type htmlLine struct {
+	Number int
+	Hits   string
+	Text   string
+	Class  string // covered, partial or uncovered, or "" for lines without statements
+	Title  string
+}
+
+type htmlFile struct {
+	Name       string
+	Statements string
+	Branches   string
+	Lines      []htmlLine
+	Missing    bool
+}
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table.source { border-collapse: collapse; font-family: monospace; }
+.source td { padding: 0 0.6em; white-space: pre; }
+.source td.number, .source td.hits { color: #888; text-align: right; }
+tr.covered { background: #d8f5d8; }
+tr.partial { background: #fbf3c4; }
+tr.uncovered { background: #f8d6d6; }
+</style>
+</head>
+<body>
+<h1>Coverage</h1>
+<table>
+<tr><th>file</th><th>statements</th><th>branches</th></tr>
+{{range $i, $f := .}}<tr><td><a href="#file{{$i}}">{{$f.Name}}</a></td><td>{{$f.Statements}}</td><td>{{$f.Branches}}</td></tr>
+{{end}}</table>
+{{range $i, $f := .}}
+<h2 id="file{{$i}}">{{$f.Name}}</h2>
+{{if $f.Missing}}<p>The source is not available.</p>{{else}}<table class="source">
+{{range $f.Lines}}<tr class="{{.Class}}"{{if .Title}} title="{{.Title}}"{{end}}><td class="number">{{.Number}}</td><td class="hits">{{.Hits}}</td><td>{{.Text}}</td></tr>
+{{end}}</table>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes a page showing the source of each file with the lines
+// that ran, those that didn't and those with a statement or branch left
+// out marked.
+func (p *Profile) WriteHTML(w io.Writer) error {
+	files := []htmlFile{}
+	for _, f := range p.Files() {
+		s := f.Summary()
+		hf := htmlFile{
+			Name:       displayName(f.Name),
+			Statements: s.StatementCoverage(),
+			Branches:   s.BranchCoverage(),
+		}
+		text, err := os.ReadFile(f.Name)
+		if err != nil {
+			hf.Missing = true
+		} else {
+			hf.Lines = annotate(f, strings.Split(strings.TrimSuffix(string(text), "\n"), "\n"))
+		}
+		files = append(files, hf)
+	}
+	return htmlTemplate.Execute(w, files)
+}
+
+func annotate(f *File, source []string) []htmlLine {
+	lines := make([]htmlLine, len(source))
+	for i, text := range source {
+		lines[i] = htmlLine{Number: i + 1, Text: text}
+	}
+
+	type lineCoverage struct {
+		statements, run int
+		hits            int64
+		missed          []string
+	}
+	coverage := map[int]*lineCoverage{}
+	for _, c := range f.Counters() {
+		if c.Line < 1 || c.Line > len(lines) {
+			continue
+		}
+		lc, ok := coverage[c.Line]
+		if !ok {
+			lc = &lineCoverage{}
+			coverage[c.Line] = lc
+		}
+
+		if c.Kind != Statement {
+			if c.Count == 0 {
+				lc.missed = append(lc.missed, fmt.Sprintf("%s branch at column %d never taken", c.Kind, c.Column))
+			}
+			continue
+		}
+		lc.statements++
+		if c.Count > 0 {
+			lc.run++
+		}
+		if c.Count > lc.hits {
+			lc.hits = c.Count
+		}
+	}
+
+	for n, lc := range coverage {
+		line := &lines[n-1]
+		switch {
+		case lc.statements == 0:
+			continue
+		case lc.run == 0:
+			line.Class = "uncovered"
+		case lc.run < lc.statements || len(lc.missed) > 0:
+			line.Class = "partial"
+		default:
+			line.Class = "covered"
+		}
+		line.Hits = fmt.Sprint(lc.hits)
+		if lc.run < lc.statements {
+			lc.missed = append([]string{fmt.Sprintf("%d of %d statements run", lc.run, lc.statements)}, lc.missed...)
+		}
+		if lc.run > 0 {
+			line.Title = strings.Join(lc.missed, "; ")
+		}
+	}
+	return lines
+}
@@ -0,0 +1,57 @@
+package coverage
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTML(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"rules.mk": rules})
+	p := New()
+	run(t, p, filepath.Join(dir, "rules.mk"))
+	p.Merge(mustRead(t, "mode: count\n"+filepath.Join(dir, "missing.mk")+":1.1 stmt 1\n"))
+
+	var out bytes.Buffer
+	if err := p.WriteHTML(&out); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+
+	rows := regexp.MustCompile(`<tr class="([a-z]*)"(?: title="([^"]*)")?><td class="number">(\d+)</td><td class="hits">(\d*)</td><td>(.*)</td></tr>`).FindAllStringSubmatch(page, -1)
+	expected := []string{
+		"covered  1 1 let discount = fn(total, member) {",
+		"covered  2 2   if (member) {",
+		"partial else branch at column 5 never taken 3 1     if (total &gt; 100) { return total / 10; }",
+		"uncovered  4 0     return 5;",
+		"  5    } else {",
+		"covered  6 1     0",
+		"  7    }",
+		"  8  };",
+		"  9  let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };",
+		"covered  10 1 unless(false, discount(200, true));",
+		"covered  11 1 discount(50, false);",
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("wrong number of source lines. expected=%d, got=%d", len(expected), len(rows))
+	}
+	for i, row := range rows {
+		if got := strings.Join(row[1:], " "); got != expected[i] {
+			t.Errorf("line %d wrong. expected=%q, got=%q", i+1, expected[i], got)
+		}
+	}
+
+	if !strings.Contains(page, "<p>The source is not available.</p>") {
+		t.Errorf("missing source not reported")
+	}
+}
+
+func mustRead(t *testing.T, input string) *Profile {
+	p, err := ReadProfile(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,135 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+// A Summary counts what was covered.
+type Summary struct {
+	Statements, StatementsRun int
+	Branches, BranchesTaken   int
+}
+
+// Summary sums up the coverage of f.
+func (f *File) Summary() Summary {
+	var s Summary
+	s.add(f)
+	return s
+}
+
+func (s *Summary) add(f *File) {
+	for _, c := range f.counters {
+		if c.Kind == Statement {
+			s.Statements++
+			if c.Count > 0 {
+				s.StatementsRun++
+			}
+		} else {
+			s.Branches++
+			if c.Count > 0 {
+				s.BranchesTaken++
+			}
+		}
+	}
+}
+
+func ratio(covered, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%% (%d/%d)", float64(covered)*100/float64(total), covered, total)
+}
+
+// StatementCoverage shows the statements run, as "75.0% (3/4)".
+func (s Summary) StatementCoverage() string { return ratio(s.StatementsRun, s.Statements) }
+
+// BranchCoverage shows the branches taken, as "50.0% (1/2)".
+func (s Summary) BranchCoverage() string { return ratio(s.BranchesTaken, s.Branches) }
+
+// displayName shortens a file name, relative to the current directory.
+func displayName(name string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return name
+	}
+	if rel, err := filepath.Rel(wd, name); err == nil && len(rel) < len(name) {
+		return rel
+	}
+	return name
+}
+
+// WriteText writes the statement and branch coverage of each file and of
+// them all.
+func (p *Profile) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "file\tstatements\tbranches\n")
+	var total Summary
+	for _, f := range p.Files() {
+		s := f.Summary()
+		total.add(f)
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", displayName(f.Name), s.StatementCoverage(), s.BranchCoverage())
+	}
+	fmt.Fprintf(tw, "total\t%s\t%s\n", total.StatementCoverage(), total.BranchCoverage())
+	return tw.Flush()
+}
+
+// WriteLCOV writes p in the LCOV tracefile format read by genhtml and
+// most coverage services. Each line gets the highest count of the
+// statements starting on it.
+func (p *Profile) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range p.Files() {
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", f.Name)
+
+		lines := map[int]int64{}
+		branches, taken, block := 0, 0, 0
+		for _, c := range f.Counters() {
+			switch c.Kind {
+			case Statement:
+				if count, ok := lines[c.Line]; !ok || c.Count > count {
+					lines[c.Line] = c.Count
+				}
+			case Then:
+				counts := []int64{c.Count, 0}
+				if alternative, ok := f.counters[key{c.Line, c.Column, Else}]; ok {
+					counts[1] = alternative.Count
+				}
+				for branch, count := range counts {
+					// an if that never ran took neither branch
+					result := "-"
+					if counts[0]+counts[1] > 0 {
+						result = fmt.Sprint(count)
+					}
+					fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", c.Line, block, branch, result)
+					branches++
+					if count > 0 {
+						taken++
+					}
+				}
+				block++
+			}
+		}
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", branches, taken)
+
+		numbers := make([]int, 0, len(lines))
+		for line := range lines {
+			numbers = append(numbers, line)
+		}
+		sort.Ints(numbers)
+		hit := 0
+		for _, line := range numbers {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line, lines[line])
+			if lines[line] > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,77 @@
+package coverage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const saved = `mode: count
+/src/a.mk:1.1 stmt 1
+/src/a.mk:2.3 stmt 2
+/src/a.mk:2.3 then 2
+/src/a.mk:2.3 else 0
+/src/a.mk:3.5 stmt 2
+/src/a.mk:5.1 stmt 0
+/src/a.mk:5.10 stmt 4
+/src/a.mk:6.1 stmt 0
+/src/a.mk:6.1 then 0
+/src/a.mk:6.1 else 0
+/src/b.mk:1.1 stmt 0
+`
+
+func TestWriteText(t *testing.T) {
+	p, err := ReadProfile(strings.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	p.WriteText(&out)
+	expected := `file       statements   branches
+/src/a.mk  66.7% (4/6)  25.0% (1/4)
+/src/b.mk  0.0% (0/1)   -
+total      57.1% (4/7)  25.0% (1/4)
+`
+	if out.String() != expected {
+		t.Errorf("wrong report. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	p, err := ReadProfile(strings.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	p.WriteLCOV(&out)
+	expected := `TN:
+SF:/src/a.mk
+BRDA:2,0,0,2
+BRDA:2,0,1,0
+BRDA:6,1,0,-
+BRDA:6,1,1,-
+BRF:4
+BRH:1
+DA:1,1
+DA:2,2
+DA:3,2
+DA:5,4
+DA:6,0
+LF:5
+LH:4
+end_of_record
+TN:
+SF:/src/b.mk
+BRF:0
+BRH:0
+DA:1,0
+LF:1
+LH:0
+end_of_record
+`
+	if out.String() != expected {
+		t.Errorf("wrong LCOV. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
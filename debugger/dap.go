@@ -0,0 +1,388 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// The program is run on a single thread as far as the protocol is
+// concerned.
+const threadID = 1
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type dapResponse struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+type dapSource struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type dapStackFrame struct {
+	ID     int        `json:"id"`
+	Name   string     `json:"name"`
+	Source *dapSource `json:"source,omitempty"`
+	Line   int        `json:"line"`
+	Column int        `json:"column"`
+}
+
+type dapScope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type dapBreakpoint struct {
+	Verified bool `json:"verified"`
+	Line     int  `json:"line"`
+}
+
+// A DAPServer lets an editor drive a Debugger over the Debug Adapter
+// Protocol.
+type DAPServer struct {
+	r  *textproto.Reader
+	d  *Debugger
+	mu sync.Mutex // guards w, seq and refs
+	w  io.Writer
+
+	seq  int
+	refs []Variable // what each variablesReference, less one, expands
+
+	program     string
+	stopOnEntry bool
+	launched    bool
+	configured  bool
+	started     bool
+	exited      chan struct{}
+}
+
+func NewDAPServer(r io.Reader, w io.Writer) *DAPServer {
+	return &DAPServer{
+		r:      textproto.NewReader(bufio.NewReader(r)),
+		w:      w,
+		d:      New(),
+		exited: make(chan struct{}),
+	}
+}
+
+// Output shows text written by the program in the editor. Category is
+// "stdout" or "stderr".
+func (s *DAPServer) Output(category, text string) {
+	s.send(&dapEvent{Type: "event", Event: "output", Body: map[string]string{"category": category, "output": text}})
+}
+
+// Serve handles requests until the client disconnects.
+func (s *DAPServer) Serve() error {
+	for {
+		req, err := s.read()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		body, err := s.handle(req)
+		resp := &dapResponse{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		s.send(resp)
+
+		switch req.Command {
+		case "initialize":
+			s.send(&dapEvent{Type: "event", Event: "initialized"})
+		case "launch", "configurationDone":
+			if s.launched && s.configured && !s.started {
+				s.start()
+			}
+		case "continue":
+			s.d.Continue()
+		case "next":
+			s.d.StepOver()
+		case "stepIn":
+			s.d.StepIn()
+		case "stepOut":
+			s.d.StepOut()
+		case "disconnect", "terminate":
+			if s.started {
+				s.d.Detach()
+			}
+			return nil
+		}
+	}
+}
+
+func (s *DAPServer) read() (*dapRequest, error) {
+	header, err := s.r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.r.R, body); err != nil {
+		return nil, err
+	}
+	req := &dapRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// send writes a response or event, numbering it.
+func (s *DAPServer) send(msg interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	switch msg := msg.(type) {
+	case *dapResponse:
+		msg.Seq = s.seq
+	case *dapEvent:
+		msg.Seq = s.seq
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// start runs the launched program, reporting its events to the client.
+func (s *DAPServer) start() {
+	s.started = true
+	program := s.program
+	s.d.Start(func() object.Object { return evaluator.EvalFile(program) }, s.stopOnEntry)
+
+	go func() {
+		for ev := range s.d.Events() {
+			if !ev.Exited() {
+				s.mu.Lock()
+				s.refs = nil
+				s.mu.Unlock()
+				s.send(&dapEvent{Type: "event", Event: "stopped", Body: map[string]interface{}{
+					"reason": ev.Reason, "threadId": threadID, "allThreadsStopped": true,
+				}})
+				continue
+			}
+
+			code := 0
+			if errObj, ok := ev.Result.(*object.Error); ok {
+				s.Output("stderr", errObj.Inspect()+"\n")
+				code = 1
+			}
+			s.send(&dapEvent{Type: "event", Event: "exited", Body: map[string]int{"exitCode": code}})
+			s.send(&dapEvent{Type: "event", Event: "terminated"})
+			close(s.exited)
+			return
+		}
+	}()
+}
+
+// Exited is closed once the launched program has finished.
+func (s *DAPServer) Exited() <-chan struct{} {
+	return s.exited
+}
+
+func (s *DAPServer) handle(req *dapRequest) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]bool{"supportsConfigurationDoneRequest": true}, nil
+
+	case "launch":
+		var args struct {
+			Program     string `json:"program"`
+			StopOnEntry bool   `json:"stopOnEntry"`
+		}
+		if err := decodeArguments(req, &args); err != nil {
+			return nil, err
+		}
+		if args.Program == "" {
+			return nil, errors.New("launch needs a program to run")
+		}
+		s.program, s.stopOnEntry, s.launched = args.Program, args.StopOnEntry, true
+		return nil, nil
+
+	case "configurationDone":
+		s.configured = true
+		return nil, nil
+
+	case "setBreakpoints":
+		var args struct {
+			Source      dapSource `json:"source"`
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		if err := decodeArguments(req, &args); err != nil {
+			return nil, err
+		}
+		lines := []int{}
+		verified := []dapBreakpoint{}
+		for _, bp := range args.Breakpoints {
+			lines = append(lines, bp.Line)
+			verified = append(verified, dapBreakpoint{Verified: true, Line: bp.Line})
+		}
+		s.d.SetBreakpoints(args.Source.Path, lines)
+		return map[string]interface{}{"breakpoints": verified}, nil
+
+	case "threads":
+		return map[string]interface{}{"threads": []map[string]interface{}{{"id": threadID, "name": "main"}}}, nil
+
+	case "stackTrace":
+		frames := []dapStackFrame{}
+		for i, f := range s.d.Stack() {
+			frame := dapStackFrame{ID: i + 1, Name: f.Name, Line: f.Line, Column: f.Column}
+			if f.File != "" {
+				frame.Source = &dapSource{Name: filepath.Base(f.File), Path: f.File}
+			}
+			frames = append(frames, frame)
+		}
+		return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+
+	case "scopes":
+		f, err := s.frame(req)
+		if err != nil {
+			return nil, err
+		}
+		scopes := []dapScope{}
+		for _, scope := range Scopes(f.Env) {
+			scopes = append(scopes, dapScope{Name: scope.Name, VariablesReference: s.reference(scope)})
+		}
+		return map[string]interface{}{"scopes": scopes}, nil
+
+	case "variables":
+		var args struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err := decodeArguments(req, &args); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if args.VariablesReference < 1 || args.VariablesReference > len(s.refs) {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+		}
+		v := s.refs[args.VariablesReference-1]
+		s.mu.Unlock()
+
+		vars := []dapVariable{}
+		for _, child := range v.Children() {
+			vars = append(vars, dapVariable{Name: child.Name, Value: child.Describe(), VariablesReference: s.reference(child)})
+		}
+		return map[string]interface{}{"variables": vars}, nil
+
+	case "evaluate":
+		var args struct {
+			Expression string `json:"expression"`
+		}
+		if err := decodeArguments(req, &args); err != nil {
+			return nil, err
+		}
+		f, err := s.frame(req)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := f.Env.Get(strings.TrimSpace(args.Expression))
+		if !ok {
+			return nil, fmt.Errorf("%s is not defined here", args.Expression)
+		}
+		v := Variable{Name: args.Expression, Value: value}
+		return map[string]interface{}{"result": v.Describe(), "variablesReference": s.reference(v)}, nil
+
+	case "continue":
+		return map[string]bool{"allThreadsContinued": true}, nil
+
+	case "pause":
+		s.d.Pause()
+		return nil, nil
+
+	case "next", "stepIn", "stepOut", "disconnect", "terminate":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request %s", req.Command)
+}
+
+// frame returns the stack frame named by the frameId argument of req.
+func (s *DAPServer) frame(req *dapRequest) (Frame, error) {
+	var args struct {
+		FrameID int `json:"frameId"`
+	}
+	if err := decodeArguments(req, &args); err != nil {
+		return Frame{}, err
+	}
+
+	frames := s.d.Stack()
+	if !s.d.Stopped() || args.FrameID < 1 || args.FrameID > len(frames) {
+		return Frame{}, fmt.Errorf("no stack frame %d", args.FrameID)
+	}
+	f := frames[args.FrameID-1]
+	if f.Env == nil {
+		return Frame{}, fmt.Errorf("stack frame %d has not started", args.FrameID)
+	}
+	return f, nil
+}
+
+// reference returns the variablesReference for v, or 0 if it has nothing
+// to expand.
+func (s *DAPServer) reference(v Variable) int {
+	if !v.Expandable() {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs = append(s.refs, v)
+	return len(s.refs)
+}
+
+func decodeArguments(req *dapRequest, v interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(req.Arguments, v)
+}
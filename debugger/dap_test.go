@@ -0,0 +1,349 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// dapMessage is a response or event as the client sees it.
+type dapMessage struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// dapClient drives a server over pipes, as an editor would.
+type dapClient struct {
+	t      *testing.T
+	r      *textproto.Reader
+	w      io.Writer
+	seq    int
+	events []*dapMessage // events read while waiting for a response
+	done   chan error
+}
+
+func newDAPClient(t *testing.T) *dapClient {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &dapClient{
+		t:    t,
+		r:    textproto.NewReader(bufio.NewReader(clientIn)),
+		w:    clientOut,
+		done: make(chan error, 1),
+	}
+	go func() {
+		c.done <- NewDAPServer(serverIn, serverOut).Serve()
+	}()
+	return c
+}
+
+func (c *dapClient) read() *dapMessage {
+	c.t.Helper()
+
+	type result struct {
+		msg *dapMessage
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		header, err := c.r.ReadMIMEHeader()
+		if err != nil {
+			ch <- result{err: err}
+			return
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(c.r.R, body); err != nil {
+			ch <- result{err: err}
+			return
+		}
+		msg := &dapMessage{}
+		ch <- result{msg: msg, err: json.Unmarshal(body, msg)}
+	}()
+
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			c.t.Fatalf("reading from server failed: %s", res.err)
+		}
+		return res.msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("timed out waiting for the server")
+	}
+	return nil
+}
+
+// request sends a request and returns its response, decoding the body
+// into body if it is not nil.
+func (c *dapClient) request(command string, args interface{}, body interface{}) *dapMessage {
+	c.t.Helper()
+
+	c.seq++
+	raw, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(raw), raw); err != nil {
+		c.t.Fatalf("writing %s failed: %s", command, err)
+	}
+
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg.RequestSeq != c.seq || msg.Command != command {
+			c.t.Fatalf("expected response to %s, got=%+v", command, msg)
+		}
+		if body != nil {
+			if !msg.Success {
+				c.t.Fatalf("%s failed: %s", command, msg.Message)
+			}
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatalf("decoding response to %s failed: %s", command, err)
+			}
+		}
+		return msg
+	}
+}
+
+// event waits for the named event, decoding its body into body if it is
+// not nil. Other events before it are dropped.
+func (c *dapClient) event(name string, body interface{}) {
+	c.t.Helper()
+
+	for {
+		var msg *dapMessage
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.read()
+		}
+		if msg.Type != "event" || msg.Event != name {
+			continue
+		}
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatalf("decoding %s event failed: %s", name, err)
+			}
+		}
+		return
+	}
+}
+
+type stoppedBody struct {
+	Reason   string `json:"reason"`
+	ThreadID int    `json:"threadId"`
+}
+
+type stackTraceBody struct {
+	StackFrames []dapStackFrame `json:"stackFrames"`
+}
+
+type scopesBody struct {
+	Scopes []dapScope `json:"scopes"`
+}
+
+type variablesBody struct {
+	Variables []dapVariable `json:"variables"`
+}
+
+func (c *dapClient) expectStopped(reason string) {
+	c.t.Helper()
+
+	var stopped stoppedBody
+	c.event("stopped", &stopped)
+	if stopped.Reason != reason || stopped.ThreadID != threadID {
+		c.t.Fatalf("expected stop for %q on thread %d, got=%+v", reason, threadID, stopped)
+	}
+}
+
+func (c *dapClient) stack() []dapStackFrame {
+	c.t.Helper()
+
+	var trace stackTraceBody
+	c.request("stackTrace", map[string]int{"threadId": threadID}, &trace)
+	return trace.StackFrames
+}
+
+func (c *dapClient) launch(path string, stopOnEntry bool, breakpoints ...int) {
+	c.t.Helper()
+
+	var capabilities map[string]bool
+	c.request("initialize", map[string]string{"adapterID": "monkey"}, &capabilities)
+	if !capabilities["supportsConfigurationDoneRequest"] {
+		c.t.Errorf("configurationDone not supported. got=%v", capabilities)
+	}
+	c.event("initialized", nil)
+
+	c.request("launch", map[string]interface{}{"program": path, "stopOnEntry": stopOnEntry}, nil)
+
+	lines := []map[string]int{}
+	for _, line := range breakpoints {
+		lines = append(lines, map[string]int{"line": line})
+	}
+	var set struct {
+		Breakpoints []dapBreakpoint `json:"breakpoints"`
+	}
+	c.request("setBreakpoints", map[string]interface{}{"source": map[string]string{"path": path}, "breakpoints": lines}, &set)
+	if len(set.Breakpoints) != len(breakpoints) {
+		c.t.Fatalf("wrong number of breakpoints set. expected=%d, got=%d", len(breakpoints), len(set.Breakpoints))
+	}
+	for i, bp := range set.Breakpoints {
+		if !bp.Verified || bp.Line != breakpoints[i] {
+			c.t.Errorf("breakpoints[%d] wrong. got=%+v", i, bp)
+		}
+	}
+
+	c.request("configurationDone", nil, nil)
+}
+
+func (c *dapClient) disconnect() {
+	c.t.Helper()
+
+	c.request("disconnect", nil, nil)
+	select {
+	case err := <-c.done:
+		if err != nil {
+			c.t.Errorf("server failed: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("server did not stop after disconnect")
+	}
+}
+
+func TestDAPSession(t *testing.T) {
+	path := writeProgram(t, program)
+	c := newDAPClient(t)
+	c.launch(path, false, 2)
+	c.expectStopped(ReasonBreakpoint)
+
+	var threads struct {
+		Threads []struct {
+			ID int `json:"id"`
+		} `json:"threads"`
+	}
+	c.request("threads", nil, &threads)
+	if len(threads.Threads) != 1 || threads.Threads[0].ID != threadID {
+		t.Errorf("wrong threads. got=%+v", threads.Threads)
+	}
+
+	frames := c.stack()
+	expected := []struct {
+		name string
+		line int
+	}{
+		{"add", 2},
+		{"inc", 5},
+		{"main", 7},
+	}
+	if len(frames) != len(expected) {
+		t.Fatalf("wrong stack depth. expected=%d, got=%d", len(expected), len(frames))
+	}
+	for i, tt := range expected {
+		f := frames[i]
+		if f.Name != tt.name || f.Line != tt.line || f.Source == nil || f.Source.Path != path || f.Source.Name != "main.mk" {
+			t.Errorf("frames[%d] wrong. expected=%s at line %d, got=%+v", i, tt.name, tt.line, f)
+		}
+	}
+
+	var scopes scopesBody
+	c.request("scopes", map[string]int{"frameId": 2}, &scopes)
+	names := []string{}
+	for _, scope := range scopes.Scopes {
+		names = append(names, scope.Name)
+	}
+	if fmt.Sprint(names) != "[Locals Closure Globals]" {
+		t.Fatalf("wrong scopes in inc. got=%v", names)
+	}
+
+	var vars variablesBody
+	c.request("variables", map[string]int{"variablesReference": scopes.Scopes[1].VariablesReference}, &vars)
+	if len(vars.Variables) != 1 || vars.Variables[0].Name != "n" || vars.Variables[0].Value != "1" {
+		t.Errorf("wrong closure variables. got=%+v", vars.Variables)
+	}
+
+	c.request("variables", map[string]int{"variablesReference": scopes.Scopes[2].VariablesReference}, &vars)
+	var makeRef int
+	for _, v := range vars.Variables {
+		if v.Name == "make" {
+			makeRef = v.VariablesReference
+		}
+	}
+	if makeRef == 0 {
+		t.Fatalf("function make is not expandable. got=%+v", vars.Variables)
+	}
+	c.request("variables", map[string]int{"variablesReference": makeRef}, &vars)
+	if len(vars.Variables) != 1 || vars.Variables[0].Name != "closure" {
+		t.Errorf("wrong children of make. got=%+v", vars.Variables)
+	}
+
+	var result struct {
+		Result string `json:"result"`
+	}
+	c.request("evaluate", map[string]interface{}{"expression": "a", "frameId": 1}, &result)
+	if result.Result != "2" {
+		t.Errorf("wrong value of a. got=%q", result.Result)
+	}
+	if resp := c.request("evaluate", map[string]interface{}{"expression": "x", "frameId": 1}, nil); resp.Success {
+		t.Errorf("evaluating a name from another frame succeeded")
+	}
+
+	c.request("next", map[string]int{"threadId": threadID}, nil)
+	c.expectStopped(ReasonStep)
+	if frames := c.stack(); frames[0].Line != 3 {
+		t.Errorf("next stopped at the wrong line. got=%d", frames[0].Line)
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID}, nil)
+	var exited struct {
+		ExitCode int `json:"exitCode"`
+	}
+	c.event("exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Errorf("wrong exit code. got=%d", exited.ExitCode)
+	}
+	c.event("terminated", nil)
+	c.disconnect()
+}
+
+func TestDAPError(t *testing.T) {
+	path := writeProgram(t, "let x = 1;\nx + true;\n")
+	c := newDAPClient(t)
+	c.launch(path, true)
+	c.expectStopped(ReasonEntry)
+
+	if resp := c.request("variables", map[string]int{"variablesReference": 42}, nil); resp.Success {
+		t.Errorf("unknown variables reference was accepted")
+	}
+	if resp := c.request("stepBack", nil, nil); resp.Success || resp.Message != "unsupported request stepBack" {
+		t.Errorf("wrong response to unsupported request. got=%+v", resp)
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID}, nil)
+	var output struct {
+		Category string `json:"category"`
+		Output   string `json:"output"`
+	}
+	c.event("output", &output)
+	if output.Category != "stderr" || output.Output != "ERROR: type mismatch: INTEGER + BOOLEAN\n" {
+		t.Errorf("wrong output. got=%+v", output)
+	}
+	var exited struct {
+		ExitCode int `json:"exitCode"`
+	}
+	c.event("exited", &exited)
+	if exited.ExitCode != 1 {
+		t.Errorf("wrong exit code. got=%d", exited.ExitCode)
+	}
+	c.disconnect()
+}
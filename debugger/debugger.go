@@ -0,0 +1,302 @@
+// Package debugger runs Monkey programs under control: it stops them at
+// breakpoints, steps through them and inspects their call stack and
+// environments. The same debugger backs the command-line debugger and the
+// Debug Adapter Protocol server.
+package debugger
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// Reasons a program stops.
+const (
+	ReasonEntry      = "entry"
+	ReasonBreakpoint = "breakpoint"
+	ReasonStep       = "step"
+	ReasonPause      = "pause"
+)
+
+// An Event tells that the program stopped, or that it finished.
+type Event struct {
+	Reason string        // why the program stopped, or "" if it finished
+	Result object.Object // what a finished program evaluated to
+}
+
+// Exited reports whether e tells that the program finished.
+func (e Event) Exited() bool { return e.Reason == "" }
+
+// A Frame is a function call in progress, or the top level of the program.
+type Frame struct {
+	Name         string // the function as called, or "main"
+	File         string // "" for code that isn't from a file
+	Line, Column int    // the position of the node being evaluated
+	Env          *object.Environment
+
+	// the statement last reached in the frame, so that a line with several
+	// statements is stepped over as one
+	stmtFile string
+	stmtLine int
+}
+
+type mode int
+
+const (
+	running mode = iota
+	pausing
+	entering
+	steppingIn
+	steppingOver
+	steppingOut
+)
+
+// A Debugger controls one program at a time. Its methods may be called
+// from any goroutine.
+type Debugger struct {
+	mu          sync.Mutex
+	breakpoints map[string]map[int]bool
+	mode        mode
+	depth       int // the stack depth a step started at
+	stack       []*Frame
+	stopped     bool
+
+	resume chan mode
+	events chan Event
+}
+
+func New() *Debugger {
+	return &Debugger{
+		breakpoints: map[string]map[int]bool{},
+		resume:      make(chan mode),
+		events:      make(chan Event),
+	}
+}
+
+// Events delivers an event each time the program stops and once when it
+// finishes. They must be received for the program to go on.
+func (d *Debugger) Events() <-chan Event {
+	return d.events
+}
+
+// Start runs eval, which evaluates a program, under the debugger on a new
+// goroutine. If stopOnEntry is set the program stops before its first
+// statement.
+func (d *Debugger) Start(eval func() object.Object, stopOnEntry bool) {
+	d.mu.Lock()
+	d.stack = []*Frame{{Name: "main"}}
+	d.mode = running
+	if stopOnEntry {
+		d.mode = entering
+	}
+	d.mu.Unlock()
+
+	go func() {
+		evaluator.SetTracer(d)
+		result := eval()
+		evaluator.SetTracer(nil)
+
+		d.mu.Lock()
+		d.stack = nil
+		d.mu.Unlock()
+		d.events <- Event{Result: result}
+	}()
+}
+
+// SetBreakpoints replaces the breakpoints in file with ones on lines.
+// Code that isn't from a file has the file "".
+func (d *Debugger) SetBreakpoints(file string, lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	set := map[int]bool{}
+	for _, line := range lines {
+		set[line] = true
+	}
+	d.breakpoints[cleanPath(file)] = set
+}
+
+// Breakpoints returns the lines with breakpoints in file, in order.
+func (d *Debugger) Breakpoints(file string) []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lines := []int{}
+	for line := range d.breakpoints[cleanPath(file)] {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func cleanPath(file string) string {
+	if file == "" {
+		return ""
+	}
+	return filepath.Clean(file)
+}
+
+// Continue runs a stopped program until the next breakpoint.
+func (d *Debugger) Continue() { d.proceed(running) }
+
+// StepIn runs a stopped program to the next statement, entering calls.
+func (d *Debugger) StepIn() { d.proceed(steppingIn) }
+
+// StepOver runs a stopped program to the next statement of the current
+// function, running calls to completion.
+func (d *Debugger) StepOver() { d.proceed(steppingOver) }
+
+// StepOut runs a stopped program until the current function returns.
+func (d *Debugger) StepOut() { d.proceed(steppingOut) }
+
+func (d *Debugger) proceed(m mode) {
+	d.mu.Lock()
+	stopped := d.stopped
+	d.stopped = false
+	d.mu.Unlock()
+
+	if stopped {
+		d.resume <- m
+	}
+}
+
+// Pause stops a running program at its next statement.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.stopped {
+		d.mode = pausing
+	}
+}
+
+// Detach drops all breakpoints and lets the program run to the end.
+func (d *Debugger) Detach() {
+	d.mu.Lock()
+	d.breakpoints = map[string]map[int]bool{}
+	d.mode = running
+	d.mu.Unlock()
+
+	d.Continue()
+}
+
+// Stopped reports whether the program is stopped.
+func (d *Debugger) Stopped() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.stopped
+}
+
+// Stack returns the calls in progress, innermost first. The frames are
+// only up to date while the program is stopped.
+func (d *Debugger) Stack() []Frame {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	frames := make([]Frame, len(d.stack))
+	for i, f := range d.stack {
+		frames[len(d.stack)-1-i] = *f
+	}
+	return frames
+}
+
+// Step implements evaluator.Tracer, stopping the program when a statement
+// is reached that a breakpoint or step asks for.
+func (d *Debugger) Step(node ast.Node, env *object.Environment) {
+	tok, ok := ast.TokenOf(node)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	if len(d.stack) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	f := d.stack[len(d.stack)-1]
+	f.Env, f.File, f.Line, f.Column = env, env.File(), tok.Line, tok.Column
+	if !isStatement(node) || (f.File == f.stmtFile && tok.Line == f.stmtLine) {
+		d.mu.Unlock()
+		return
+	}
+	f.stmtFile, f.stmtLine = f.File, tok.Line
+
+	reason := d.stopReason(f)
+	if reason == "" {
+		d.mu.Unlock()
+		return
+	}
+	d.stopped = true
+	d.mu.Unlock()
+
+	d.events <- Event{Reason: reason}
+	m := <-d.resume
+
+	d.mu.Lock()
+	d.mode, d.depth = m, len(d.stack)
+	d.mu.Unlock()
+}
+
+func (d *Debugger) stopReason(f *Frame) string {
+	if d.breakpoints[cleanPath(f.File)][f.Line] {
+		return ReasonBreakpoint
+	}
+
+	depth := len(d.stack)
+	switch {
+	case d.mode == entering:
+		return ReasonEntry
+	case d.mode == pausing:
+		return ReasonPause
+	case d.mode == steppingIn,
+		d.mode == steppingOver && depth <= d.depth,
+		d.mode == steppingOut && depth < d.depth:
+		return ReasonStep
+	}
+	return ""
+}
+
+func isStatement(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.LetStatement, *ast.ReturnStatement, *ast.ExpressionStatement,
+		*ast.ImportStatement, *ast.ExportStatement:
+		return true
+	}
+	return false
+}
+
+// Enter implements evaluator.Tracer. The caller's frame is left at the
+// call rather than at the last argument evaluated.
+func (d *Debugger) Enter(call *ast.CallExpression, fn *object.Function) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.stack) == 0 {
+		return
+	}
+	// A function called back by a builtin is called from wherever the
+	// caller already is.
+	if call != nil {
+		if tok, ok := ast.TokenOf(call.Function); ok {
+			caller := d.stack[len(d.stack)-1]
+			caller.Line, caller.Column = tok.Line, tok.Column
+		}
+	}
+	d.stack = append(d.stack, &Frame{Name: evaluator.CallName(call, fn)})
+}
+
+// Leave implements evaluator.Tracer.
+func (d *Debugger) Leave(call *ast.CallExpression, result object.Object) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.stack) > 1 {
+		d.stack = d.stack[:len(d.stack)-1]
+	}
+}
+
@@ -0,0 +1,199 @@
+package debugger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+const program = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let make = fn(n) { fn(x) { add(x, n) } };
+let inc = make(1);
+let y = inc(2);
+y;
+`
+
+func writeProgram(t *testing.T, source string) string {
+	path := filepath.Join(t.TempDir(), "main.mk")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func start(t *testing.T, stopOnEntry bool, breakpoints ...int) (*Debugger, string) {
+	path := writeProgram(t, program)
+	d := New()
+	d.SetBreakpoints(path, breakpoints)
+	d.Start(func() object.Object { return evaluator.EvalFile(path) }, stopOnEntry)
+	return d, path
+}
+
+func nextEvent(t *testing.T, d *Debugger) Event {
+	t.Helper()
+
+	select {
+	case ev := <-d.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the program neither stopped nor finished")
+	}
+	return Event{}
+}
+
+// expectStop checks that the program stopped for reason at line in the
+// function called name.
+func expectStop(t *testing.T, d *Debugger, reason, name string, line int) {
+	t.Helper()
+
+	ev := nextEvent(t, d)
+	if ev.Reason != reason {
+		t.Fatalf("expected stop for %q, got=%+v", reason, ev)
+	}
+	if !d.Stopped() {
+		t.Errorf("debugger does not report the program as stopped")
+	}
+	top := d.Stack()[0]
+	if top.Name != name || top.Line != line {
+		t.Errorf("expected stop in %s at line %d, got=%s at line %d", name, line, top.Name, top.Line)
+	}
+}
+
+func expectExit(t *testing.T, d *Debugger, expected int64) {
+	t.Helper()
+
+	ev := nextEvent(t, d)
+	if !ev.Exited() {
+		t.Fatalf("expected the program to finish, got stop for %q", ev.Reason)
+	}
+	result, ok := ev.Result.(*object.Integer)
+	if !ok || result.Value != expected {
+		t.Errorf("expected result %d, got=%+v", expected, ev.Result)
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	d, path := start(t, false, 2, 8)
+
+	if lines := d.Breakpoints(path); len(lines) != 2 || lines[0] != 2 || lines[1] != 8 {
+		t.Errorf("wrong breakpoints. got=%v", lines)
+	}
+
+	expectStop(t, d, ReasonBreakpoint, "add", 2)
+	d.Continue()
+	expectStop(t, d, ReasonBreakpoint, "main", 8)
+	d.Continue()
+	expectExit(t, d, 3)
+}
+
+func TestStepping(t *testing.T) {
+	d, _ := start(t, true)
+
+	expectStop(t, d, ReasonEntry, "main", 1)
+	d.StepOver()
+	expectStop(t, d, ReasonStep, "main", 5)
+	d.StepOver()
+	expectStop(t, d, ReasonStep, "main", 6)
+	d.StepOver()
+	expectStop(t, d, ReasonStep, "main", 7)
+	d.StepIn()
+	expectStop(t, d, ReasonStep, "inc", 5)
+	d.StepIn()
+	expectStop(t, d, ReasonStep, "add", 2)
+	d.StepOver()
+	expectStop(t, d, ReasonStep, "add", 3)
+	d.StepOut()
+	expectStop(t, d, ReasonStep, "main", 8)
+	d.StepIn()
+	expectExit(t, d, 3)
+}
+
+func TestStack(t *testing.T) {
+	d, path := start(t, false, 2)
+
+	expectStop(t, d, ReasonBreakpoint, "add", 2)
+	stack := d.Stack()
+
+	expected := []struct {
+		name   string
+		line   int
+		column int
+	}{
+		{"add", 2, 3},
+		{"inc", 5, 28},
+		{"main", 7, 9},
+	}
+	if len(stack) != len(expected) {
+		t.Fatalf("wrong stack depth. expected=%d, got=%d", len(expected), len(stack))
+	}
+	for i, tt := range expected {
+		f := stack[i]
+		if f.Name != tt.name || f.Line != tt.line || f.Column != tt.column || f.File != path {
+			t.Errorf("frames[%d] wrong. expected=%s at %s:%d:%d, got=%s at %s:%d:%d",
+				i, tt.name, path, tt.line, tt.column, f.Name, f.File, f.Line, f.Column)
+		}
+	}
+
+	if a, ok := stack[0].Env.Get("a"); !ok || a.Inspect() != "2" {
+		t.Errorf("wrong value of a in add. got=%v", a)
+	}
+	if n, ok := stack[1].Env.Get("n"); !ok || n.Inspect() != "1" {
+		t.Errorf("closure variable n not visible in inc. got=%v", n)
+	}
+
+	d.Detach()
+	expectExit(t, d, 3)
+}
+
+func TestBuiltinCallbacks(t *testing.T) {
+	path := writeProgram(t, `fn pred(x) {
+  x > 1
+}
+let out = filter([1, 2, 3], pred);
+let again = filter(out, pred);
+len(again);
+`)
+	d := New()
+	d.Start(func() object.Object { return evaluator.EvalFile(path) }, true)
+
+	expectStop(t, d, ReasonEntry, "main", 1)
+	d.StepOver()
+	expectStop(t, d, ReasonStep, "main", 4)
+	d.StepOver()
+	expectStop(t, d, ReasonStep, "main", 5)
+
+	d.SetBreakpoints(path, []int{2})
+	d.Continue()
+	expectStop(t, d, ReasonBreakpoint, "pred", 2)
+	if stack := d.Stack(); len(stack) != 2 || stack[1].Name != "main" || stack[1].Line != 5 {
+		t.Errorf("wrong stack in a filter predicate. got=%+v", stack)
+	}
+
+	d.SetBreakpoints(path, nil)
+	d.StepOut()
+	expectStop(t, d, ReasonStep, "main", 6)
+	d.Continue()
+	expectExit(t, d, 2)
+}
+
+func TestDetach(t *testing.T) {
+	d, path := start(t, true, 2, 8)
+
+	expectStop(t, d, ReasonEntry, "main", 1)
+	d.Detach()
+	expectExit(t, d, 3)
+
+	if lines := d.Breakpoints(path); len(lines) != 0 {
+		t.Errorf("breakpoints were kept after detaching. got=%v", lines)
+	}
+	if d.Stopped() {
+		t.Errorf("debugger reports a finished program as stopped")
+	}
+}
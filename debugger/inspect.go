@@ -0,0 +1,130 @@
+package debugger
+
+import (
+	"strconv"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// A Variable is something shown when inspecting a stopped program: a name
+// bound in an environment, an element of an array or hash, or the
+// environment a closure captured.
+type Variable struct {
+	Name  string
+	Value object.Object       // nil for environments
+	Env   *object.Environment // set for environments
+
+	// scope is set for the environments returned by Scopes, which list
+	// their outer environments separately
+	scope bool
+}
+
+// Describe returns a one-line summary of v.
+func (v Variable) Describe() string {
+	if v.Env != nil {
+		return "environment"
+	}
+	return Describe(v.Value)
+}
+
+// Children returns what v contains, or nothing if it can't be expanded.
+func (v Variable) Children() []Variable {
+	if v.Env != nil {
+		vars := EnvVariables(v.Env)
+		if outer := v.Env.Outer(); outer != nil && !v.scope {
+			vars = append(vars, Variable{Name: "outer", Env: outer})
+		}
+		return vars
+	}
+
+	switch obj := v.Value.(type) {
+	case *object.Array:
+		children := make([]Variable, len(obj.Elements))
+		for i, el := range obj.Elements {
+			children[i] = Variable{Name: "[" + strconv.Itoa(i) + "]", Value: el}
+		}
+		return children
+	case *object.Hash:
+		children := []Variable{}
+		for _, pair := range obj.Pairs() {
+			children = append(children, Variable{Name: pair.Key.Inspect(), Value: pair.Value})
+		}
+		return children
+	case *object.Function:
+		return []Variable{{Name: "closure", Env: obj.Env}}
+	case *object.Module:
+		return sortedVariables(obj.Exports)
+	}
+	return nil
+}
+
+// Expandable reports whether v has children to show.
+func (v Variable) Expandable() bool {
+	if v.Env != nil {
+		return true
+	}
+
+	switch obj := v.Value.(type) {
+	case *object.Array:
+		return len(obj.Elements) > 0
+	case *object.Hash:
+		return len(obj.Pairs()) > 0
+	case *object.Function, *object.Module:
+		return true
+	}
+	return false
+}
+
+// EnvVariables returns the names bound in env itself.
+func EnvVariables(env *object.Environment) []Variable {
+	vars := []Variable{}
+	for _, name := range env.Names() {
+		value, _ := env.Get(name)
+		vars = append(vars, Variable{Name: name, Value: value})
+	}
+	return vars
+}
+
+func sortedVariables(m map[string]object.Object) []Variable {
+	env := object.NewEnvironment()
+	for name, value := range m {
+		env.Set(name, value)
+	}
+	return EnvVariables(env)
+}
+
+// Describe returns a one-line summary of obj, shortening functions to
+// their names and parameters.
+func Describe(obj object.Object) string {
+	switch obj := obj.(type) {
+	case nil:
+		return "null"
+	case *object.Function:
+		name := ""
+		if obj.Name != "" {
+			name = " " + obj.Name
+		}
+		return "fn" + name + "(" + ast.ParameterList(obj.Parameters, obj.Rest) + ")"
+	case *object.Builtin:
+		return "builtin function"
+	}
+	return obj.Inspect()
+}
+
+// Scopes returns the environments visible from env, innermost first: the
+// frame's own, those it is enclosed in and, last, the global one.
+func Scopes(env *object.Environment) []Variable {
+	scopes := []Variable{}
+	for e := env; e != nil; e = e.Outer() {
+		name := "Locals"
+		switch {
+		case e.Outer() == nil:
+			name = "Globals"
+		case e != env:
+			name = "Closure"
+		}
+		scopes = append(scopes, Variable{Name: name, Env: e, scope: true})
+	}
+	return scopes
+}
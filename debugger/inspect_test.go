@@ -0,0 +1,146 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+func evalEnv(t *testing.T, input string) *object.Environment {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	env := object.NewEnvironment()
+	if errObj, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		t.Fatalf("evaluation failed: %s", errObj.Inspect())
+	}
+	return env
+}
+
+type child struct {
+	name  string
+	value string
+}
+
+func testChildren(t *testing.T, v Variable, expected []child) {
+	t.Helper()
+
+	children := v.Children()
+	if len(children) != len(expected) {
+		t.Fatalf("wrong number of children of %s. expected=%d, got=%d (%+v)", v.Name, len(expected), len(children), children)
+	}
+	for i, tt := range expected {
+		if children[i].Name != tt.name || children[i].Describe() != tt.value {
+			t.Errorf("children of %s [%d] wrong. expected=%s = %s, got=%s = %s",
+				v.Name, i, tt.name, tt.value, children[i].Name, children[i].Describe())
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	env := evalEnv(t, `let f = fn(a, b) { a }; let xs = [1, "two"]; let h = {"k": true}; fn g(...r) { r }`)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"f", "fn(a, b)"},
+		{"g", "fn g(...r)"},
+		{"xs", `[1, two]`},
+		{"h", `{k: true}`},
+		{"len", "builtin function"},
+	}
+
+	for _, tt := range tests {
+		value, _ := env.Get(tt.name)
+		if tt.name == "len" {
+			value = &object.Builtin{}
+		}
+		if got := Describe(value); got != tt.expected {
+			t.Errorf("Describe(%s) wrong. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+
+	if got := Describe(nil); got != "null" {
+		t.Errorf("Describe(nil) wrong. expected=%q, got=%q", "null", got)
+	}
+}
+
+func TestChildren(t *testing.T) {
+	env := evalEnv(t, `
+		let xs = [1, [2]];
+		let h = {"a": 1, 2: "b"};
+		let make = fn(n) { fn(x) { x + n } };
+		let inc = make(1);
+	`)
+
+	xs, _ := env.Get("xs")
+	testChildren(t, Variable{Name: "xs", Value: xs}, []child{{"[0]", "1"}, {"[1]", "[2]"}})
+
+	h, _ := env.Get("h")
+	testChildren(t, Variable{Name: "h", Value: h}, []child{{"a", "1"}, {"2", "b"}})
+
+	inc, _ := env.Get("inc")
+	v := Variable{Name: "inc", Value: inc}
+	testChildren(t, v, []child{{"closure", "environment"}})
+
+	closure := v.Children()[0]
+	testChildren(t, closure, []child{{"n", "1"}, {"outer", "environment"}})
+	testChildren(t, closure.Children()[1], []child{
+		{"h", `{a: 1, 2: b}`},
+		{"inc", "fn(x)"},
+		{"make", "fn(n)"},
+		{"xs", "[1, [2]]"},
+	})
+}
+
+func TestExpandable(t *testing.T) {
+	env := evalEnv(t, `let empty = []; let one = [1]; let n = 5; let f = fn() { 1 };`)
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"empty", false},
+		{"one", true},
+		{"n", false},
+		{"f", true},
+	}
+
+	for _, tt := range tests {
+		value, _ := env.Get(tt.name)
+		if got := (Variable{Name: tt.name, Value: value}).Expandable(); got != tt.expected {
+			t.Errorf("%s expandable wrong. expected=%t, got=%t", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestScopes(t *testing.T) {
+	globals := object.NewEnvironment()
+	globals.Set("g", &object.Integer{Value: 1})
+	closure := object.NewEnclosedEnvironment(globals)
+	closure.Set("n", &object.Integer{Value: 2})
+	locals := object.NewEnclosedEnvironment(closure)
+	locals.Set("x", &object.Integer{Value: 3})
+
+	scopes := Scopes(locals)
+	expected := []struct {
+		name string
+		vars []child
+	}{
+		{"Locals", []child{{"x", "3"}}},
+		{"Closure", []child{{"n", "2"}}},
+		{"Globals", []child{{"g", "1"}}},
+	}
+
+	if len(scopes) != len(expected) {
+		t.Fatalf("wrong number of scopes. expected=%d, got=%d", len(expected), len(scopes))
+	}
+	for i, tt := range expected {
+		if scopes[i].Name != tt.name {
+			t.Errorf("scopes[%d] wrong name. expected=%q, got=%q", i, tt.name, scopes[i].Name)
+		}
+		testChildren(t, scopes[i], tt.vars)
+	}
+}
@@ -0,0 +1,203 @@
+package evaluator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// The assertion builtins are added in init because assert_error calls
+// functions, which refer back to the builtins.
+func init() {
+	builtins["assert"] = &object.Builtin{Fn: assert}
+	builtins["assert_eq"] = &object.Builtin{Fn: assertEq}
+	builtins["assert_error"] = &object.Builtin{Fn: assertError}
+}
+
+// assertMessage returns the optional message argument of an assertion at
+// index i, or an error if it isn't a string.
+func assertMessage(name string, args []object.Object, i int) (string, *object.Error) {
+	if len(args) <= i {
+		return "", nil
+	}
+	msg, ok := args[i].(*object.String)
+	if !ok {
+		return "", newError("message of '%s' must be STRING, got %s", name, args[i].Type())
+	}
+	return msg.Value, nil
+}
+
+func failure(name, msg string) string {
+	if msg == "" {
+		return name + " failed"
+	}
+	return name + " failed: " + msg
+}
+
+func assert(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	cond, ok := args[0].(*object.Boolean)
+	if !ok {
+		return newError("argument to 'assert' must be BOOLEAN, got %s", args[0].Type())
+	}
+	msg, err := assertMessage("assert", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if !cond.Value {
+		return newError("%s", failure("assert", msg))
+	}
+	return NULL
+}
+
+func assertEq(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	msg, err := assertMessage("assert_eq", args, 2)
+	if err != nil {
+		return err
+	}
+
+	actual, expected := args[0], args[1]
+	if object.Equal(actual, expected) {
+		return NULL
+	}
+	diff := diffLines(prettyLines(expected), prettyLines(actual))
+	return newError("%s (-expected +actual)\n%s", failure("assert_eq", msg), strings.Join(diff, "\n"))
+}
+
+func assertError(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	switch fn := args[0].(type) {
+	case *object.Function:
+		if n := requiredParameters(fn); n != 0 {
+			return newError("argument to 'assert_error' must be a function without parameters, got %d", n)
+		}
+	case *object.Builtin:
+	default:
+		return newError("argument to 'assert_error' must be FUNCTION, got %s", args[0].Type())
+	}
+	want, err := assertMessage("assert_error", args, 1)
+	if err != nil {
+		return err
+	}
+
+	result := applyTracedFunction(nil, args[0], nil)
+	got, ok := result.(*object.Error)
+	if !ok {
+		inspected := "null"
+		if result != nil {
+			inspected = result.Inspect()
+		}
+		return newError("assert_error failed: expected an error, got %s", inspected)
+	}
+	if !strings.Contains(got.Message, want) {
+		return newError("assert_error failed: error %q does not contain %q", got.Message, want)
+	}
+	return NULL
+}
+
+// prettyLines prints obj with each element of an array or hash on a line
+// of its own, so that a diff points at the elements that differ. Strings
+// are quoted to tell them apart from other values, and hash pairs are
+// sorted since their order doesn't matter to equality.
+func prettyLines(obj object.Object) []string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return []string{strconv.Quote(obj.Value)}
+	case *object.Array:
+		if len(obj.Elements) == 0 {
+			return []string{"[]"}
+		}
+		lines := []string{"["}
+		for _, e := range obj.Elements {
+			lines = append(lines, indent(prettyLines(e), "")...)
+		}
+		return append(lines, "]")
+	case *object.Hash:
+		if obj.Len() == 0 {
+			return []string{"{}"}
+		}
+		type pair struct {
+			key   string
+			value []string
+		}
+		pairs := []pair{}
+		for _, p := range obj.Pairs() {
+			pairs = append(pairs, pair{strings.Join(prettyLines(p.Key), " "), prettyLines(p.Value)})
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
+
+		lines := []string{"{"}
+		for _, p := range pairs {
+			lines = append(lines, indent(p.value, p.key+": ")...)
+		}
+		return append(lines, "}")
+	case nil:
+		return []string{"null"}
+	default:
+		return []string{obj.Inspect()}
+	}
+}
+
+// indent indents the lines of one element, putting prefix before its first
+// line and a comma after its last.
+func indent(lines []string, prefix string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if i == 0 {
+			line = prefix + line
+		}
+		out[i] = "  " + line
+	}
+	out[len(out)-1] += ","
+	return out
+}
+
+// diffLines returns the lines of a and b marked "-" if only in a, "+" if
+// only in b and " " if in both, using their longest common subsequence.
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	out := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	return out
+}
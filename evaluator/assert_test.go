@@ -0,0 +1,48 @@
+package evaluator
+
+import "testing"
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // nil for success, otherwise the error message
+	}{
+		{`assert(1 < 2)`, nil},
+		{`assert(1 > 2)`, "assert failed"},
+		{`assert(false, "math is broken")`, "assert failed: math is broken"},
+		{`assert(1)`, "argument to 'assert' must be BOOLEAN, got INTEGER"},
+		{`assert(true, 1)`, "message of 'assert' must be STRING, got INTEGER"},
+		{`assert()`, "wrong number of arguments. got=0, want=1 or 2"},
+
+		{`assert_eq(1 + 1, 2)`, nil},
+		{`assert_eq([1, {"a": 2, "b": 3}], [1, {"b": 3, "a": 2}])`, nil},
+		{`assert_eq(3, 4)`, "assert_eq failed (-expected +actual)\n- 4\n+ 3"},
+		{`assert_eq("1", 1, "types")`, "assert_eq failed: types (-expected +actual)\n- 1\n+ \"1\""},
+		{`assert_eq([1, 2, 3], [1, 5, 3])`,
+			"assert_eq failed (-expected +actual)\n  [\n    1,\n-   5,\n+   2,\n    3,\n  ]"},
+		{`assert_eq({"b": [1], "a": 1}, {"a": 1, "b": []})`,
+			"assert_eq failed (-expected +actual)\n  {\n    \"a\": 1,\n-   \"b\": [],\n+   \"b\": [\n+     1,\n+   ],\n  }"},
+
+		{`assert_error(fn() { 1 + "a" })`, nil},
+		{`assert_error(fn() { 1 + "a" }, "type mismatch")`, nil},
+		{`assert_error(fn() { 1 + "a" }, "unknown")`,
+			`assert_error failed: error "type mismatch: INTEGER + STRING" does not contain "unknown"`},
+		{`assert_error(fn() { 1 })`, "assert_error failed: expected an error, got 1"},
+		{`assert_error(fn(x) { x })`, "argument to 'assert_error' must be a function without parameters, got 1"},
+		{`assert_error(1)`, "argument to 'assert_error' must be FUNCTION, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if tt.expected == nil {
+			testNullObject(t, evaluated)
+			continue
+		}
+		testErrorObject(t, evaluated, tt.expected.(string))
+	}
+}
+
+func TestTestStatementsAreSkipped(t *testing.T) {
+	evaluated := testEval(`let x = 1; test "fails" { assert(false) } x`)
+	testIntegerObject(t, evaluated, 1)
+}
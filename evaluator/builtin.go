@@ -2,10 +2,36 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/OlyaIvanovs/interpreter_in_go/object"
 )
 
+// Output is where puts writes.
+var Output io.Writer = os.Stdout
+
+// IsBuiltin reports whether name refers to a builtin function.
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
+
+// BuiltinNames returns the names of the builtin functions, sorted.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
@@ -15,9 +41,11 @@ var builtins = map[string]*object.Builtin{
 		
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(arg.Len())}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -95,13 +123,446 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},	
+	"split": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to 'split' must be STRING, got %s", args[0].Type())
+			}
+
+			var parts []string
+			if len(args) == 1 {
+				parts = strings.Fields(str.Value)
+			} else {
+				sep, ok := args[1].(*object.String)
+				if !ok {
+					return newError("separator for 'split' must be STRING, got %s", args[1].Type())
+				}
+				parts = strings.Split(str.Value, sep.Value)
+			}
+
+			return stringsToArray(parts)
+		},
+	},
+	"join": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to 'join' must be ARRAY, got %s", args[0].Type())
+			}
+
+			sep := ""
+			if len(args) == 2 {
+				sepObj, ok := args[1].(*object.String)
+				if !ok {
+					return newError("separator for 'join' must be STRING, got %s", args[1].Type())
+				}
+				sep = sepObj.Value
+			}
+
+			parts := make([]string, len(arr.Elements))
+			for i, el := range arr.Elements {
+				str, ok := el.(*object.String)
+				if !ok {
+					return newError("element %d passed to 'join' must be STRING, got %s", i, el.Type())
+				}
+				parts[i] = str.Value
+			}
+
+			return &object.String{Value: strings.Join(parts, sep)}
+		},
+	},
+	"trim": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to 'trim' must be STRING, got %s", args[0].Type())
+			}
+			if len(args) == 1 {
+				return &object.String{Value: strings.TrimSpace(str.Value)}
+			}
+
+			cutset, ok := args[1].(*object.String)
+			if !ok {
+				return newError("cutset for 'trim' must be STRING, got %s", args[1].Type())
+			}
+			return &object.String{Value: strings.Trim(str.Value, cutset.Value)}
+		},
+	},
+	"upper": stringTransformBuiltin("upper", strings.ToUpper),
+	"lower": stringTransformBuiltin("lower", strings.ToLower),
+	"contains": stringPredicateBuiltin("contains", strings.Contains),
+	"starts_with": stringPredicateBuiltin("starts_with", strings.HasPrefix),
+	"ends_with": stringPredicateBuiltin("ends_with", strings.HasSuffix),
+	"replace": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+			for _, arg := range args {
+				if arg.Type() != object.STRING_OBJ {
+					return newError("arguments to 'replace' must be STRING, got %s", arg.Type())
+				}
+			}
+
+			str := args[0].(*object.String).Value
+			old := args[1].(*object.String).Value
+			new := args[2].(*object.String).Value
+			return &object.String{Value: strings.ReplaceAll(str, old, new)}
+		},
+	},
+	"repeat": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to 'repeat' must be STRING, got %s", args[0].Type())
+			}
+			count, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("count for 'repeat' must be INTEGER, got %s", args[1].Type())
+			}
+			if count.Value < 0 {
+				return newError("count for 'repeat' must not be negative, got %d", count.Value)
+			}
+			if len(str.Value) > 0 && count.Value > math.MaxInt/int64(len(str.Value)) {
+				return newError("count for 'repeat' is too large, got %d", count.Value)
+			}
+
+			return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+		},
+	},
+	"substr": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 2 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to 'substr' must be STRING, got %s", args[0].Type())
+			}
+
+			runes := []rune(str.Value)
+			length := int64(len(runes))
+
+			bounds := []int64{0, length}
+			for i, arg := range args[1:] {
+				idx, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("indices for 'substr' must be INTEGER, got %s", arg.Type())
+				}
+				bounds[i] = clampIndex(idx.Value, length)
+			}
+
+			start, end := bounds[0], bounds[1]
+			if start >= end {
+				return &object.String{Value: ""}
+			}
+			return &object.String{Value: string(runes[start:end])}
+		},
+	},
+	"chars": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to 'chars' must be STRING, got %s", args[0].Type())
+			}
+
+			chars := []string{}
+			for _, r := range str.Value {
+				chars = append(chars, string(r))
+			}
+			return stringsToArray(chars)
+		},
+	},
+	"to_int": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				return &object.Integer{Value: int64(arg.Value)}
+			case *object.String:
+				value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+				if err != nil {
+					return newError("could not convert %q to INTEGER", arg.Value)
+				}
+				return &object.Integer{Value: value}
+			case *object.Boolean:
+				if arg.Value {
+					return &object.Integer{Value: 1}
+				}
+				return &object.Integer{Value: 0}
+			default:
+				return newError("argument to 'to_int' not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"to_string": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+	"keys": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'keys' must be HASH, got %s", args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for _, pair := range hash.Pairs() {
+				elements = append(elements, pair.Key)
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"values": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'values' must be HASH, got %s", args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for _, pair := range hash.Pairs() {
+				elements = append(elements, pair.Value)
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"items": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'items' must be HASH, got %s", args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for _, pair := range hash.Pairs() {
+				item := &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+				elements = append(elements, item)
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"has": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'has' must be HASH, got %s", args[0].Type())
+			}
+			key, ok := object.AsHashable(args[1])
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			_, ok = hash.Get(key)
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+	"set": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'set' must be HASH, got %s", args[0].Type())
+			}
+			key, ok := object.AsHashable(args[1])
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			newHash := hash.Copy()
+			newHash.Set(key, args[2])
+			return newHash
+		},
+	},
+	"delete": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'delete' must be HASH, got %s", args[0].Type())
+			}
+			key, ok := object.AsHashable(args[1])
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			newHash := hash.Copy()
+			newHash.Delete(key)
+			return newHash
+		},
+	},
+	"merge": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want at least 1", len(args))
+			}
+
+			merged := object.NewHash()
+			for _, arg := range args {
+				hash, ok := arg.(*object.Hash)
+				if !ok {
+					return newError("arguments to 'merge' must be HASH, got %s", arg.Type())
+				}
+				for _, pair := range hash.Pairs() {
+					merged.Set(pair.Key.(object.Hashable), pair.Value)
+				}
+			}
+			return merged
+		},
+	},
+	"json_parse": &object.Builtin{Fn: jsonParse},
+	"json_stringify": &object.Builtin{Fn: jsonStringify},
 	"puts": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range(args) {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(Output, arg.Inspect())
 			}
 			
 			return NULL
 		},
 	},
 }
+
+// filter is added in init because it calls functions, which refer back to
+// the builtins.
+func init() {
+	builtins["filter"] = &object.Builtin{Fn: filter}
+}
+
+// filter returns the elements of an array for which a predicate holds,
+// deciding its results as conditions.
+func filter(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to 'filter' must be ARRAY, got %s", args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return newError("predicate for 'filter' must be FUNCTION, got %s", args[1].Type())
+	}
+
+	kept := []object.Object{}
+	for _, element := range array.Elements {
+		result := applyTracedFunction(nil, args[1], []object.Object{element})
+		if isError(result) {
+			return result
+		}
+		keep, err := truthy(result)
+		if err != nil {
+			return err
+		}
+		if keep {
+			kept = append(kept, element)
+		}
+	}
+	return &object.Array{Elements: kept}
+}
+
+// stringTransformBuiltin wraps a func(string) string as a one-argument builtin.
+func stringTransformBuiltin(name string, transform func(string) string) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to '%s' must be STRING, got %s", name, args[0].Type())
+			}
+
+			return &object.String{Value: transform(str.Value)}
+		},
+	}
+}
+
+// stringPredicateBuiltin wraps a func(s, sub string) bool as a two-argument builtin.
+func stringPredicateBuiltin(name string, predicate func(string, string) bool) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			for _, arg := range args {
+				if arg.Type() != object.STRING_OBJ {
+					return newError("arguments to '%s' must be STRING, got %s", name, arg.Type())
+				}
+			}
+
+			str := args[0].(*object.String).Value
+			sub := args[1].(*object.String).Value
+			return nativeBoolToBooleanObject(predicate(str, sub))
+		},
+	}
+}
+
+func stringsToArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, v := range values {
+		elements[i] = &object.String{Value: v}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// clampIndex resolves a possibly negative index against length and clamps
+// it to [0, length].
+func clampIndex(idx, length int64) int64 {
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 {
+		return 0
+	}
+	if idx > length {
+		return length
+	}
+	return idx
+}
@@ -14,7 +14,15 @@ var (
 	NULL  = &object.Null{}
 )
 
+// Strict makes conditions that are not booleans errors, rather than
+// deciding them by object.Truthy.
+var Strict bool
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if tracer != nil {
+		tracer.Step(node, env)
+	}
+	
 	switch node := node.(type) {
 	// Expressions
 	case *ast.IntegerLiteral:
@@ -26,9 +34,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		if node.Name == nil {
+			return newFunction(node, env)
+		}
+		// a named function can call itself by its name wherever it is
+		inner := object.NewEnclosedEnvironment(env)
+		fn := newFunction(node, inner)
+		inner.Set(node.Name.Value, fn)
+		return fn
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -36,6 +49,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -47,7 +63,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalInfixExpression(node.Operator, left, right) 
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.MacroLiteral:
+		return newError("macro literals must be bound by a top-level let statement")
 	case *ast.CallExpression:
+		if ident, ok := node.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+			if len(node.Arguments) != 1 {
+				return newError("wrong number of arguments to 'quote'. got=%d, want=1", len(node.Arguments))
+			}
+			return quote(node.Arguments[0], env)
+		}
+		
 		function:= Eval(node.Function, env)
 		if isError(function) {
 			return function
@@ -56,7 +83,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return applyTracedFunction(node, function, args)
+	case *ast.SpreadExpression:
+		return newError("... can only spread the arguments of a call")
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -69,12 +98,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return array
 		}
 		index := Eval(node.Index, env)
-		if isError(array) {
+		if isError(index) {
 			return index
 		}
 		return evalIndexExpression(array, index) 
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
 				
 	// Statements
 	case *ast.BlockStatement:
@@ -86,13 +117,25 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
+		if node.IsDeclaration() {
+			// bound by Hoist before the statements around it ran
+			return nil
+		}
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := bind(node.Name, val, env, node.IsConst()); err != nil {
+			return err
+		}
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *ast.ExportStatement:
+		return Eval(node.Statement, env)
+	case *ast.TestStatement:
+		// tests only run under the test runner
+		return nil
 	case *ast.Program:
-		fmt.Printf("program %s\n", node.String())
 		return evalProgram(node, env)	
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
@@ -104,6 +147,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 	
+	if err := Hoist(program.Statements, env); err != nil {
+		return err
+	}
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 		
@@ -118,9 +164,55 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
+// Hoist binds the functions declared among stmts before any of them run,
+// so that they can be called before their declarations and from each
+// other. Eval does this for programs and blocks; tools evaluating
+// statements one at a time call it first. It fails if a declared name is
+// a constant of env or is bound by a const statement among stmts.
+func Hoist(stmts []ast.Statement, env *object.Environment) *object.Error {
+	constants := map[string]bool{}
+	for _, stmt := range stmts {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+		if let, ok := stmt.(*ast.LetStatement); ok && let.IsConst() {
+			for _, name := range ast.PatternNames(let.Name) {
+				constants[name.Value] = true
+			}
+		}
+	}
+
+	for _, stmt := range stmts {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+		if let, ok := stmt.(*ast.LetStatement); ok && let.IsDeclaration() {
+			fl := let.Value.(*ast.FunctionLiteral)
+			if constants[fl.Name.Value] {
+				return newError("cannot redeclare constant %s", fl.Name.Value)
+			}
+			if err := define(env, fl.Name.Value, newFunction(fl, env), false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newFunction(fl *ast.FunctionLiteral, env *object.Environment) *object.Function {
+	fn := &object.Function{Parameters: fl.Parameters, Rest: fl.Rest, Env: env, Body: fl.Body}
+	if fl.Name != nil {
+		fn.Name = fl.Name.Value
+	}
+	return fn
+}
+
 func evalBlockStatements(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 	
+	if err := Hoist(block.Statements, env); err != nil {
+		return err
+	}
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 		
@@ -132,6 +224,11 @@ func evalBlockStatements(block *ast.BlockStatement, env *object.Environment) obj
 		}
 	}
 	
+	// a block is an expression, so it needs a value even when it is empty
+	// or ends in a statement without one
+	if result == nil {
+		return NULL
+	}
 	return result
 }
 
@@ -139,11 +236,25 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	var result []object.Object
 	
 	for _, e := range exps {
+		spread, ok := e.(*ast.SpreadExpression)
+		if ok {
+			e = spread.Value
+		}
+		
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
-		result = append(result, evaluated)
+		if !ok {
+			result = append(result, evaluated)
+			continue
+		}
+		
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			return []object.Object{newError("cannot spread %s, only ARRAY", evaluated.Type())}
+		}
+		result = append(result, array.Elements...)
 	}
 	
 	return result
@@ -155,38 +266,82 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 		return condition
 	} 
 	
- 	if condition.Type() == object.INTEGER_OBJ || (condition.(*object.Boolean).Value == true) {
+	ok, err := truthy(condition)
+	if err != nil {
+		return err
+	}
+	
+	if ok {
+		traceBranch(ie, true)
 		return Eval(ie.Consequence, env)
-	} else if ie.Alternative != nil {
+	} 
+	
+	traceBranch(ie, false)
+	if ie.Alternative != nil {
 		return Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+// evalMatchExpression evaluates the body of the first arm whose pattern
+// matches the value and whose guard, if it has one, holds. Each arm binds
+// its names in an environment of its own.
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	value := Eval(me.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	for _, arm := range me.Arms {
+		if !matches(arm.Pattern, value) {
+			continue
+		}
+		armEnv := object.NewEnclosedEnvironment(env)
+		if err := bind(arm.Pattern, value, armEnv, false); err != nil {
+			return err
+		}
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			ok, err := truthy(guard)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newError("no pattern matches %s", value.Inspect())
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 	
-	for k, v  := range node.Pairs {
+	for _, k := range node.Keys {
 		key := Eval(k, env)
 		if isError(key) {
 			return key
 		}
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 		
-		value := Eval(v, env)
+		value := Eval(node.Pairs[k], env)
 		if isError(value) {
 			return value
 		}
 		
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, value)
 	}
 	
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalPrefixExpression(operator string, right object.Object) object.Object {
@@ -197,19 +352,21 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalMinusPrefixOperator(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
-	}		
-	
-	return nil
+	}
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right) && (left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ:
 		return evalBooleanInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringLiteralInfixExpression(operator, left, right)
+	case (operator == "==" || operator == "!=") && isStructuralComparison(left, right):
+		return evalEqualityExpression(operator, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -229,16 +386,19 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal} 
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / 0", leftVal)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 		
 	case "<":
-		return &object.Boolean{Value: leftVal < rightVal}
+		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
-		return &object.Boolean{Value: leftVal > rightVal}
+		return nativeBoolToBooleanObject(leftVal > rightVal)
 	case "==":
-		return &object.Boolean{Value: leftVal == rightVal}
+		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
-		return &object.Boolean{Value: leftVal != rightVal}
+		return nativeBoolToBooleanObject(leftVal != rightVal)
 
 	
 	default:
@@ -246,15 +406,54 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+	
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+		
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	
+	default:
+		return  newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	if integer, ok := obj.(*object.Integer); ok {
+		return float64(integer.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
 func evalBooleanInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Boolean).Value
 	rightVal := right.(*object.Boolean).Value
 	
 	switch operator {
 	case "==":
-		return &object.Boolean{Value: leftVal == rightVal}
+		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
-		return &object.Boolean{Value: leftVal != rightVal}
+		return nativeBoolToBooleanObject(leftVal != rightVal)
 	
 	default:
 		return  newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
@@ -268,11 +467,42 @@ func evalStringLiteralInfixExpression(operator string, left, right object.Object
 	switch operator {
 	case "+":
 		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+// isStructuralComparison reports whether == compares left and right by
+// value: arrays with arrays, hashes with hashes, and null with anything.
+func isStructuralComparison(left, right object.Object) bool {
+	if left.Type() == object.NULL_OBJ || right.Type() == object.NULL_OBJ {
+		return true
+	}
+	
+	switch left.Type() {
+	case object.ARRAY_OBJ, object.HASH_OBJ:
+		return left.Type() == right.Type()
+	default:
+		return false
+	}
+}
+
+func evalEqualityExpression(operator string, left, right object.Object) object.Object {
+	equal := object.Equal(left, right)
+	if operator == "!=" {
+		return nativeBoolToBooleanObject(!equal)
+	}
+	return nativeBoolToBooleanObject(equal)
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
@@ -286,6 +516,10 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 }
 
 func evalMinusPrefixOperator(right object.Object) object.Object {
+	if float, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -float.Value}
+	}
+	
 	if right.Type() != object.INTEGER_OBJ {
 		return newError("unknown operator: -%s", right.Type())
 	}
@@ -303,22 +537,67 @@ func nativeBoolToBooleanObject(val bool) *object.Boolean{
 }
 
 func evalBangOperator(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
-	default:
-		return FALSE
-	}	
+	ok, err := truthy(right)
+	if err != nil {
+		return err
+	}
+	
+	return nativeBoolToBooleanObject(!ok)
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// evalLogicalExpression evaluates && and ||, leaving the right operand
+// unevaluated when the left one decides the result.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	ok, err := truthy(left)
+	if err != nil {
+		return err
+	}
+	if ok == (node.Operator == "||") {
+		return nativeBoolToBooleanObject(ok)
+	}
+	
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	ok, err = truthy(right)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(ok)
+}
+
+// truthy decides obj as a condition by object.Truthy, or in strict mode
+// fails unless it is a boolean.
+func truthy(obj object.Object) (bool, *object.Error) {
+	if b, ok := obj.(*object.Boolean); ok {
+		return b.Value, nil
+	}
+	if Strict {
+		return false, newError("condition must be BOOLEAN in strict mode, got %s", obj.Type())
+	}
+	return object.Truthy(obj), nil
+}
+
+// applyFunction calls fn with args. name is what the caller calls fn, for
+// errors, or "" if it has no name for it.
+func applyFunction(name string, fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		if name == "" {
+			name = fn.Name
+		}
+		if err := checkArity(name, fn, len(args)); err != nil {
+			return err
+		}
+		extendedEnv, err := extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
@@ -332,6 +611,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -351,15 +632,27 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	maxIdx := int64(len(runes) - 1)
+	
+	if idx < 0 || idx > maxIdx {
+		return NULL
+	}
+	
+	return &object.String{Value: string(runes[idx])}
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 	
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 	
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key)
 	if !ok {
 		return NULL
 	}
@@ -367,14 +660,73 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
  
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// requiredParameters counts the parameters of fn without defaults, which
+// come before those with them.
+func requiredParameters(fn *object.Function) int {
+	for i, param := range fn.Parameters {
+		if ast.DefaultOf(param) != nil {
+			return i
+		}
+	}
+	return len(fn.Parameters)
+}
+
+// checkArity fails unless fn can be called with n arguments.
+func checkArity(name string, fn *object.Function, n int) *object.Error {
+	min, max := requiredParameters(fn), len(fn.Parameters)
+	if n >= min && (n <= max || fn.Rest != nil) {
+		return nil
+	}
+	
+	var want string
+	switch {
+	case fn.Rest != nil:
+		want = fmt.Sprintf(" at least %d", min)
+	case min == max:
+		want = fmt.Sprintf("=%d", min)
+	case min+1 == max:
+		want = fmt.Sprintf("=%d or %d", min, max)
+	default:
+		want = fmt.Sprintf("=%d to %d", min, max)
+	}
+	if name != "" {
+		name = " to " + name
+	}
+	return newError("wrong number of arguments%s. got=%d, want%s", name, n, want)
+}
+
+// extendFunctionEnv binds the parameters of fn to args, which checkArity
+// has accepted. Defaults of the parameters left out are evaluated in the
+// new environment, so that they can refer to the parameters before them.
+// Parameters that are patterns bind the parts of their arguments.
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, *object.Error) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 	
 	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+		var value object.Object
+		if i < len(args) {
+			value = args[i]
+		} else {
+			def, err := evalDefault(param, env)
+			if err != nil {
+				return nil, err
+			}
+			value = def
+		}
+		if err := bind(param, value, env, false); err != nil {
+			return nil, err
+		}
+	}
+	
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
 	}
 	
-	return env
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
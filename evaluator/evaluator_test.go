@@ -1,6 +1,9 @@
 package evaluator 
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	
 	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
@@ -129,10 +132,13 @@ func TestBangOperator(t *testing.T) {
 		{"!true", false},
 		{"!false", true},
 		{"!5", false},
-		{"!0", false},
+		{"!0", true},
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		{`!""`, true},
+		{"![1]", false},
+		{"!(1 > 2)", true},
 	}
 		
 	for _, tt := range tests {
@@ -142,6 +148,60 @@ func TestBangOperator(t *testing.T) {
 }
 
 // Evaluate if-else expressions
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && \"a\"", true},
+		{"[] || {}", false},
+		{"1 < 2 && 2 < 3", true},
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"true || false && false", true},
+	}
+	
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	Strict = true
+	defer func() { Strict = false }()
+	
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"if (1) { 10 }", "condition must be BOOLEAN in strict mode, got INTEGER"},
+		{"!first([])", "condition must be BOOLEAN in strict mode, got NULL"},
+		{`!"a"`, "condition must be BOOLEAN in strict mode, got STRING"},
+		{"true && []", "condition must be BOOLEAN in strict mode, got ARRAY"},
+		{"false || fn() {}", "condition must be BOOLEAN in strict mode, got FUNCTION"},
+		{"false && 1", ""},
+		{"filter([1, 2], fn(x) { x })", "condition must be BOOLEAN in strict mode, got INTEGER"},
+		{"if (1 < 2) { 10 }", ""},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, isErr := evaluated.(*object.Error)
+		switch {
+		case tt.expected == "" && isErr:
+			t.Errorf("unexpected error for %q: %s", tt.input, errObj.Message)
+		case tt.expected != "" && !isErr:
+			t.Errorf("no error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+		case isErr && errObj.Message != tt.expected:
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
 func TestIfElseExpressions(t *testing.T) {
 	tests := []struct{
 		input string
@@ -154,6 +214,16 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) {10}", nil},
 		{"if (1 > 2) {10} else {20}", 20},
 		{"if (1 < 2) {10} else {20}", 10},
+		{"if (0) {10}", nil},
+		{`if ("") {10}`, nil},
+		{`if ("a") {10}`, 10},
+		{"if ([]) {10}", nil},
+		{"if ([0]) {10}", 10},
+		{"if ({}) {10}", nil},
+		{"if (fn() {}) {10}", 10},
+		{"if (if (false) {1}) {10} else {20}", 20},
+		{"if (true) {}", nil},
+		{"if (true) { let x = 1; }", nil},
 	}
 	
 	for _, tt := range tests {
@@ -209,6 +279,8 @@ func TestErrorHandling(t *testing.T) {
 		{"foobar", "identifier not found:foobar"},
 		{`"Hello" - "hello"`, "unknown operator: STRING - STRING"},
 		{`{"name": "Monkey"}[fn(x){ x }]`, "unusable as hash key: FUNCTION"},
+		{"10 / (5 - 5)", "division by zero: 10 / 0"},
+		{"let add = fn(x, y) { x + y }; add(1);", "wrong number of arguments to add. got=1, want=2"},
 	}
 	
 	for _, tt := range tests {
@@ -283,12 +355,250 @@ func TestFuntionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionArity(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let f = fn(a, b) { a }; f(1, 2, 3)", "wrong number of arguments to f. got=3, want=2"},
+		{"fn(a) { a }()", "wrong number of arguments. got=0, want=1"},
+		{"let f = fn(a, b = 1) { a }; f()", "wrong number of arguments to f. got=0, want=1 or 2"},
+		{"let f = fn(a, b = 1, c = 2) { a }; f(1, 2, 3, 4)", "wrong number of arguments to f. got=4, want=1 to 3"},
+		{"let f = fn(a, ...r) { a }; f()", "wrong number of arguments to f. got=0, want at least 1"},
+		{"let h = {\"f\": fn(a) { a }}; h[\"f\"](1, 2)", "wrong number of arguments. got=2, want=1"},
+	}
+	
+	for _, tt := range tests {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let f = fn(a, b = 10) { a + b }; f(1)", "11"},
+		{"let f = fn(a, b = 10) { a + b }; f(1, 2)", "3"},
+		{"let f = fn(a, b = a * 2, c = a + b) { [a, b, c] }; f(1)", "[1, 2, 3]"},
+		{"let n = 5; let f = fn(a = n) { a }; let n2 = 6; f()", "5"},
+		{"let f = fn(first, ...rest) { [first, rest] }; f(1, 2, 3)", "[1, [2, 3]]"},
+		{"let f = fn(first, ...rest) { rest }; f(1)", "[]"},
+		{"let f = fn(a = 0, ...rest) { [a, rest] }; f()", "[0, []]"},
+		{"let f = fn(a = undefined) { a }; f(1)", "1"},
+		{"let f = fn(a = undefined) { a }; f()", "ERROR: identifier not found:undefined"},
+		{"fn(...r) { r }", "fn(...r) {\nr\n}"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let [a, b] = [1, 2]; a + b", "3"},
+		{"let [a, b] = [1, 2, 3]; [a, b]", "[1, 2]"},
+		{"let [first, ...others] = [1, 2, 3]; [first, others]", "[1, [2, 3]]"},
+		{"let [a, ...r] = [1]; r", "[]"},
+		{"let [a, b = a + 1] = [1]; b", "2"},
+		{`let {name, age: years} = {"name": "Ann", "age": 30}; [name, years]`, `[Ann, 30]`},
+		{`let {"full name": n, x = 0} = {"full name": "A B"}; [n, x]`, `[A B, 0]`},
+		{`let {a, ...other} = {"a": 1, 2: "b", "c": 3}; other`, `{2: b, c: 3}`},
+		{`let {p: [x, {y}]} = {"p": [1, {"y": 2}]}; x + y`, "3"},
+		{`let [{a} = {"a": 5}] = []; a`, "5"},
+		{`let f = fn([a, b], {c} = {"c": 3}) { a + b + c }; f([1, 2])`, "6"},
+		{"let swap = fn([a, b]) { [b, a] }; swap([1, 2])", "[2, 1]"},
+		{"let [a] = [1]; let [a] = [2]; a", "2"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let [a, b] = 1;", "cannot bind INTEGER to [a, b], which takes an array"},
+		{"let {a} = [1];", "cannot bind ARRAY to {a}, which takes a hash"},
+		{"let [a, b] = [1];", "no element at index 1 for b in [a, b]"},
+		{`let {name, age: years} = {"name": 1};`, `no key "age" for years in {name, age: years}`},
+		{`let {p: [x]} = {"p": {}};`, "cannot bind HASH to [x], which takes an array"},
+		{"let [a = b] = [];", "identifier not found:b"},
+		{"let f = fn([a]) { a }; f(1)", "cannot bind INTEGER to [a], which takes an array"},
+		{"let [m] = macro(x) { x };", "macro literals must be bound by a top-level let statement"},
+	}
+	
+	for _, tt := range tests {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"match (1) { 1 => \"one\", _ => \"other\" }", "one"},
+		{"match (2) { 1 => \"one\", _ => \"other\" }", "other"},
+		{"match (-3) { -3 => true, _ => false }", "true"},
+		{`match ("b") { "a" => 1, "b" => 2 }`, "2"},
+		{"match (false) { true => 1, false => 0 }", "0"},
+		{"match (1) { \"1\" => 1, x => x + 1 }", "2"},
+		{"match ([1, 2]) { [a] => a, [a, b] => a + b }", "3"},
+		{"match ([1, 2, 3]) { [a, b] => 0, [a, ...r] => r }", "[2, 3]"},
+		{"match ([]) { [a = 5] => a }", "5"},
+		{"match ([0, 1]) { [1, x] => x, [0, x] => -x }", "-1"},
+		{`match ({"k": 1, "v": 2}) { {k: 0, v} => v, {k: 1, v} => v * 10 }`, "20"},
+		{`match ({"v": 2}) { {k, v} => k, {v} => v }`, "2"},
+		{`match ({"a": [1]}) { {a: [b], ...r} => [b, r] }`, "[1, {}]"},
+		{"match (5) { n if n > 10 => \"big\", n if n > 0 => \"small\", _ => \"none\" }", "small"},
+		{"match (3) { [a] => a, {a} => a, n => n }", "3"},
+		{"let x = 1; match (2) { x => x }; x", "1"},
+		{"let f = fn(v) { match (v) { 0 => \"zero\", n => f(n - 1) } }; f(3)", "zero"},
+		{"match (1) { 1 => 1 } + match (2) { 2 => 2 }", "3"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"match (3) { 1 => 1, 2 => 2 }", "no pattern matches 3"},
+		{"match ([1, 2]) { [a] => a }", "no pattern matches [1, 2]"},
+		{"match (1) { n if n > 1 => n }", "no pattern matches 1"},
+		{"match (x) { _ => 1 }", "identifier not found:x"},
+		{"match (1) { n if y => n }", "identifier not found:y"},
+		{"match (1) { n => n + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"match ([]) { [[a] = 1] => a }", "cannot bind INTEGER to [a] = 1, which takes an array"},
+		{"match ([]) { [[1] = [2]] => 1 }", "cannot bind 2 to 1"},
+	}
+	
+	for _, tt := range tests {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"const x = 5; x", "5"},
+		{"const x = 1; let x = 2;", "ERROR: cannot redeclare constant x"},
+		{"const x = 1; const x = 2;", "ERROR: cannot redeclare constant x"},
+		{"let x = 1; const x = 2; x", "2"},
+		{"const x = 1; let f = fn() { let x = 2; x }; f() + x", "3"},
+		{"const x = 1; fn(x) { x }(2) + match (3) { x => x }", "5"},
+		{"const [a, ...r] = [1, 2]; let r = 3;", "ERROR: cannot redeclare constant r"},
+		{"const {a, b = a} = {\"a\": 1}; let [b] = [2];", "ERROR: cannot redeclare constant b"},
+		{"const f = 1; if (true) { fn f() { 2 } }", "ERROR: cannot redeclare constant f"},
+		{"const X = 1; fn X() { 1 }", "ERROR: cannot redeclare constant X"},
+		{"fn X() { 1 } const X = 1; X", "ERROR: cannot redeclare constant X"},
+		{"export const [a, b] = [1, 2]; export fn b() { 3 }", "ERROR: cannot redeclare constant b"},
+		{"const X = 1; let f = fn() { fn X() { 2 } X() }; f() + X", "3"},
+		{"const f = fn f(n) { if (n < 1) { 0 } else { f(n - 1) } }; f(3)", "0"},
+		{`const h = {"a": [1]}; let g = set(h, "b", 2); [h, g]`, "[{a: [1]}, {a: [1], b: 2}]"},
+		{"const m = macro(x) { x };", "ERROR: macro literals must be bound by a top-level let statement"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+	
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New(`const c = [{"a": [1]}];`)).ParseProgram()
+	Eval(program, env)
+	c, _ := env.Get("c")
+	array, ok := c.(*object.Array)
+	if !ok || !array.Frozen() || !array.Elements[0].(*object.Hash).Frozen() {
+		t.Errorf("constant %s is not frozen", c.Inspect())
+	}
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"fn add(a, b) { a + b }; add(1, 2)", "3"},
+		{"let x = add(1, 2); fn add(a, b) { a + b } x", "3"},
+		{"fn even(n) { if (n == 0) { true } else { odd(n - 1) } } fn odd(n) { if (n == 0) { false } else { even(n - 1) } } even(10)", "true"},
+		{"let f = fn() { let x = g(); fn g() { 5 } x }; f()", "5"},
+		{"let f = fn() { fn g() { 5 } }; f(); g", "ERROR: identifier not found:g"},
+		{"let fact = fn go(n) { if (n < 2) { 1 } else { n * go(n - 1) } }; fact(5)", "120"},
+		{"let f = fn go() { 1 }; go", "ERROR: identifier not found:go"},
+		{"let x = f(1); let f = fn f(a) { a }; x", "ERROR: identifier not found:f"},
+		{"fn add(a, b) { a + b } add", "fn add(a, b) {\n(a + b)\n}"},
+		{"fn add(a, b) { a + b } let plus = add; plus(1)", "ERROR: wrong number of arguments to plus. got=1, want=2"},
+		{`let h = {"f": fn named(a) { a }}; h["f"]()`, "ERROR: wrong number of arguments to named. got=0, want=1"},
+		{"filter([1], fn keep(a, b) { a })", "ERROR: wrong number of arguments to keep. got=1, want=2"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let add = fn(a, b, c) { a + b + c }; add(...[1, 2, 3])", "6"},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2], ...[], 3)", "6"},
+		{"let f = fn(...all) { all }; let xs = [1, 2]; f(0, ...xs, ...xs)", "[0, 1, 2, 1, 2]"},
+		{"len(...[[1, 2]])", "2"},
+		{"let f = fn(a) { a }; f(...[])", "ERROR: wrong number of arguments to f. got=0, want=1"},
+		{"let f = fn(a) { a }; f(...1)", "ERROR: cannot spread INTEGER, only ARRAY"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := "let newAdder = fn(x) { fn(y) { x + y }}; let addTwo = newAdder(2); addTwo(2);"
 	
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestTypeAnnotationsAreIgnored(t *testing.T) {
+	input := `let add = fn(a: int, b: int) -> int { a + b }; let x: string = add(1, 2); x;`
+	
+	testIntegerObject(t, testEval(input), 3)
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " +"world!"`
 	evaluated := testEval(input)
@@ -334,6 +644,17 @@ func TestBuiltFunctions(t *testing.T) {
 	}
 }
 
+func TestPutsWritesToOutput(t *testing.T) {
+	var out strings.Builder
+	Output = &out
+	defer func() { Output = os.Stdout }()
+	
+	evaluated := testEval(`puts("hello", 1, [true]);`)
+	testNullObject(t, evaluated)
+	if expected := "hello\n1\n[true]\n"; out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
 
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
@@ -400,21 +721,21 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return Hash. got=%[1]T (%+[1]v)", evaluated)
 	}
 	
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey(): 1,
-		(&object.String{Value: "two"}).HashKey(): 2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.String{Value: "4"}).HashKey(): 4,
-		TRUE.HashKey(): 5,
-		FALSE.HashKey(): 6,
+	expected := map[object.Hashable]int64{
+		&object.String{Value: "one"}: 1,
+		&object.String{Value: "two"}: 2,
+		&object.String{Value: "three"}: 3,
+		&object.String{Value: "4"}: 4,
+		TRUE: 5,
+		FALSE: 6,
 	}
 	
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs, got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs, got=%d", result.Len())
 	}
 	
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Get(expectedKey)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -448,4 +769,572 @@ func TestHashIndexExpression(t *testing.T) {
 		}
 	}
 
-}
\ No newline at end of file
+}
+func TestStringComparison(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" != "a"`, false},
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"b" > "a"`, true},
+		{`"abc" > "abd"`, false},
+		{`!("a" == "b")`, true},
+	}
+	
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStringIndexExpression(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{`"hello"[0]`, "h"},
+		{`"hello"[4]`, "o"},
+		{`let s = "hello"; s[len(s) - 1]`, "o"},
+		{`"héllo"[1]`, "é"},
+		{`"hello"[5]`, nil},
+		{`"hello"[-1]`, nil},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := tt.expected.(string)
+		if ok {
+			testStringObject(t, evaluated, str)
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{`len("héllo")`, 5},
+		{`split("a,b,c", ",")`, []string{"a", "b", "c"}},
+		{`split("  a b   c ")`, []string{"a", "b", "c"}},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join(["a", "b"])`, "ab"},
+		{`join(split("a b", " "), ",")`, "a,b"},
+		{`trim("  hi  ")`, "hi"},
+		{`trim("xxhixx", "x")`, "hi"},
+		{`upper("Hello")`, "HELLO"},
+		{`lower("Hello")`, "hello"},
+		{`contains("hello", "ell")`, true},
+		{`contains("hello", "xyz")`, false},
+		{`starts_with("hello", "he")`, true},
+		{`starts_with("hello", "lo")`, false},
+		{`ends_with("hello", "lo")`, true},
+		{`ends_with("hello", "he")`, false},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`substr("hello", 1)`, "ello"},
+		{`substr("hello", 1, 3)`, "el"},
+		{`substr("hello", -3)`, "llo"},
+		{`substr("hello", 3, 1)`, ""},
+		{`substr("hello", 0, 100)`, "hello"},
+		{`chars("héy")`, []string{"h", "é", "y"}},
+		{`to_int("42")`, 42},
+		{`to_int(" -7 ")`, -7},
+		{`to_int(true)`, 1},
+		{`to_string(42)`, "42"},
+		{`to_string("x")`, "x"},
+		{`to_string([1, "a"])`, "[1, a]"},
+		{`split(1, ",")`, errorMessage("argument to 'split' must be STRING, got INTEGER")},
+		{`join([1], ",")`, errorMessage("element 0 passed to 'join' must be STRING, got INTEGER")},
+		{`upper(1)`, errorMessage("argument to 'upper' must be STRING, got INTEGER")},
+		{`contains("a", 1)`, errorMessage("arguments to 'contains' must be STRING, got INTEGER")},
+		{`repeat("a", -1)`, errorMessage("count for 'repeat' must not be negative, got -1")},
+		{`repeat("ab", 9223372036854775807)`, errorMessage("count for 'repeat' is too large, got 9223372036854775807")},
+		{`to_int("abc")`, errorMessage(`could not convert "abc" to INTEGER`)},
+		{`replace("a", "b")`, errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case []string:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong number of elements. want=%d, got=%d", len(expected), len(array.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testStringObject(t, array.Elements[i], el)
+			}
+		case errorMessage:
+			testErrorObject(t, evaluated, string(expected))
+		}
+	}
+}
+
+type errorMessage string
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String, got=%T (%+v)", obj, obj)
+		return false
+	}
+	
+	if result.Value != expected {
+		t.Errorf("object has wrong value, got=%q, want=%q", result.Value, expected)
+		return false
+	}
+	
+	return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error, got=%T (%+v)", obj, obj)
+		return false
+	}
+	
+	if errObj.Message != expected {
+		t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+	
+	return true
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"filter([1, 2, 3, 4], fn(x) { x > 2 })", "[3, 4]"},
+		{`filter([0, 1, "", "a", [], [0], false, true], fn(x) { x })`, `[1, a, [0], true]`},
+		{"filter([], fn(x) { true })", "[]"},
+		{"filter([[], [1]], len)", "[[1]]"},
+		{"filter(1, len)", errorMessage("argument to 'filter' must be ARRAY, got INTEGER")},
+		{"filter([1], 1)", errorMessage("predicate for 'filter' must be FUNCTION, got INTEGER")},
+		{"filter([1], fn(x) { x + true })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"filter([1])", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q. expected=%s, got=%s", tt.input, expected, evaluated.Inspect())
+			}
+		case errorMessage:
+			testErrorObject(t, evaluated, string(expected))
+		}
+	}
+}
+
+func TestHashInspectKeepsInsertionOrder(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{`{3: "x", 1: "y", 2: "z"}`, "{3: x, 1: y, 2: z}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a: 3, b: 2}"},
+		{`set({"b": 1, "a": 2}, "c", 3)`, "{b: 1, a: 2, c: 3}"},
+		{`set({"b": 1, "a": 2}, "b", 3)`, "{b: 3, a: 2}"},
+		{`delete({"b": 1, "a": 2, "c": 3}, "a")`, "{b: 1, c: 3}"},
+		{`merge({"b": 1, "a": 2}, {"c": 3, "b": 4})`, "{b: 4, a: 2, c: 3}"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect() for %q. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{`len({"a": 1, "b": 2})`, 2},
+		{`len({})`, 0},
+		{`keys({"b": 1, "a": 2})`, "[b, a]"},
+		{`values({"b": 1, "a": 2})`, "[1, 2]"},
+		{`items({"b": 1, "a": 2})`, "[[b, 1], [a, 2]]"},
+		{`keys({})`, "[]"},
+		{`has({"a": 1}, "a")`, true},
+		{`has({"a": 1}, "b")`, false},
+		{`has({1: 1}, 1)`, true},
+		{`let h = {"a": 1}; let updated = set(h, "b", 2); len(h)`, 1},
+		{`let h = {"a": 1}; let updated = set(h, "b", 2); updated["b"]`, 2},
+		{`let h = {"a": 1}; let updated = delete(h, "a"); has(h, "a")`, true},
+		{`let h = {"a": 1}; let updated = delete(h, "a"); has(updated, "a")`, false},
+		{`delete({"a": 1}, "missing")`, "{a: 1}"},
+		{`merge({"a": 1})`, "{a: 1}"},
+		{`keys([1])`, errorMessage("argument to 'keys' must be HASH, got ARRAY")},
+		{`has({}, fn(x) { x })`, errorMessage("unusable as hash key: FUNCTION")},
+		{`set({}, fn(x) { x }, 1)`, errorMessage("unusable as hash key: FUNCTION")},
+		{`merge({}, 1)`, errorMessage("arguments to 'merge' must be HASH, got INTEGER")},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong Inspect() for %q. want=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		case errorMessage:
+			testErrorObject(t, evaluated, string(expected))
+		}
+	}
+}
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected bool
+	}{
+		{"[1, 2, 3] == [1, 2, 3]", true},
+		{"[1, 2, 3] == [1, 2]", false},
+		{"[1, 2, 3] != [1, 2, 4]", true},
+		{`[1, "a", [true]] == [1, "a", [true]]`, true},
+		{"[] == []", true},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": [1]} == {"a": [1]}`, true},
+		{`{"a": 1} != {"a": 1, "b": 2}`, true},
+		{"[1][5] == [2][5]", true},
+		{"[1][5] == 1", false},
+		{"1 != [1][5]", true},
+		{"let f = fn(x) { x }; [f] == [f]", true},
+	}
+	
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArraysAsHashKeys(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{`{[1, 2]: "a"}[[1, 2]]`, "a"},
+		{`{[1, 2]: "a"}[[2, 1]]`, nil},
+		{`let x = 3; let y = 4; {[x, y]: "point"}[[3, 4]]`, "point"},
+		{`{[1, ["a", true]]: "nested"}[[1, ["a", true]]]`, "nested"},
+		{`{[]: "empty"}[[]]`, "empty"},
+		{`has(set({}, [1, 2], 1), [1, 2])`, true},
+		{`{[1, fn(x) { x }]: 1}`, errorMessage("unusable as hash key: ARRAY")},
+		{`{"a": 1}[[fn(x) { x }]]`, errorMessage("unusable as hash key: ARRAY")},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		
+		switch expected := tt.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case errorMessage:
+			testErrorObject(t, evaluated, string(expected))
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("half", &object.Float{Value: 0.5})
+	
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"half + half", "1.0"},
+		{"half * 3", "1.5"},
+		{"3 - half", "2.5"},
+		{"-half", "-0.5"},
+		{"half < 1", "true"},
+		{"half == 0", "false"},
+		{"to_int(half * 5)", "2"},
+		{"to_string(half)", "0.5"},
+		{"[half * 2] == [1]", "true"},
+		{`[1, {"a": half * 2}] == [1, {"a": 1}]`, "true"},
+		{"[half] == [0]", "false"},
+		{`match (half * 2) { 1 => "one", _ => "other" }`, "one"},
+		{`match ([1, half + half]) { [1, 1] => "ones", _ => "other" }`, "ones"},
+	}
+	
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := Eval(program, env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestJSONParse(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": [true, false, null], "c": {"d": "x"}}`, "{b: 1, a: [true, false, null], c: {d: x}}"},
+		{`  [1, -2, 3.5, 1e3, 0]  `, "[1, -2, 3.5, 1000.0, 0]"},
+		{`"line\nbreak \"quoted\" é😀"`, "line\nbreak \"quoted\" é😀"},
+		{`[]`, "[]"},
+		{`{}`, "{}"},
+		{`9223372036854775808`, "9.223372036854776e+18"},
+	}
+	
+	for _, tt := range tests {
+		evaluated := jsonParse(&object.String{Value: tt.input})
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+	
+	hash := jsonParse(&object.String{Value: `{"n": 1, "f": 1.0}`}).(*object.Hash)
+	pairs := hash.Pairs()
+	if _, ok := pairs[0].Value.(*object.Integer); !ok {
+		t.Errorf("integer literal not parsed as Integer. got=%T", pairs[0].Value)
+	}
+	if _, ok := pairs[1].Value.(*object.Float); !ok {
+		t.Errorf("float literal not parsed as Float. got=%T", pairs[1].Value)
+	}
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{``, "json_parse: unexpected end of input at line 1, column 1"},
+		{`{"a": 1,}`, `json_parse: expected string key, got character '}' at line 1, column 9`},
+		{"{\n  \"a\": 1\n  \"b\": 2\n}", `json_parse: expected ',' or '}' in object, got character '"' at line 3, column 3`},
+		{`[1, 2`, "json_parse: expected ',' or ']' in array, got end of input at line 1, column 6"},
+		{`{"a" 1}`, `json_parse: expected ':' after object key, got character '1' at line 1, column 6`},
+		{`"abc`, "json_parse: unterminated string at line 1, column 5"},
+		{`"\x"`, `json_parse: invalid escape sequence '\x' at line 1, column 2`},
+		{`[01]`, `json_parse: expected ',' or ']' in array, got character '1' at line 1, column 3`},
+		{`1.`, "json_parse: expected digit after decimal point at line 1, column 3"},
+		{`tru`, `json_parse: unexpected character 't' at line 1, column 1`},
+		{`{} x`, `json_parse: unexpected character 'x' after top-level value at line 1, column 4`},
+		{`["é", x]`, `json_parse: unexpected character 'x' at line 1, column 7`},
+	}
+	
+	for _, tt := range tests {
+		testErrorObject(t, jsonParse(&object.String{Value: tt.input}), tt.expected)
+	}
+	
+	testErrorObject(t, jsonParse(&object.Integer{Value: 1}), "argument to 'json_parse' must be STRING, got INTEGER")
+	
+	deep := strings.Repeat("[", maxJSONDepth+2) + strings.Repeat("]", maxJSONDepth+2)
+	if _, ok := jsonParse(&object.String{Value: deep}).(*object.Error); !ok {
+		t.Errorf("deeply nested input did not produce an error")
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{`json_stringify({"b": 1, "a": [true, "x"], "c": {}})`, `{"b":1,"a":[true,"x"],"c":{}}`},
+		{`json_stringify([1][5])`, "null"},
+		{`json_stringify({1: "one", true: "yes"})`, `{"1":"one","true":"yes"}`},
+		{`json_stringify({"a": [1, 2], "b": []}, 2)`, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": []\n}"},
+		{`json_stringify([{"a": 1}], "	")`, "[\n\t{\n\t\t\"a\": 1\n\t}\n]"},
+	}
+	
+	for _, tt := range tests {
+		testStringObject(t, testEval(tt.input), tt.expected)
+	}
+	
+	quoted := jsonStringify(&object.String{Value: "say \"hi\"\n\x01"})
+	testStringObject(t, quoted, `"say \"hi\"\n\u0001"`)
+	
+	errors := []struct{
+		input    string
+		expected string
+	}{
+		{`json_stringify(fn(x) { x })`, "json_stringify: cannot serialize FUNCTION"},
+		{`json_stringify({"f": [len]})`, "json_stringify: cannot serialize BUILTIN"},
+		{`json_stringify({[1]: 1})`, "json_stringify: cannot serialize ARRAY as object key"},
+		{`json_stringify(1, true)`, "indent for 'json_stringify' must be INTEGER or STRING, got BOOLEAN"},
+	}
+	
+	for _, tt := range errors {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `{"name": "monkey", "tags": ["a", "b"], "nested": {"n": -1.25, "ok": false, "none": null}}`
+	
+	parsed := jsonParse(&object.String{Value: input})
+	stringified := jsonStringify(parsed)
+	reparsed := jsonParse(stringified)
+	
+	if !object.Equal(parsed, reparsed) {
+		t.Errorf("round trip changed value. got=%s, want=%s", reparsed.Inspect(), parsed.Inspect())
+	}
+	testStringObject(t, stringified, `{"name":"monkey","tags":["a","b"],"nested":{"n":-1.25,"ok":false,"none":null}}`)
+	
+	floats := []struct{
+		input    float64
+		expected string
+	}{
+		{1, "1.0"},
+		{-2, "-2.0"},
+		{1e21, "1e+21"},
+		{0.5, "0.5"},
+	}
+	
+	for _, tt := range floats {
+		stringified := jsonStringify(&object.Float{Value: tt.input})
+		testStringObject(t, stringified, tt.expected)
+		
+		reparsed, ok := jsonParse(stringified).(*object.Float)
+		if !ok || reparsed.Value != tt.input {
+			t.Errorf("float %v did not round trip. got=%s", tt.input, jsonParse(stringified).Inspect())
+		}
+	}
+}
+
+func writeModules(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportModules(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/math.mk": `
+			import "helpers";
+			export let square = fn(x) { helpers.times(x, x) };
+			export let answer = 42;
+			let secret = 1;
+		`,
+		"lib/helpers.mk": `export let times = fn(a, b) { a * b }; export fn twice(x) { times(x, 2) }`,
+		"shared/util.mk": `export let name = "util";`,
+		"early.mk": `export let before = 1; return 1; export let x = 2;`,
+	})
+	ModulePath = []string{filepath.Join(dir, "shared")}
+	defer func() { ModulePath = nil }()
+	
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{`import "lib/math"; math.answer`, 42},
+		{`import "lib/math"; math.square(5)`, 25},
+		{`import "lib/helpers"; helpers.twice(4)`, 8},
+		{`import "./lib/math.mk" as m; m.square(3)`, 9},
+		{`import "util"; util.name`, "util"},
+		{`import "early"; early.before`, 1},
+		{`import "early"; early.x`, errorMessage("module early has no exported member x")},
+		{`import "early"; puts(early.x)`, errorMessage("module early has no exported member x")},
+		{`import "lib/math"; math.secret`, errorMessage("module math has no exported member secret")},
+		{`import "lib/missing"`, errorMessage(`module "lib/missing" not found`)},
+		{`import "./util"`, errorMessage(`module "./util" not found`)},
+		{`import "lib/math"; math.answer.x`, errorMessage("member access not supported: INTEGER")},
+		{`import "my-lib"`, errorMessage(`cannot bind module "my-lib" to a name, use: import "my-lib" as name`)},
+	}
+	
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := Eval(program, object.NewFileEnvironment(filepath.Join(dir, "main.mk")))
+		
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case errorMessage:
+			testErrorObject(t, evaluated, string(expected))
+		}
+	}
+}
+
+func TestImportEvaluatesModuleOnce(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "a"; import "b"; a.value == b.value`,
+		"a.mk": `import "shared"; export let value = shared.value;`,
+		"b.mk": `import "shared"; export let value = shared.value;`,
+		"shared.mk": `export let value = [1, 2];`,
+	})
+	
+	evaluated := EvalFile(filepath.Join(dir, "main.mk"))
+	testBooleanObject(t, evaluated, true)
+	
+	shared := modules[filepath.Join(dir, "shared.mk")]
+	a := modules[filepath.Join(dir, "a.mk")]
+	if shared == nil || a == nil {
+		t.Fatalf("modules were not cached")
+	}
+	if a.Exports["value"] != shared.Exports["value"] {
+		t.Errorf("shared module was evaluated more than once")
+	}
+}
+
+func TestImportCycle(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "a";`,
+		"a.mk": `import "b";`,
+		"b.mk": `import "main";`,
+	})
+	
+	evaluated := EvalFile(filepath.Join(dir, "main.mk"))
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	
+	cycle := []string{"main.mk", "a.mk", "b.mk", "main.mk"}
+	for i := range cycle {
+		cycle[i] = filepath.Join(dir, cycle[i])
+	}
+	expected := "import cycle: " + strings.Join(cycle, " -> ")
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
@@ -0,0 +1,104 @@
+package evaluator
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+var fuzzSeeds = []string{
+	"let add = fn(x, y) { x + y }; add(1, 2); add(1); add(1, 2, 3);",
+	`if (1) { 1 }; if ("") { 2 }; if ([]) { 3 }; if ({}) { 4 }; if (fn() {}) { 5 }; if (if (false) { 1 }) { 6 }`,
+	`!1; !"a"; -"a"; -true; 1 + "a"; "a" - "b"; [1] + [2]; {} == {}; true > false;`,
+	`[1, 2, 3][-1]; [1][5]; "abc"[1]; {"a": 1}["b"]; {fn() {}: 1}; 1[0]; {}[[]];`,
+	`let h = {"a": [1, {"b": 2}]}; h["a"][1]["b"]; keys(h); values(h); items(h); merge(h, {});`,
+	`len(); len(1); first([]); last([]); rest([]); push(1, 2); split("a,b", ","); join([1], 2);`,
+	`to_int("x"); to_int("12"); to_string([1]); substr("abc", 5, 1); chars(""); has({}, []);`,
+	`json_parse("[1, true, {}]"); json_parse("{"); json_stringify(fn() {}); json_stringify({1: 2});`,
+	"let f = fn(n) { if (n < 1) { 0 } else { f(n - 1) } }; f(10); let g = fn() { g() }; g();",
+	"let m = macro(a, b) { quote(unquote(b) - unquote(a)) }; m(2, 10); quote(unquote(fn(x) { x }));",
+	"let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) }; unless(false, 1);",
+	`test "t" { assert(false) }; assert_eq(1, 2); assert_error(fn() { 1 });`,
+	"filter([0, 1, \"\", [], {}], fn(x) { x || !x && x }); filter([1], 1); filter([1], fn() { 1 });",
+	"let f = fn(a, b = a + 1, ...c) { [a, b, c] }; f(1); f(...[1, 2, 3, 4]); f(); f(...1); fn(x = y) { x }();",
+	"fn f(a) { g(a) } fn g(b = 1) { f(b) }; let h = fn go(n) { go(n - 1) }; h(3); go; f(1);",
+	`let [a, b = a, ...c] = [1]; let {d, "e": [f] = [2], ...g} = {"d": 3, 4: 5}; fn([h], {i} = {}) { h }(1); let [j] = fn() {}();`,
+	`match ([1, {"a": 2}]) { [1] => 0, [n, {a = 3, ...r}] if n => a, [-1, ...x] => x, "s" => 1, _ => match (true) { false => 2 } }`,
+	`const a = [{"b": 1}]; let c = set(a[0], "d", 2); const [e, ...f] = a; let e = 1; fn a() {} const g = fn g() { g };`,
+	"let x = 1; x.y; 1.y; return 1; return; fn() { return fn() { return 2 } }()();",
+}
+
+// fuzzLimit is what a fuzzTracer panics with to stop a program that runs
+// too long or recurses too deeply, which is not a failure.
+type fuzzLimit struct{}
+
+// fuzzTracer bounds the number of steps and the depth of calls, since
+// the language has no other way to stop a program that does not end.
+type fuzzTracer struct {
+	steps, depth int
+}
+
+func (t *fuzzTracer) Step(node ast.Node, env *object.Environment) {
+	t.steps++
+	if t.steps > 100000 {
+		panic(fuzzLimit{})
+	}
+}
+
+func (t *fuzzTracer) Enter(call *ast.CallExpression, fn *object.Function) {
+	t.depth++
+	if t.depth > 200 {
+		panic(fuzzLimit{})
+	}
+}
+
+func (t *fuzzTracer) Leave(call *ast.CallExpression, result object.Object) {
+	t.depth--
+}
+
+// FuzzEval checks that evaluating a program that parses never panics,
+// whatever it does with the values it has.
+func FuzzEval(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	Output = io.Discard
+	defer func() { Output = os.Stdout }()
+
+	f.Fuzz(func(t *testing.T, input string) {
+		// repeat builds strings as long as it is asked to
+		if len(input) > 2000 || strings.Contains(input, "repeat") {
+			return
+		}
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			return
+		}
+
+		SetTracer(&fuzzTracer{})
+		defer SetTracer(nil)
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(fuzzLimit); !ok {
+					panic(r)
+				}
+			}
+		}()
+
+		macroEnv := object.NewEnvironment()
+		DefineMacros(program, macroEnv)
+		expanded, err := ExpandMacros(program, macroEnv)
+		if err != nil {
+			return
+		}
+		Eval(expanded, object.NewEnvironment())
+	})
+}
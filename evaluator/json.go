@@ -0,0 +1,497 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// maxJSONDepth bounds nesting so hostile input can't exhaust the stack.
+const maxJSONDepth = 512
+
+func jsonParse(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to 'json_parse' must be STRING, got %s", args[0].Type())
+	}
+
+	p := &jsonParser{input: str.Value}
+	p.skipWhitespace()
+	value := p.parseValue(0)
+	if p.err != nil {
+		return p.err
+	}
+
+	p.skipWhitespace()
+	if p.pos < len(p.input) {
+		p.errorf("unexpected %s after top-level value", p.describeCurrent())
+		return p.err
+	}
+
+	return value
+}
+
+// jsonParser is a recursive descent parser producing objects directly, so
+// hash keys keep the order in which they appear in the document.
+type jsonParser struct {
+	input string
+	pos   int
+	err   *object.Error
+}
+
+func (p *jsonParser) parseValue(depth int) object.Object {
+	if depth > maxJSONDepth {
+		return p.errorf("exceeded maximum nesting depth of %d", maxJSONDepth)
+	}
+
+	if p.pos >= len(p.input) {
+		return p.errorf("unexpected end of input")
+	}
+
+	switch ch := p.input[p.pos]; {
+	case ch == '{':
+		return p.parseObject(depth)
+	case ch == '[':
+		return p.parseArray(depth)
+	case ch == '"':
+		str, ok := p.parseString()
+		if !ok {
+			return nil
+		}
+		return &object.String{Value: str}
+	case ch == '-' || isDigit(ch):
+		return p.parseNumber()
+	case strings.HasPrefix(p.input[p.pos:], "true"):
+		p.pos += len("true")
+		return TRUE
+	case strings.HasPrefix(p.input[p.pos:], "false"):
+		p.pos += len("false")
+		return FALSE
+	case strings.HasPrefix(p.input[p.pos:], "null"):
+		p.pos += len("null")
+		return NULL
+	default:
+		return p.errorf("unexpected %s", p.describeCurrent())
+	}
+}
+
+func (p *jsonParser) parseObject(depth int) object.Object {
+	hash := object.NewHash()
+	p.pos++ // '{'
+
+	p.skipWhitespace()
+	if p.consume('}') {
+		return hash
+	}
+
+	for {
+		p.skipWhitespace()
+		if p.pos >= len(p.input) || p.input[p.pos] != '"' {
+			return p.errorf("expected string key, got %s", p.describeCurrent())
+		}
+		key, ok := p.parseString()
+		if !ok {
+			return nil
+		}
+
+		p.skipWhitespace()
+		if !p.consume(':') {
+			return p.errorf("expected ':' after object key, got %s", p.describeCurrent())
+		}
+
+		p.skipWhitespace()
+		value := p.parseValue(depth + 1)
+		if p.err != nil {
+			return nil
+		}
+		hash.Set(&object.String{Value: key}, value)
+
+		p.skipWhitespace()
+		if p.consume('}') {
+			return hash
+		}
+		if !p.consume(',') {
+			return p.errorf("expected ',' or '}' in object, got %s", p.describeCurrent())
+		}
+	}
+}
+
+func (p *jsonParser) parseArray(depth int) object.Object {
+	elements := []object.Object{}
+	p.pos++ // '['
+
+	p.skipWhitespace()
+	if p.consume(']') {
+		return &object.Array{Elements: elements}
+	}
+
+	for {
+		p.skipWhitespace()
+		value := p.parseValue(depth + 1)
+		if p.err != nil {
+			return nil
+		}
+		elements = append(elements, value)
+
+		p.skipWhitespace()
+		if p.consume(']') {
+			return &object.Array{Elements: elements}
+		}
+		if !p.consume(',') {
+			return p.errorf("expected ',' or ']' in array, got %s", p.describeCurrent())
+		}
+	}
+}
+
+func (p *jsonParser) parseString() (string, bool) {
+	var out strings.Builder
+	p.pos++ // opening quote
+
+	for {
+		if p.pos >= len(p.input) {
+			p.errorf("unterminated string")
+			return "", false
+		}
+
+		ch := p.input[p.pos]
+		switch {
+		case ch == '"':
+			p.pos++
+			return out.String(), true
+		case ch < 0x20:
+			p.errorf("invalid control character %q in string", ch)
+			return "", false
+		case ch == '\\':
+			r, ok := p.parseEscape()
+			if !ok {
+				return "", false
+			}
+			out.WriteRune(r)
+		default:
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			if r == utf8.RuneError && size == 1 {
+				p.errorf("invalid UTF-8 in string")
+				return "", false
+			}
+			out.WriteRune(r)
+			p.pos += size
+		}
+	}
+}
+
+func (p *jsonParser) parseEscape() (rune, bool) {
+	p.pos++ // backslash
+	if p.pos >= len(p.input) {
+		p.errorf("unterminated string")
+		return 0, false
+	}
+
+	ch := p.input[p.pos]
+	p.pos++
+	switch ch {
+	case '"', '\\', '/':
+		return rune(ch), true
+	case 'b':
+		return '\b', true
+	case 'f':
+		return '\f', true
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	case 'u':
+		r, ok := p.parseHex4()
+		if !ok {
+			return 0, false
+		}
+		if utf16.IsSurrogate(r) && strings.HasPrefix(p.input[p.pos:], `\u`) {
+			start := p.pos
+			p.pos += 2
+			low, ok := p.parseHex4()
+			if !ok {
+				return 0, false
+			}
+			if decoded := utf16.DecodeRune(r, low); decoded != utf8.RuneError {
+				return decoded, true
+			}
+			p.pos = start
+		}
+		if utf16.IsSurrogate(r) {
+			return utf8.RuneError, true
+		}
+		return r, true
+	default:
+		p.pos -= 2
+		p.errorf("invalid escape sequence '\\%c'", ch)
+		return 0, false
+	}
+}
+
+func (p *jsonParser) parseHex4() (rune, bool) {
+	if p.pos+4 > len(p.input) {
+		p.errorf("invalid unicode escape")
+		return 0, false
+	}
+
+	value, err := strconv.ParseUint(p.input[p.pos:p.pos+4], 16, 16)
+	if err != nil {
+		p.errorf("invalid unicode escape")
+		return 0, false
+	}
+
+	p.pos += 4
+	return rune(value), true
+}
+
+func (p *jsonParser) parseNumber() object.Object {
+	start := p.pos
+	isFloat := false
+
+	p.consume('-')
+	switch {
+	case p.consume('0'):
+	case p.pos < len(p.input) && isDigit(p.input[p.pos]):
+		p.skipDigits()
+	default:
+		return p.errorf("invalid number")
+	}
+
+	if p.consume('.') {
+		isFloat = true
+		if p.pos >= len(p.input) || !isDigit(p.input[p.pos]) {
+			return p.errorf("expected digit after decimal point")
+		}
+		p.skipDigits()
+	}
+
+	if p.consume('e') || p.consume('E') {
+		isFloat = true
+		if !p.consume('+') {
+			p.consume('-')
+		}
+		if p.pos >= len(p.input) || !isDigit(p.input[p.pos]) {
+			return p.errorf("expected digit in exponent")
+		}
+		p.skipDigits()
+	}
+
+	literal := p.input[start:p.pos]
+	if !isFloat {
+		if value, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return &object.Integer{Value: value}
+		}
+	}
+
+	value, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		p.pos = start
+		return p.errorf("number %s out of range", literal)
+	}
+	return &object.Float{Value: value}
+}
+
+func (p *jsonParser) skipDigits() {
+	for p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *jsonParser) skipWhitespace() {
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsonParser) consume(ch byte) bool {
+	if p.pos < len(p.input) && p.input[p.pos] == ch {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *jsonParser) describeCurrent() string {
+	if p.pos >= len(p.input) {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRuneInString(p.input[p.pos:])
+	return fmt.Sprintf("character %q", r)
+}
+
+// errorf records an error at the current position as line:column, both
+// starting at 1, with columns counted in characters.
+func (p *jsonParser) errorf(format string, a ...interface{}) object.Object {
+	if p.err != nil {
+		return nil
+	}
+
+	consumed := p.input[:p.pos]
+	line := strings.Count(consumed, "\n") + 1
+	column := utf8.RuneCountInString(consumed[strings.LastIndexByte(consumed, '\n')+1:]) + 1
+
+	msg := fmt.Sprintf(format, a...)
+	p.err = newError("json_parse: %s at line %d, column %d", msg, line, column)
+	return nil
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func jsonStringify(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		switch arg := args[1].(type) {
+		case *object.Integer:
+			if arg.Value < 0 || arg.Value > 10 {
+				return newError("indent for 'json_stringify' must be between 0 and 10, got %d", arg.Value)
+			}
+			indent = strings.Repeat(" ", int(arg.Value))
+		case *object.String:
+			indent = arg.Value
+		default:
+			return newError("indent for 'json_stringify' must be INTEGER or STRING, got %s", args[1].Type())
+		}
+	}
+
+	e := &jsonEncoder{indent: indent}
+	if err := e.encode(args[0], 0); err != nil {
+		return err
+	}
+	return &object.String{Value: e.out.String()}
+}
+
+type jsonEncoder struct {
+	out    strings.Builder
+	indent string
+}
+
+func (e *jsonEncoder) encode(obj object.Object, depth int) *object.Error {
+	if depth > maxJSONDepth {
+		return newError("json_stringify: exceeded maximum nesting depth of %d", maxJSONDepth)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Null:
+		e.out.WriteString("null")
+	case *object.Boolean:
+		e.out.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Integer:
+		e.out.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return newError("json_stringify: cannot serialize %s", obj.Inspect())
+		}
+		// Inspect keeps a fraction or exponent on whole numbers, so 1.0
+		// comes back from json_parse as a float rather than an integer.
+		e.out.WriteString(obj.Inspect())
+	case *object.String:
+		e.writeString(obj.Value)
+	case *object.Array:
+		if len(obj.Elements) == 0 {
+			e.out.WriteString("[]")
+			return nil
+		}
+
+		e.out.WriteString("[")
+		for i, el := range obj.Elements {
+			if i > 0 {
+				e.out.WriteString(",")
+			}
+			e.newline(depth + 1)
+			if err := e.encode(el, depth+1); err != nil {
+				return err
+			}
+		}
+		e.newline(depth)
+		e.out.WriteString("]")
+	case *object.Hash:
+		if obj.Len() == 0 {
+			e.out.WriteString("{}")
+			return nil
+		}
+
+		e.out.WriteString("{")
+		for i, pair := range obj.Pairs() {
+			if i > 0 {
+				e.out.WriteString(",")
+			}
+			e.newline(depth + 1)
+
+			switch key := pair.Key.(type) {
+			case *object.String:
+				e.writeString(key.Value)
+			case *object.Integer, *object.Boolean:
+				e.writeString(key.Inspect())
+			default:
+				return newError("json_stringify: cannot serialize %s as object key", key.Type())
+			}
+
+			e.out.WriteString(":")
+			if e.indent != "" {
+				e.out.WriteString(" ")
+			}
+			if err := e.encode(pair.Value, depth+1); err != nil {
+				return err
+			}
+		}
+		e.newline(depth)
+		e.out.WriteString("}")
+	default:
+		return newError("json_stringify: cannot serialize %s", obj.Type())
+	}
+
+	return nil
+}
+
+func (e *jsonEncoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.out.WriteString("\n")
+	e.out.WriteString(strings.Repeat(e.indent, depth))
+}
+
+func (e *jsonEncoder) writeString(s string) {
+	e.out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			e.out.WriteString(`\"`)
+		case '\\':
+			e.out.WriteString(`\\`)
+		case '\n':
+			e.out.WriteString(`\n`)
+		case '\r':
+			e.out.WriteString(`\r`)
+		case '\t':
+			e.out.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&e.out, `\u%04x`, r)
+			} else {
+				e.out.WriteRune(r)
+			}
+		}
+	}
+	e.out.WriteByte('"')
+}
@@ -0,0 +1,234 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+// ModuleExtension is appended to import paths that don't name a file
+// extension.
+const ModuleExtension = ".mk"
+
+// ModulePath lists directories searched, in order, for imports that can't be
+// resolved relative to the importing file.
+var ModulePath []string
+
+var (
+	// modules caches every module by absolute path so each one is evaluated
+	// once, however many files import it.
+	modules = map[string]*object.Module{}
+	// loading is the chain of modules currently being evaluated, used to
+	// report import cycles.
+	loading []string
+)
+
+// EvalFile evaluates the source file at path as the main program, so its
+// imports resolve relative to it and cycles back to it are detected.
+func EvalFile(path string) object.Object {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return newError("cannot resolve %s: %s", path, err)
+	}
+
+	program, errObj := parseModule(abs)
+	if errObj != nil {
+		return errObj
+	}
+
+	loading = append(loading, abs)
+	defer func() { loading = loading[:len(loading)-1] }()
+
+	return Eval(program, object.NewFileEnvironment(abs))
+}
+
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	name := moduleName(node)
+	if node.Alias == nil && !isIdentifier(name) {
+		return newError("cannot bind module %q to a name, use: import %q as name", node.Path.Value, node.Path.Value)
+	}
+
+	path, err := resolveModule(node.Path.Value, env.File())
+	if err != nil {
+		return err
+	}
+
+	module, ok := modules[path]
+	if !ok {
+		for i, loaded := range loading {
+			if loaded == path {
+				cycle := append(append([]string{}, loading[i:]...), path)
+				for j := range cycle {
+					cycle[j] = displayPath(cycle[j])
+				}
+				return newError("import cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		module, err = loadModule(path)
+		if err != nil {
+			return err
+		}
+		modules[path] = module
+	}
+
+	if err := define(env, name, module, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+func moduleName(node *ast.ImportStatement) string {
+	if node.Alias != nil {
+		return node.Alias.Value
+	}
+	base := filepath.Base(node.Path.Value)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// resolveModule finds the file an import refers to. Paths starting with
+// "./" or "../" are only looked up next to the importing file; others are
+// tried there first and then in each ModulePath directory.
+func resolveModule(importPath, importer string) (string, *object.Error) {
+	if filepath.Ext(importPath) == "" {
+		importPath += ModuleExtension
+	}
+
+	dir := "."
+	if importer != "" {
+		dir = filepath.Dir(importer)
+	}
+
+	candidates := []string{importPath}
+	if !filepath.IsAbs(importPath) {
+		candidates = []string{filepath.Join(dir, importPath)}
+		if !strings.HasPrefix(importPath, "./") && !strings.HasPrefix(importPath, "../") {
+			for _, searchDir := range ModulePath {
+				candidates = append(candidates, filepath.Join(searchDir, importPath))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		abs, err := filepath.Abs(candidate)
+		if err != nil {
+			return "", newError("cannot resolve module %q: %s", importPath, err)
+		}
+		return abs, nil
+	}
+
+	return "", newError("module %q not found", strings.TrimSuffix(importPath, ModuleExtension))
+}
+
+func loadModule(path string) (*object.Module, *object.Error) {
+	program, errObj := parseModule(path)
+	if errObj != nil {
+		return nil, errObj
+	}
+
+	loading = append(loading, path)
+	defer func() { loading = loading[:len(loading)-1] }()
+
+	env := object.NewFileEnvironment(path)
+	result := Eval(program, env)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errObj
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	module := &object.Module{Name: name, Path: path, Exports: map[string]object.Object{}}
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			// A top-level return can stop the module before an export
+			// runs, leaving its names unbound; those are not exported.
+			for _, name := range ast.PatternNames(export.Statement.Name) {
+				if value, ok := env.Get(name.Value); ok && value != nil {
+					module.Exports[name.Value] = value
+				}
+			}
+		}
+	}
+
+	return module, nil
+}
+
+// ParseFile reads the source file at path and expands its macros, for
+// tools that evaluate it in their own environments.
+func ParseFile(path string) (*ast.Program, *object.Error) {
+	return parseModule(path)
+}
+
+func parseModule(path string) (*ast.Program, *object.Error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newError("cannot read %s: %s", displayPath(path), err)
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("parse errors in %s: %s", displayPath(path), strings.Join(p.Errors(), "; "))
+	}
+
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, errObj := ExpandMacros(program, macroEnv)
+	if errObj != nil {
+		return nil, errObj
+	}
+
+	return expanded.(*ast.Program), nil
+}
+
+func evalMemberExpression(node *ast.MemberExpression, env *object.Environment) object.Object {
+	left := Eval(node.Object, env)
+	if isError(left) {
+		return left
+	}
+
+	module, ok := left.(*object.Module)
+	if !ok {
+		return newError("member access not supported: %s", left.Type())
+	}
+
+	value, ok := module.Exports[node.Property.Value]
+	if !ok || value == nil {
+		return newError("module %s has no exported member %s", module.Name, node.Property.Value)
+	}
+
+	return value
+}
+
+// displayPath shortens path relative to the working directory when possible.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, ch := range name {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package evaluator
+
+import (
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// bind binds the names in pattern to the parts of value they stand for,
+// as constants if constant is set. Elements and keys missing from value
+// take the defaults of their patterns, evaluated in env so that they can
+// refer to the names bound before them; those without defaults are
+// errors, as are values of the wrong shape. Extra elements and keys are
+// ignored unless collected by a rest name.
+func bind(pattern ast.Pattern, value object.Object, env *object.Environment, constant bool) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return define(env, pattern.Value, value, constant)
+
+	case *ast.LiteralPattern:
+		// only a default can reach here without having been matched
+		if !object.Equal(literalValue(pattern), value) {
+			return newError("cannot bind %s to %s", value.Inspect(), pattern)
+		}
+
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return newError("cannot bind %s to %s, which takes an array", value.Type(), pattern)
+		}
+		for i, el := range pattern.Elements {
+			if i < len(array.Elements) {
+				if err := bind(el, array.Elements[i], env, constant); err != nil {
+					return err
+				}
+				continue
+			}
+			def, err := evalDefault(el, env)
+			if err != nil {
+				return err
+			}
+			if def == nil {
+				return newError("no element at index %d for %s in %s", i, el, pattern)
+			}
+			if err := bind(el, def, env, constant); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := []object.Object{}
+			if len(array.Elements) > len(pattern.Elements) {
+				rest = append(rest, array.Elements[len(pattern.Elements):]...)
+			}
+			if err := define(env, pattern.Rest.Value, &object.Array{Elements: rest}, constant); err != nil {
+				return err
+			}
+		}
+
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return newError("cannot bind %s to %s, which takes a hash", value.Type(), pattern)
+		}
+		named := map[string]bool{}
+		for _, pair := range pattern.Pairs {
+			key := ast.KeyName(pair.Key)
+			named[key] = true
+			if found, ok := hash.Get(&object.String{Value: key}); ok {
+				if err := bind(pair.Value, found.Value, env, constant); err != nil {
+					return err
+				}
+				continue
+			}
+			def, err := evalDefault(pair.Value, env)
+			if err != nil {
+				return err
+			}
+			if def == nil {
+				return newError("no key %q for %s in %s", key, pair.Value, pattern)
+			}
+			if err := bind(pair.Value, def, env, constant); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := object.NewHash()
+			for _, pair := range hash.Pairs() {
+				if key, ok := pair.Key.(*object.String); ok && named[key.Value] {
+					continue
+				}
+				key, _ := object.AsHashable(pair.Key)
+				rest.Set(key, pair.Value)
+			}
+			if err := define(env, pattern.Rest.Value, rest, constant); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// define binds name to value in env, as a constant if constant is set,
+// failing if name is already a constant there.
+func define(env *object.Environment, name string, value object.Object, constant bool) *object.Error {
+	set := env.Set
+	if constant {
+		set = env.SetConst
+	}
+	if errObj, ok := set(name, value).(*object.Error); ok {
+		return errObj
+	}
+	return nil
+}
+
+// evalDefault evaluates the default of pattern, which is nil if it has
+// none.
+func evalDefault(pattern ast.Pattern, env *object.Environment) (object.Object, *object.Error) {
+	def := ast.DefaultOf(pattern)
+	if def == nil {
+		return nil, nil
+	}
+	value := Eval(def, env)
+	if errObj, ok := value.(*object.Error); ok {
+		return nil, errObj
+	}
+	return value, nil
+}
+
+// matches reports whether value has the shape pattern takes in the arm of
+// a match, with equal literals. Unlike a let, which ignores them, an array
+// pattern without a rest name does not match an array with extra
+// elements. Missing elements and keys match if they have defaults.
+func matches(pattern ast.Pattern, value object.Object) bool {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return true
+
+	case *ast.LiteralPattern:
+		return object.Equal(literalValue(pattern), value)
+
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return false
+		}
+		if pattern.Rest == nil && len(array.Elements) > len(pattern.Elements) {
+			return false
+		}
+		for i, el := range pattern.Elements {
+			if i < len(array.Elements) {
+				if !matches(el, array.Elements[i]) {
+					return false
+				}
+			} else if ast.DefaultOf(el) == nil {
+				return false
+			}
+		}
+		return true
+
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return false
+		}
+		for _, pair := range pattern.Pairs {
+			if found, ok := hash.Get(&object.String{Value: ast.KeyName(pair.Key)}); ok {
+				if !matches(pair.Value, found.Value) {
+					return false
+				}
+			} else if ast.DefaultOf(pair.Value) == nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// literalValue returns the value of the literal in pattern.
+func literalValue(pattern *ast.LiteralPattern) object.Object {
+	switch lit := pattern.Value.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: lit.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: lit.Value}
+	case *ast.Boolean:
+		return nativeBoolToBooleanObject(lit.Value)
+	}
+	return NULL
+}
@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// A Tracer follows evaluation, for debuggers. Its methods run on the
+// goroutine doing the evaluation, which they may block to pause it.
+type Tracer interface {
+	// Step is called before each node is evaluated.
+	Step(node ast.Node, env *object.Environment)
+	// Enter is called before the body of a function is run for call, and
+	// Leave once it has returned result. call is nil when a builtin such as
+	// filter calls the function.
+	Enter(call *ast.CallExpression, fn *object.Function)
+	Leave(call *ast.CallExpression, result object.Object)
+}
+
+// A BranchTracer is a Tracer that is also told which way each if
+// expression goes, for coverage.
+type BranchTracer interface {
+	Tracer
+	// Branch is called once the condition of ie has been evaluated, with
+	// whether the consequence is run.
+	Branch(ie *ast.IfExpression, consequence bool)
+}
+
+var tracer Tracer
+
+// SetTracer makes t follow all evaluation from now on, or stops tracing if
+// t is nil.
+func SetTracer(t Tracer) {
+	tracer = t
+}
+
+// applyTracedFunction applies fn for call, telling the tracer about it.
+// call is nil for functions called back by builtins.
+func applyTracedFunction(call *ast.CallExpression, fn object.Object, args []object.Object) object.Object {
+	name := ""
+	if call != nil {
+		name = calleeName(call.Function)
+	}
+	function, ok := fn.(*object.Function)
+	if tracer == nil || !ok {
+		return applyFunction(name, fn, args)
+	}
+
+	t := tracer
+	t.Enter(call, function)
+	result := applyFunction(name, fn, args)
+	t.Leave(call, result)
+	return result
+}
+
+// calleeName is the name a call gives its function, for errors: the
+// identifier or module member called, or "" for other expressions.
+func calleeName(function ast.Expression) string {
+	switch function := function.(type) {
+	case *ast.Identifier:
+		return function.Value
+	case *ast.MemberExpression:
+		return function.String()
+	}
+	return ""
+}
+
+// CallName names the function fn run for call, for debuggers and
+// profilers: as the call is written if it calls a name or a module member,
+// or else by the name fn was declared with. call may be nil, as for
+// Tracer.Enter.
+func CallName(call *ast.CallExpression, fn *object.Function) string {
+	if call != nil {
+		switch call.Function.(type) {
+		case *ast.Identifier, *ast.MemberExpression:
+			return dotted(call.Function)
+		}
+	}
+	if fn.Name != "" {
+		return fn.Name
+	}
+	return "anonymous function"
+}
+
+// dotted writes a member expression as in the source, a.b.c.
+func dotted(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.MemberExpression:
+		return dotted(e.Object) + "." + e.Property.Value
+	}
+	return e.String()
+}
+
+func traceBranch(ie *ast.IfExpression, consequence bool) {
+	if t, ok := tracer.(BranchTracer); ok {
+		t.Branch(ie, consequence)
+	}
+}
@@ -0,0 +1,113 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+type recordingTracer struct {
+	events []string
+}
+
+func (r *recordingTracer) Step(node ast.Node, env *object.Environment) {
+	if _, ok := node.(*ast.ExpressionStatement); ok {
+		r.events = append(r.events, "step "+node.String())
+	}
+	if _, ok := node.(*ast.LetStatement); ok {
+		r.events = append(r.events, "step "+node.String())
+	}
+}
+
+func (r *recordingTracer) Enter(call *ast.CallExpression, fn *object.Function) {
+	r.events = append(r.events, "enter "+call.String())
+}
+
+func (r *recordingTracer) Leave(call *ast.CallExpression, result object.Object) {
+	r.events = append(r.events, fmt.Sprintf("leave %s = %s", call, result.Inspect()))
+}
+
+func TestTracer(t *testing.T) {
+	input := `let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };
+let double = fn(x) { x * 2 };
+unless(false, double(len("ab")));`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+
+	tracer := &recordingTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	expanded, errObj := ExpandMacros(program, macroEnv)
+	if errObj != nil {
+		t.Fatalf("macro expansion failed: %s", errObj.Inspect())
+	}
+	testIntegerObject(t, Eval(expanded, object.NewEnvironment()), 4)
+
+	expected := []string{
+		"step let double = fn(x) { (x * 2) };",
+		`step if ((!false)) { double(len("ab")) }`,
+		`step double(len("ab"))`,
+		`enter double(len("ab"))`,
+		"step (x * 2)",
+		`leave double(len("ab")) = 4`,
+	}
+	if got := strings.Join(tracer.events, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("wrong trace.\nexpected=\n%s\ngot=\n%s", strings.Join(expected, "\n"), got)
+	}
+}
+
+type branchTracer struct {
+	recordingTracer
+	branches []string
+}
+
+func (b *branchTracer) Branch(ie *ast.IfExpression, consequence bool) {
+	b.branches = append(b.branches, fmt.Sprintf("%d:%d %t", ie.Token.Line, ie.Token.Column, consequence))
+}
+
+func TestBranchTracer(t *testing.T) {
+	input := `let sign = fn(x) {
+  if (x < 0) { -1 } else { if (x > 0) { 1 } }
+};
+sign(-5); sign(0); sign(3);`
+
+	tracer := &branchTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+	testEval(input)
+
+	expected := []string{"2:3 true", "2:3 false", "2:28 false", "2:3 false", "2:28 true"}
+	if got := strings.Join(tracer.branches, ", "); got != strings.Join(expected, ", ") {
+		t.Errorf("wrong branches. expected=%s, got=%s", strings.Join(expected, ", "), got)
+	}
+}
+
+func TestCallName(t *testing.T) {
+	f := &ast.Identifier{Value: "f"}
+	indexed := &ast.IndexExpression{Left: &ast.Identifier{Value: "fs"}, Index: &ast.IntegerLiteral{Value: 0}}
+	tests := []struct {
+		function ast.Expression
+		name     string // of the function called
+		expected string
+	}{
+		{f, "g", "f"},
+		{&ast.MemberExpression{Object: &ast.Identifier{Value: "m"}, Property: f}, "", "m.f"},
+		{indexed, "g", "g"},
+		{indexed, "", "anonymous function"},
+	}
+
+	for _, tt := range tests {
+		got := CallName(&ast.CallExpression{Function: tt.function}, &object.Function{Name: tt.name})
+		if got != tt.expected {
+			t.Errorf("wrong call name for %s. expected=%q, got=%q", tt.function, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package format
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"--- old\n+++ new\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+		{
+			"x\ny",
+			"x\n",
+			"--- old\n+++ new\n@@ -1,2 +1 @@\n x\n-y\n\\ No newline at end of file\n",
+		},
+		{
+			"",
+			"let a = 1;\n",
+			"--- old\n+++ new\n@@ -0,0 +1 @@\n+let a = 1;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got := string(Diff("old", []byte(tt.old), "new", []byte(tt.new)))
+		if got != tt.expected {
+			t.Errorf("wrong diff of %q and %q.\nwant=%q\ngot= %q", tt.old, tt.new, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,708 @@
+// Package format pretty-prints Monkey source code.
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
+
+const (
+	// LineWidth is the column past which calls and literals are broken
+	// into one element per line.
+	LineWidth = 80
+	// Indent is written once per nesting level.
+	Indent = "    "
+)
+
+// Source formats src and returns the result. Formatting is idempotent:
+// formatting the result again doesn't change it. Blocks, calls and array
+// and hash literals that span lines in src stay broken over lines, while
+// ones written on a single line stay that way as long as they fit.
+func Source(src []byte) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	f := newFormatter(string(src), program)
+	out := f.statements(-1, len(f.tokens)-1, program.Statements, 0, false)
+	return []byte(out), nil
+}
+
+// A comment is a source comment along with where it sits among the tokens.
+type comment struct {
+	text     string
+	line     int
+	after    int  // index of the last token before the comment, -1 if none
+	trailing bool // the comment follows a token on the same line
+}
+
+type memoKey struct {
+	node        ast.Node
+	indent, col int
+}
+
+type formatter struct {
+	tokens   []token.Token
+	index    map[[2]int]int      // token index by line and column
+	closing  map[int]int         // index of an opening bracket -> its closing bracket
+	comments map[int][]*comment  // comments by the opening token of the list that holds them
+	memo     map[memoKey]string
+}
+
+func newFormatter(src string, program *ast.Program) *formatter {
+	f := &formatter{
+		index:    map[[2]int]int{},
+		closing:  map[int]int{},
+		comments: map[int][]*comment{},
+		memo:     map[memoKey]string{},
+	}
+
+	l := lexer.New(src)
+	for {
+		tok := l.NextToken()
+		f.index[[2]int{tok.Line, tok.Column}] = len(f.tokens)
+		f.tokens = append(f.tokens, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	// outer maps each opening bracket to the one around it, and enclosing
+	// maps each token to the innermost bracket open after it.
+	outer := map[int]int{}
+	enclosing := make([]int, len(f.tokens))
+	stack := []int{-1}
+	for i, tok := range f.tokens {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			outer[i] = stack[len(stack)-1]
+			stack = append(stack, i)
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			if len(stack) > 1 {
+				f.closing[stack[len(stack)-1]] = i
+				stack = stack[:len(stack)-1]
+			}
+		}
+		enclosing[i] = stack[len(stack)-1]
+	}
+
+	// Comments belong to the innermost block, call, array, hash or match
+	// arms around them, or to the program.
+	lists := map[int]bool{-1: true}
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStatement, *ast.CallExpression, *ast.ArrayLiteral, *ast.HashLiteral:
+			if i, ok := f.tokenIndex(n); ok {
+				lists[i] = true
+			}
+		case *ast.MatchExpression:
+			if i, ok := f.armsIndex(n); ok {
+				lists[i] = true
+			}
+		}
+		return true
+	})
+
+	next := 0
+	for _, c := range l.Comments() {
+		for next < len(f.tokens) && before(f.tokens[next], c) {
+			next++
+		}
+		after := next - 1
+
+		cm := &comment{text: strings.TrimRight(c.Literal, " \t\r"), line: c.Line, after: after}
+		cm.trailing = after >= 0 && f.tokens[after].Line == c.Line
+
+		list := -1
+		if after >= 0 {
+			list = enclosing[after]
+		}
+		for !lists[list] {
+			list = outer[list]
+		}
+		f.comments[list] = append(f.comments[list], cm)
+	}
+
+	return f
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+func (f *formatter) tokenIndex(n ast.Node) (int, bool) {
+	tok, ok := ast.TokenOf(n)
+	if !ok {
+		return 0, false
+	}
+	i, ok := f.index[[2]int{tok.Line, tok.Column}]
+	return i, ok
+}
+
+// armsIndex returns the index of the brace opening the arms of me, which
+// follows the parenthesized value.
+func (f *formatter) armsIndex(me *ast.MatchExpression) (int, bool) {
+	i, ok := f.tokenIndex(me)
+	if !ok || i+1 >= len(f.tokens) {
+		return 0, false
+	}
+	rparen, ok := f.closing[i+1]
+	return rparen + 1, ok
+}
+
+// arms formats the arms of me with the opening brace at column col, on
+// one line if they were in the source, hold no comments and fit, or else
+// one per line.
+func (f *formatter) arms(me *ast.MatchExpression, indent, col int) string {
+	arm := func(i, indent, col int) string {
+		a := me.Arms[i]
+		out := f.pattern(a.Pattern, indent, col)
+		if a.Guard != nil {
+			out += " if "
+			out += f.expr(a.Guard, indent, endColumn(col, out))
+		}
+		out += " => "
+		return out + f.expr(a.Body, indent, endColumn(col, out))
+	}
+
+	open, _ := f.armsIndex(me)
+	if close, ok := f.closing[open]; ok && f.tokens[open].Line == f.tokens[close].Line && len(f.comments[open]) == 0 {
+		out := "{ "
+		for i := range me.Arms {
+			if i > 0 {
+				out += ", "
+			}
+			out += arm(i, indent, endColumn(col, out))
+		}
+		out += " }"
+		if !strings.Contains(out, "\n") && col+len(out) <= LineWidth {
+			return out
+		}
+	}
+
+	starts := make([]int, len(me.Arms))
+	for i, a := range me.Arms {
+		bound := open
+		if i > 0 {
+			bound = starts[i-1]
+		}
+		starts[i] = f.start(a, bound)
+	}
+	return f.brokenList("{", "}", open, starts, indent, arm)
+}
+
+// start returns the index of the first token of n, counting the opening
+// parentheses of a grouped expression that come after bound.
+func (f *formatter) start(n ast.Node, bound int) int {
+	first := -1
+	ast.Inspect(n, func(n ast.Node) bool {
+		if i, ok := f.tokenIndex(n); ok && (first < 0 || i < first) {
+			first = i
+		}
+		return true
+	})
+	for first-1 > bound && f.tokens[first-1].Type == token.LPAREN {
+		first--
+	}
+	return first
+}
+
+// layout sorts the comments of a list into those before, after and inside
+// each of its items, given the token index each item starts at.
+type layout struct {
+	leading, trailing, inner [][]*comment
+	dangling                 []*comment // after the last item
+}
+
+func (f *formatter) layout(open, close int, starts []int) layout {
+	k := len(starts)
+	lay := layout{
+		leading:  make([][]*comment, k),
+		trailing: make([][]*comment, k),
+		inner:    make([][]*comment, k),
+	}
+
+	for _, c := range f.comments[open] {
+		if k == 0 {
+			lay.dangling = append(lay.dangling, c)
+			continue
+		}
+		if c.after < starts[0] {
+			lay.leading[0] = append(lay.leading[0], c)
+			continue
+		}
+
+		j := k - 1
+		for starts[j] > c.after {
+			j--
+		}
+		end := close - 1
+		if j+1 < k {
+			end = starts[j+1] - 1
+		}
+
+		switch {
+		case c.after < end:
+			lay.inner[j] = append(lay.inner[j], c)
+		case c.trailing && len(lay.trailing[j]) == 0:
+			lay.trailing[j] = append(lay.trailing[j], c)
+		case j+1 < k:
+			lay.leading[j+1] = append(lay.leading[j+1], c)
+		default:
+			lay.dangling = append(lay.dangling, c)
+		}
+	}
+
+	return lay
+}
+
+// statements formats the statements of the program or of a block opened
+// by the token at open, one per line at indent.
+func (f *formatter) statements(open, close int, stmts []ast.Statement, indent int, inBlock bool) string {
+	starts := make([]int, len(stmts))
+	for i, stmt := range stmts {
+		bound := open
+		if i > 0 {
+			bound = starts[i-1]
+		}
+		starts[i] = f.start(stmt, bound)
+	}
+	lay := f.layout(open, close, starts)
+
+	texts := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		texts[i] = f.statement(stmt, indent)
+	}
+
+	var out strings.Builder
+	prefix := strings.Repeat(Indent, indent)
+	lastLine := 0 // source line of what was written last
+	if open >= 0 {
+		lastLine = f.tokens[open].Line
+	}
+	line := func(srcLine int, text string) {
+		if out.Len() > 0 && srcLine > lastLine+1 {
+			out.WriteString("\n")
+		}
+		out.WriteString(prefix + text + "\n")
+		if srcLine > lastLine {
+			lastLine = srcLine
+		}
+	}
+	comments := func(list []*comment) {
+		for _, c := range list {
+			line(c.line, c.text)
+		}
+	}
+
+	for i := range stmts {
+		comments(lay.leading[i])
+
+		text := texts[i]
+		if needsSemicolon(stmts, texts, i, inBlock) {
+			text += ";"
+		}
+		for _, c := range lay.trailing[i] {
+			text += " " + c.text
+		}
+
+		line(f.tokens[starts[i]].Line, text)
+		end := close - 1
+		if i+1 < len(stmts) {
+			end = starts[i+1] - 1
+		}
+		if f.tokens[end].Line > lastLine {
+			lastLine = f.tokens[end].Line
+		}
+
+		comments(lay.inner[i])
+	}
+	comments(lay.dangling)
+
+	return out.String()
+}
+
+// needsSemicolon reports whether expression statement i is followed by a
+// semicolon. The last statement of a block, the block's value, goes
+// without, as do if expressions unless the next statement would otherwise
+// continue them as a call, an index or a subtraction.
+func needsSemicolon(stmts []ast.Statement, texts []string, i int, inBlock bool) bool {
+	es, ok := stmts[i].(*ast.ExpressionStatement)
+	if !ok || inBlock && i == len(stmts)-1 {
+		return false
+	}
+	if _, ok := es.Expression.(*ast.IfExpression); ok {
+		return i+1 < len(stmts) && strings.ContainsAny(texts[i+1][:1], "([-")
+	}
+	return true
+}
+
+// statement formats stmt starting at indent. Expression statements are
+// returned without their semicolon, which depends on the statements
+// around them.
+func (f *formatter) statement(stmt ast.Statement, indent int) string {
+	col := len(Indent) * indent
+
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		if s.IsDeclaration() {
+			return f.expr(s.Value, indent, col)
+		}
+		keyword := "let "
+		if s.IsConst() {
+			keyword = "const "
+		}
+		prefix := keyword + f.pattern(s.Name, indent, col+len(keyword)) + " = "
+		return prefix + f.expr(s.Value, indent, col+len(prefix)) + ";"
+	case *ast.ReturnStatement:
+		if s.ReturnValue == nil {
+			return "return;"
+		}
+		return "return " + f.expr(s.ReturnValue, indent, col+len("return ")) + ";"
+	case *ast.ExpressionStatement:
+		return f.expr(s.Expression, indent, col)
+	case *ast.ImportStatement:
+		out := "import \"" + s.Path.Value + "\""
+		if s.Alias != nil {
+			out += " as " + s.Alias.Value
+		}
+		return out + ";"
+	case *ast.ExportStatement:
+		return "export " + f.statement(s.Statement, indent)
+	case *ast.TestStatement:
+		prefix := "test \"" + s.Name.Value + "\" "
+		return prefix + f.block(s.Body, indent, col+len(prefix), f.inlineBlock(s.Body))
+	}
+	panic(fmt.Sprintf("format: unexpected statement %T", stmt))
+}
+
+// expr formats e starting at column col of a line indented by indent.
+func (f *formatter) expr(e ast.Expression, indent, col int) string {
+	key := memoKey{e, indent, col}
+	if out, ok := f.memo[key]; ok {
+		return out
+	}
+	out := f.format(e, indent, col)
+	f.memo[key] = out
+	return out
+}
+
+func (f *formatter) format(e ast.Expression, indent, col int) string {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(e.Value, 10)
+	case *ast.StringLiteral:
+		return `"` + e.Value + `"`
+	case *ast.Boolean:
+		return strconv.FormatBool(e.Value)
+
+	case *ast.PrefixExpression:
+		return e.Operator + f.operand(e.Right, parser.PREFIX, false, indent, col+len(e.Operator))
+
+	case *ast.InfixExpression:
+		prec := parser.PrecedenceOf(token.TokenType(e.Operator))
+		left := f.operand(e.Left, prec, false, indent, col)
+		op := " " + e.Operator + " "
+		return left + op + f.operand(e.Right, prec, true, indent, endColumn(col, left)+len(op))
+
+	case *ast.IfExpression:
+		prefix := "if (" + f.expr(e.Condition, indent, col+len("if (")) + ") "
+		inline := f.inlineBlock(e.Consequence) && (e.Alternative == nil || f.inlineBlock(e.Alternative))
+		out := prefix + f.block(e.Consequence, indent, endColumn(col, prefix), inline)
+		if e.Alternative != nil {
+			out += " else "
+			out += f.block(e.Alternative, indent, endColumn(col, out), inline)
+		}
+		return out
+
+	case *ast.MatchExpression:
+		prefix := "match (" + f.expr(e.Value, indent, col+len("match (")) + ") "
+		return prefix + f.arms(e, indent, endColumn(col, prefix))
+
+	case *ast.FunctionLiteral:
+		prefix := "fn("
+		if e.Name != nil {
+			prefix = "fn " + e.Name.Value + "("
+		}
+		prefix += f.parameters(e.Parameters, e.Rest, indent, col+len(prefix)) + ") "
+		if e.ReturnType != nil {
+			prefix += "-> " + e.ReturnType.String() + " "
+		}
+		return prefix + f.block(e.Body, indent, endColumn(col, prefix), f.inlineBlock(e.Body))
+
+	case *ast.MacroLiteral:
+		params := []ast.Pattern{}
+		for _, param := range e.Parameters {
+			params = append(params, param)
+		}
+		prefix := "macro(" + f.parameters(params, nil, indent, col+len("macro(")) + ") "
+		return prefix + f.block(e.Body, indent, endColumn(col, prefix), f.inlineBlock(e.Body))
+
+	case *ast.CallExpression:
+		function := f.operand(e.Function, parser.CALL, false, indent, col)
+		open, _ := f.tokenIndex(e)
+		items := make([]ast.Node, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			items[i] = arg
+		}
+		return function + f.list("(", ")", open, items, indent, endColumn(col, function), func(i, indent, col int) string {
+			return f.expr(e.Arguments[i], indent, col)
+		})
+
+	case *ast.ArrayLiteral:
+		open, _ := f.tokenIndex(e)
+		items := make([]ast.Node, len(e.Elements))
+		for i, el := range e.Elements {
+			items[i] = el
+		}
+		return f.list("[", "]", open, items, indent, col, func(i, indent, col int) string {
+			return f.expr(e.Elements[i], indent, col)
+		})
+
+	case *ast.HashLiteral:
+		open, _ := f.tokenIndex(e)
+		items := make([]ast.Node, len(e.Keys))
+		for i, key := range e.Keys {
+			items[i] = key
+		}
+		return f.list("{", "}", open, items, indent, col, func(i, indent, col int) string {
+			key := f.expr(e.Keys[i], indent, col) + ": "
+			return key + f.expr(e.Pairs[e.Keys[i]], indent, endColumn(col, key))
+		})
+
+	case *ast.IndexExpression:
+		left := f.operand(e.Left, parser.INDEX, false, indent, col)
+		return left + "[" + f.expr(e.Index, indent, endColumn(col, left)+1) + "]"
+
+	case *ast.MemberExpression:
+		return f.operand(e.Object, parser.INDEX, false, indent, col) + "." + e.Property.Value
+
+	case *ast.SpreadExpression:
+		return "..." + f.expr(e.Value, indent, col+len("..."))
+	}
+	panic(fmt.Sprintf("format: unexpected expression %T", e))
+}
+
+// operand formats e as an operand of an operator that binds with prec,
+// adding parentheses where the parser would otherwise group it differently.
+func (f *formatter) operand(e ast.Expression, prec parser.Precedence, right bool, indent, col int) string {
+	own := parser.INDEX + 1
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		own = parser.PrecedenceOf(token.TokenType(e.Operator))
+	case *ast.PrefixExpression:
+		own = parser.PREFIX
+	}
+
+	if own < prec || right && own == prec {
+		return "(" + f.expr(e, indent, col+1) + ")"
+	}
+	return f.expr(e, indent, col)
+}
+
+// inlineBlock reports whether a block can be written on one line: it was
+// on one line in the source and has at most one statement and no comments.
+func (f *formatter) inlineBlock(b *ast.BlockStatement) bool {
+	open, ok := f.tokenIndex(b)
+	if !ok || len(b.Statements) > 1 || len(f.comments[open]) > 0 {
+		return false
+	}
+	close, ok := f.closing[open]
+	return ok && f.tokens[open].Line == f.tokens[close].Line
+}
+
+// block formats b with its opening brace at column col, on one line if
+// inline is set and it fits.
+func (f *formatter) block(b *ast.BlockStatement, indent, col int, inline bool) string {
+	if inline {
+		if len(b.Statements) == 0 {
+			return "{}"
+		}
+		stmt := f.statement(b.Statements[0], indent)
+		if out := "{ " + stmt + " }"; !strings.Contains(stmt, "\n") && col+len(out) <= LineWidth {
+			return out
+		}
+	}
+
+	open, _ := f.tokenIndex(b)
+	close := f.closing[open]
+	return "{\n" + f.statements(open, close, b.Statements, indent+1, true) + strings.Repeat(Indent, indent) + "}"
+}
+
+// list formats the items of a call or literal between open and close, all
+// on the line of the opening bracket if they fit (only the last item may
+// spill over several lines) or else one per line. A list that was broken
+// over lines in the source or that holds comments is always broken.
+func (f *formatter) list(open, close string, openIndex int, items []ast.Node, indent, col int,
+	item func(i, indent, col int) string) string {
+	if len(items) == 0 && len(f.comments[openIndex]) == 0 {
+		return open + close
+	}
+
+	starts := make([]int, len(items))
+	for i, n := range items {
+		bound := openIndex
+		if i > 0 {
+			bound = starts[i-1]
+		}
+		starts[i] = f.start(n, bound)
+	}
+
+	broken := len(f.comments[openIndex]) > 0 ||
+		len(items) > 0 && f.tokens[starts[0]].Line > f.tokens[openIndex].Line
+
+	if !broken {
+		out := open
+		for i := range items {
+			if i > 0 {
+				out += ", "
+			}
+			text := item(i, indent, endColumn(col, out))
+			if strings.Contains(text, "\n") && (i < len(items)-1 || !f.hangs(items[i])) {
+				broken = true
+				break
+			}
+			out += text
+		}
+		out += close
+
+		firstLine, _, multiline := strings.Cut(out, "\n")
+		if !broken && (multiline || col+len(out) <= LineWidth) && col+len(firstLine) <= LineWidth {
+			return out
+		}
+	}
+
+	return f.brokenList(open, close, openIndex, starts, indent, item)
+}
+
+// brokenList formats the items of a list opened by the token at
+// openIndex one per line, given the token index each item starts at.
+func (f *formatter) brokenList(open, close string, openIndex int, starts []int, indent int,
+	item func(i, indent, col int) string) string {
+	closeIndex := f.closing[openIndex]
+	lay := f.layout(openIndex, closeIndex, starts)
+	prefix := strings.Repeat(Indent, indent+1)
+
+	var out strings.Builder
+	out.WriteString(open + "\n")
+	writeComments := func(list []*comment) {
+		for _, c := range list {
+			out.WriteString(prefix + c.text + "\n")
+		}
+	}
+
+	for i := range starts {
+		writeComments(lay.leading[i])
+		out.WriteString(prefix + item(i, indent+1, len(prefix)))
+		if i < len(starts)-1 {
+			out.WriteString(",")
+		}
+		for _, c := range lay.trailing[i] {
+			out.WriteString(" " + c.text)
+		}
+		out.WriteString("\n")
+		writeComments(lay.inner[i])
+	}
+	writeComments(lay.dangling)
+
+	out.WriteString(strings.Repeat(Indent, indent) + close)
+	return out.String()
+}
+
+// hangs reports whether n may be the last item of a list written on one
+// line even though it spans several: a function, macro, if or match
+// expression, or a list that was broken over lines in the source.
+func (f *formatter) hangs(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.FunctionLiteral, *ast.MacroLiteral, *ast.IfExpression, *ast.MatchExpression:
+		return true
+	case *ast.CallExpression, *ast.ArrayLiteral, *ast.HashLiteral:
+		open, ok := f.tokenIndex(n)
+		if !ok {
+			return false
+		}
+		if len(f.comments[open]) > 0 {
+			return true
+		}
+		close := f.closing[open]
+		return close > open+1 && f.tokens[open+1].Line > f.tokens[open].Line
+	}
+	return false
+}
+
+// endColumn returns the column reached by writing s from column col.
+func endColumn(col int, s string) int {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return len(s) - i - 1
+	}
+	return col + len(s)
+}
+
+// parameters formats the parameters of a function, or the elements of
+// an array pattern, with the rest parameter, if any, last.
+func (f *formatter) parameters(list []ast.Pattern, rest *ast.Identifier, indent, col int) string {
+	out := ""
+	for i, param := range list {
+		if i > 0 {
+			out += ", "
+		}
+		out += f.pattern(param, indent, endColumn(col, out))
+	}
+	if rest != nil {
+		if len(list) > 0 {
+			out += ", "
+		}
+		out += "..." + rest.String()
+	}
+	return out
+}
+
+// pattern formats what a let statement or parameter binds, with its
+// default, if any.
+func (f *formatter) pattern(p ast.Pattern, indent, col int) string {
+	out := ""
+	switch p := p.(type) {
+	case *ast.Identifier:
+		out = p.Value
+		if p.Type != nil {
+			out += ": " + p.Type.String()
+		}
+	case *ast.LiteralPattern:
+		out = f.expr(p.Value, indent, col)
+	case *ast.ArrayPattern:
+		out = "[" + f.parameters(p.Elements, p.Rest, indent, col+1) + "]"
+	case *ast.HashPattern:
+		out = "{"
+		for i, pair := range p.Pairs {
+			if i > 0 {
+				out += ", "
+			}
+			if !pair.IsShorthand() {
+				out += f.expr(pair.Key, indent, endColumn(col, out)) + ": "
+			}
+			out += f.pattern(pair.Value, indent, endColumn(col, out))
+		}
+		if p.Rest != nil {
+			if len(p.Pairs) > 0 {
+				out += ", "
+			}
+			out += "..." + p.Rest.String()
+		}
+		out += "}"
+	}
+	if def := ast.DefaultOf(p); def != nil {
+		out += " = "
+		out += f.expr(def, indent, endColumn(col, out))
+	}
+	return out
+}
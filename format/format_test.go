@@ -0,0 +1,139 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"return   x*2", "return x * 2;\n"},
+		{"a;b;c", "a;\nb;\nc;\n"},
+		{"(a + b) * c; a + (b + c); a - (b - c); -(a + b); (-a)[0]; -a[0]",
+			"(a + b) * c;\na + (b + c);\na - (b - c);\n-(a + b);\n(-a)[0];\n-a[0];\n"},
+		{"(a || b) && c; a || (b && c); a && (b && c); !(a && b); (a == b) && c",
+			"(a || b) && c;\na || b && c;\na && (b && c);\n!(a && b);\na == b && c;\n"},
+		{`import "lib"as l;export let v=l.f(1)`, "import \"lib\" as l;\nexport let v = l.f(1);\n"},
+		{"test \"adds\"{assert_eq(1+1,2)}\ntest \"b\" {\nlet x=1\nx}", "test \"adds\" { assert_eq(1 + 1, 2) }\ntest \"b\" {\n    let x = 1;\n    x\n}\n"},
+		{"let f = fn(x,y){x+y};", "let f = fn(x, y) { x + y };\n"},
+		{"let f = fn(x,y){\nlet z = x+y\nz}", "let f = fn(x, y) {\n    let z = x + y;\n    z\n};\n"},
+		{"let f = fn() {}; let g = fn() {\n}", "let f = fn() {};\nlet g = fn() {\n};\n"},
+		{"if (a) { b } else { c }", "if (a) { b } else { c }\n"},
+		{"if (a) { b } else {\nc }", "if (a) {\n    b\n} else {\n    c\n}\n"},
+		{"if (a) { b }\n-1", "if (a) { b } - 1;\n"},
+		{"if (a) { b };\n[1][0]", "if (a) { b };\n[1][0];\n"},
+		{"if (a) { b }\nc", "if (a) { b }\nc;\n"},
+		{`{"a" : 1,"b":2,}`, "{\"a\": 1, \"b\": 2};\n"},
+		{"let m = macro(x) { quote(unquote(x)) }", "let m = macro(x) { quote(unquote(x)) };\n"},
+		{"let f:fn(int)->[int]=fn(a:int,b)->[int]{[a]}", "let f: fn(int) -> [int] = fn(a: int, b) -> [int] { [a] };\n"},
+		{"let f=fn(a,b=(1+2)*3,...rest){rest};f(1,...[2,3])", "let f = fn(a, b = (1 + 2) * 3, ...rest) { rest };\nf(1, ...[2, 3]);\n"},
+		{"fn(...r: [int]) {}", "fn(...r: [int]) {};\n"},
+		{"fn  add(a,b){a+b} add(1,2)", "fn add(a, b) { a + b }\nadd(1, 2);\n"},
+		{"export fn f(){\n1}\nlet g=fn go(n){go(n)};", "export fn f() {\n    1\n}\nlet g = fn go(n) { go(n) };\n"},
+		{"let f=fn f(x){x}", "let f = fn f(x) { x };\n"},
+		{"let [a,b=1,...c]=x", "let [a, b = 1, ...c] = x;\n"},
+		{`let {name,"full name":n,age:years=(1+2)*3,p:{q},...o}=x`, "let {name, \"full name\": n, age: years = (1 + 2) * 3, p: {q}, ...o} = x;\n"},
+		{"fn swap([a,b],{c}={}){[b,a]}", "fn swap([a, b], {c} = {}) { [b, a] }\n"},
+		{"const  x=5;export const [a,b]=x", "const x = 5;\nexport const [a, b] = x;\n"},
+		{"match(x){1=>a,-2 if b=>c,[d,...e]=>d,{f:\"g\",h=1}=>h,_=>0}",
+			"match (x) { 1 => a, -2 if b => c, [d, ...e] => d, {f: \"g\", h = 1} => h, _ => 0 };\n"},
+		{"match (x) {\n1 => a, // one\n// any\n_ => b,\n}", "match (x) {\n    1 => a, // one\n    // any\n    _ => b\n};\n"},
+		{"let y = match (x) { n if n > 1 => fn() {\nn} }", "let y = match (x) {\n    n if n > 1 => fn() {\n        n\n    }\n};\n"},
+
+		// blank lines
+		{"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;", "let a = 1;\n\nlet b = 2;\nlet c = 3;\n"},
+		{"\n\nlet a = fn() {\n\n  a\n\n};\n\n", "let a = fn() {\n    a\n};\n"},
+
+		// line breaking
+		{
+			"let list = [\"alpha\", \"beta\", \"gamma\", \"delta\", \"epsilon\", \"zeta\", \"eta\", \"theta\"];",
+			"let list = [\n    \"alpha\",\n    \"beta\",\n    \"gamma\",\n    \"delta\",\n    \"epsilon\",\n" +
+				"    \"zeta\",\n    \"eta\",\n    \"theta\"\n];\n",
+		},
+		{"puts([1,\n2], 3)", "puts([1, 2], 3);\n"},
+		{"puts([\n1, 2], 3)", "puts(\n    [\n        1,\n        2\n    ],\n    3\n);\n"},
+		{"puts(1, [\n2, 3])", "puts(1, [\n    2,\n    3\n]);\n"},
+		{
+			"map(numbers, fn(x) { let doubled = x * 2; let tripled = x * 3; doubled + tripled });",
+			"map(numbers, fn(x) {\n    let doubled = x * 2;\n    let tripled = x * 3;\n    doubled + tripled\n});\n",
+		},
+		{
+			"let p = [{\"name\": \"Alice\", \"age\": 24}, {\"name\": \"Anna\", \"age\": 28}, {\"name\": \"Bob\"}];",
+			"let p = [\n    {\"name\": \"Alice\", \"age\": 24},\n    {\"name\": \"Anna\", \"age\": 28},\n" +
+				"    {\"name\": \"Bob\"}\n];\n",
+		},
+
+		// comments
+		{"// only a comment", "// only a comment\n"},
+		{"// header\n\nlet a = 1; // one   \n// two\nlet b = 2;\n// end",
+			"// header\n\nlet a = 1; // one\n// two\nlet b = 2;\n// end\n"},
+		{"let f = fn() {\n// inside\nx // value\n// last\n}",
+			"let f = fn() {\n    // inside\n    x // value\n    // last\n};\n"},
+		{"let f = fn() { x // value\n}", "let f = fn() {\n    x // value\n};\n"},
+		{"let h = {\"a\": 1, // first\n\"b\": 2}", "let h = {\n    \"a\": 1, // first\n    \"b\": 2\n};\n"},
+		{"puts(1, // one\n2)", "puts(\n    1, // one\n    2\n);\n"},
+		{"let x = 1 + // odd\n2;", "let x = 1 + 2;\n// odd\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("Source(%q) failed: %s", tt.input, err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("wrong output for %q.\nwant=%q\ngot= %q", tt.input, tt.expected, out)
+			continue
+		}
+
+		again, err := Source(out)
+		if err != nil {
+			t.Fatalf("formatting the output of %q failed: %s", tt.input, err)
+		}
+		if string(again) != string(out) {
+			t.Errorf("formatting %q is not idempotent.\nfirst= %q\nsecond=%q", tt.input, out, again)
+		}
+
+		if before, after := parse(t, tt.input), parse(t, string(out)); before != after {
+			t.Errorf("formatting %q changed the program.\nwant=%q\ngot= %q", tt.input, before, after)
+		}
+	}
+}
+
+func TestSourceParseErrors(t *testing.T) {
+	_, err := Source([]byte("let = 5;"))
+	if err == nil || !strings.HasPrefix(err.Error(), "parse errors:") {
+		t.Errorf("expected parse errors. got=%v", err)
+	}
+}
+
+func TestSourceUnclosedBlock(t *testing.T) {
+	tests := []string{
+		"if (true) { 1",
+		"let f = fn(x) { x",
+		"fn f(x) {\n    if (x) { 1 }",
+		"if (true) { 1 } else { 2",
+	}
+
+	for _, input := range tests {
+		_, err := Source([]byte(input))
+		if err == nil || !strings.HasPrefix(err.Error(), "parse errors:") {
+			t.Errorf("expected parse errors for %q. got=%v", input, err)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("cannot parse %q: %v", input, p.Errors())
+	}
+	return program.String()
+}
@@ -0,0 +1,45 @@
+package format
+
+import (
+	"testing"
+)
+
+var fuzzSeeds = []string{
+	"",
+	"let five = 5; let add = fn(x, y) { x + y; }; add(five, 10);",
+	"if (x < y) { x } else { y }; if (x) { return; }",
+	"// comment\nlet a = [1, // one\n2];\n\nputs({\"a\": 1, \"b\": [2]}) // trailing\n",
+	"let f = fn(a, b: int = a + 1, ...c: [int]) -> int {\n    f(...c, ...[a, b])\n};",
+	"fn f(a) { g(a) } fn g() { f() }; export fn e() {}",
+	`let [a, [b = 1], ...c] = x; let {d, "e f": {g: h = 2}, ...i} = y;`,
+	"match (x) {\n    0 => a, // zero\n    [e, ...f] if e => e,\n    _ => match (i) { j => j }\n}",
+	"const a = 1; import \"path/lib\" as l; test \"adds\" { assert_eq(1 + 1, 2); }",
+	"if (true) { 1",
+	"let f = fn(x) { x",
+}
+
+// FuzzSource checks that formatting never panics, and that what it
+// formats is a fixed point that parses to the same program.
+func FuzzSource(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		out, err := Source([]byte(input))
+		if err != nil {
+			return
+		}
+
+		again, err := Source(out)
+		if err != nil {
+			t.Fatalf("formatted source does not format.\ninput=%q\nformatted=%q\nerror=%s", input, out, err)
+		}
+		if string(again) != string(out) {
+			t.Fatalf("formatting is not idempotent.\ninput=%q\nfirst= %q\nsecond=%q", input, out, again)
+		}
+		if before, after := parse(t, input), parse(t, string(out)); before != after {
+			t.Fatalf("formatting changed the program.\ninput=%q\nwant=%q\ngot= %q", input, before, after)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
+
+var fuzzSeeds = []string{
+	"",
+	"let five = 5; let add = fn(x, y) { x + y; }; add(five, 10);",
+	`!-/*5; 5 < 10 > 5; if (5 < 10) { return true; } else { return false; } 10 == 10; 10 != 9;`,
+	`"foobar" "foo bar" "unterminated`,
+	`[1, 2]; {"foo": "bar"}; lib.member; import "lib" as l; export let x = 1;`,
+	"// a comment\nlet x: [int] = fn(a: string) -> bool { a }; // trailing",
+	"let m = macro(a) { quote(unquote(a)) }; test \"name\" { assert(true) }",
+	"a && b || !c & d | e",
+	"fn(a, b = 1, ...c) { f(...c, a.b) }; .. . ....",
+	"héllo ☃ \x00 \xff @ # $",
+}
+
+// FuzzNextToken checks that the lexer always reaches the end of its input,
+// moving forward through it.
+func FuzzNextToken(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := New(input)
+		prev := token.Token{Line: 1, Column: 0}
+		// every token but EOF takes at least one byte
+		for i := 0; i <= len(input)+1; i++ {
+			tok := l.NextToken()
+			if tok.Type == token.EOF {
+				return
+			}
+			if tok.Line < prev.Line || tok.Line == prev.Line && tok.Column <= prev.Column {
+				t.Fatalf("token %q at %d:%d does not follow the one at %d:%d",
+					tok.Literal, tok.Line, tok.Column, prev.Line, prev.Column)
+			}
+			prev = tok
+		}
+		t.Fatalf("no EOF after %d tokens", len(input)+2)
+	})
+}
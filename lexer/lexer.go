@@ -1,6 +1,10 @@
 package lexer
 
-import "github.com/OlyaIvanovs/interpreter_in_go/token"
+import (
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
 
 // import "fmt"
 
@@ -9,16 +13,25 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line         int // line of ch
+	column       int // column of ch
+	comments     []token.Token
 }
 
 func New(input string) *Lexer {
-    l := &Lexer{input: input}
+    l := &Lexer{input: input, line: 1}
     l.readChar()
     return l
 }
 
 
 func (l *Lexer) readChar() {
+    if l.ch == '\n' {
+        l.line += 1
+        l.column = 0
+    }
+    l.column += 1
+
     if l.readPosition >= len(l.input) {
         l.ch = 0
     } else {
@@ -40,6 +53,7 @@ func (l *Lexer) NextToken() token.Token {
     var tok token.Token
     
     l.skipWhiteSpace()
+    line, column := l.line, l.column
     
     switch l.ch {
     case '"': 
@@ -49,6 +63,9 @@ func (l *Lexer) NextToken() token.Token {
     	if l.peekChar() == '=' {
     		tok = token.Token{Type: token.EQ, Literal: "=="}
     		l.readChar();
+    	} else if l.peekChar() == '>' {
+    		tok = token.Token{Type: token.FAT_ARROW, Literal: "=>"}
+    		l.readChar()
     	} else {
     		tok = newToken(token.ASSIGN, l.ch)
         }
@@ -62,10 +79,23 @@ func (l *Lexer) NextToken() token.Token {
         tok = newToken(token.RPAREN, l.ch)
     case ',':
         tok = newToken(token.COMMA, l.ch)
+    case '.':
+        if strings.HasPrefix(l.input[l.position:], "...") {
+            tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+            l.readChar()
+            l.readChar()
+        } else {
+            tok = newToken(token.DOT, l.ch)
+        }
     case '+':
         tok = newToken(token.PLUS, l.ch)
     case '-':
-        tok = newToken(token.MINUS, l.ch)
+        if l.peekChar() == '>' {
+            tok = token.Token{Type: token.ARROW, Literal: "->"}
+            l.readChar()
+        } else {
+            tok = newToken(token.MINUS, l.ch)
+        }
     case '!':
     	if l.peekChar() == '=' {
     		tok = token.Token{Type: token.NOT_EQ, Literal: "!="}
@@ -73,6 +103,20 @@ func (l *Lexer) NextToken() token.Token {
     	} else {
 			tok = newToken(token.BANG, l.ch)    	
     	}
+    case '&':
+        if l.peekChar() == '&' {
+            tok = token.Token{Type: token.AND, Literal: "&&"}
+            l.readChar()
+        } else {
+            tok = newToken(token.ILLEGAL, l.ch)
+        }
+    case '|':
+        if l.peekChar() == '|' {
+            tok = token.Token{Type: token.OR, Literal: "||"}
+            l.readChar()
+        } else {
+            tok = newToken(token.ILLEGAL, l.ch)
+        }
     case '*':
         tok = newToken(token.ASTERISK, l.ch)
     case '<':
@@ -80,6 +124,10 @@ func (l *Lexer) NextToken() token.Token {
     case '>':
         tok = newToken(token.GT, l.ch)
     case '/':
+        if l.peekChar() == '/' {
+            l.readComment(line, column)
+            return l.NextToken()
+        }
         tok = newToken(token.SLASH, l.ch)
     case '{':
         tok = newToken(token.LBRACE, l.ch)
@@ -96,10 +144,12 @@ func (l *Lexer) NextToken() token.Token {
         if isLetter(l.ch) {
             tok.Literal = l.readIdentifier()
             tok.Type = token.LookupIdent(tok.Literal)
+            tok.Line, tok.Column = line, column
             return tok
         } else if isDigit(l.ch) {
         	tok.Literal = l.readNumber()
         	tok.Type = token.INT;
+        	tok.Line, tok.Column = line, column
         	return tok;
         } else {
             tok = newToken(token.ILLEGAL, l.ch)
@@ -107,9 +157,26 @@ func (l *Lexer) NextToken() token.Token {
     }   
     
     l.readChar()
+    tok.Line, tok.Column = line, column
     return tok
 }
 
+// Comments returns the comments skipped so far, in source order. Comments
+// run from // to the end of the line.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+func (l *Lexer) readComment(line, column int) {
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	
+	literal := l.input[position:l.position]
+	l.comments = append(l.comments, token.Token{Type: token.COMMENT, Literal: literal, Line: line, Column: column})
+}
+
 func (l *Lexer) readString() string {
 	position := l.position + 1
 	for {
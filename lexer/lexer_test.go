@@ -3,6 +3,7 @@ package lexer
 import (
 	"testing"
 	"fmt"
+	"strings"
 
 	"github.com/OlyaIvanovs/interpreter_in_go/token"
 )
@@ -64,6 +65,13 @@ if (5 < 10) {
 "foo bar"
 [1, 2];
 {"1": "2"};
+import "lib" as l;
+export let x = l.y;
+fn(a: int) -> bool;
+a && b || c & d | e;
+fn(...r) { f(...r, a.b) };
+match (x) { _ => 1 }
+const k = 1;
 `
 
 
@@ -159,6 +167,69 @@ if (5 < 10) {
          {token.STRING, "2"},
          {token.RBRACE, "}"},
          {token.SEMICOLON, ";"},
+         {token.IMPORT, "import"},
+         {token.STRING, "lib"},
+         {token.IDENT, "as"},
+         {token.IDENT, "l"},
+         {token.SEMICOLON, ";"},
+         {token.EXPORT, "export"},
+         {token.LET, "let"},
+         {token.IDENT, "x"},
+         {token.ASSIGN, "="},
+         {token.IDENT, "l"},
+         {token.DOT, "."},
+         {token.IDENT, "y"},
+         {token.SEMICOLON, ";"},
+         {token.FUNCTION, "fn"},
+         {token.LPAREN, "("},
+         {token.IDENT, "a"},
+         {token.COLON, ":"},
+         {token.IDENT, "int"},
+         {token.RPAREN, ")"},
+         {token.ARROW, "->"},
+         {token.IDENT, "bool"},
+         {token.SEMICOLON, ";"},
+         {token.IDENT, "a"},
+         {token.AND, "&&"},
+         {token.IDENT, "b"},
+         {token.OR, "||"},
+         {token.IDENT, "c"},
+         {token.ILLEGAL, "&"},
+         {token.IDENT, "d"},
+         {token.ILLEGAL, "|"},
+         {token.IDENT, "e"},
+         {token.SEMICOLON, ";"},
+         {token.FUNCTION, "fn"},
+         {token.LPAREN, "("},
+         {token.ELLIPSIS, "..."},
+         {token.IDENT, "r"},
+         {token.RPAREN, ")"},
+         {token.LBRACE, "{"},
+         {token.IDENT, "f"},
+         {token.LPAREN, "("},
+         {token.ELLIPSIS, "..."},
+         {token.IDENT, "r"},
+         {token.COMMA, ","},
+         {token.IDENT, "a"},
+         {token.DOT, "."},
+         {token.IDENT, "b"},
+         {token.RPAREN, ")"},
+         {token.RBRACE, "}"},
+         {token.SEMICOLON, ";"},
+         {token.MATCH, "match"},
+         {token.LPAREN, "("},
+         {token.IDENT, "x"},
+         {token.RPAREN, ")"},
+         {token.LBRACE, "{"},
+         {token.IDENT, "_"},
+         {token.FAT_ARROW, "=>"},
+         {token.INT, "1"},
+         {token.RBRACE, "}"},
+         {token.CONST, "const"},
+         {token.IDENT, "k"},
+         {token.ASSIGN, "="},
+         {token.INT, "1"},
+         {token.SEMICOLON, ";"},
          {token.EOF, ""},
     }
     
@@ -179,3 +250,73 @@ if (5 < 10) {
 	}
 
 }
+
+func TestNextTokenPositions(t *testing.T) {
+	input := `let x = "a b";
+  x == 10
+`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"a b", 1, 9},
+		{";", 1, 14},
+		{"x", 2, 3},
+		{"==", 2, 5},
+		{"10", 2, 8},
+		{"", 3, 1},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+let x = 10 / 2; // half
+// last`
+
+	l := New(input)
+
+	var literals []string
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		literals = append(literals, tok.Literal)
+	}
+
+	expected := "let x = 10 / 2 ;"
+	if got := strings.Join(literals, " "); got != expected {
+		t.Fatalf("wrong tokens. expected=%q, got=%q", expected, got)
+	}
+
+	comments := l.Comments()
+	expectedComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// header", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// half", Line: 2, Column: 17},
+		{Type: token.COMMENT, Literal: "// last", Line: 3, Column: 1},
+	}
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expectedComments), len(comments))
+	}
+	for i, c := range expectedComments {
+		if comments[i] != c {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, c, comments[i])
+		}
+	}
+}
@@ -0,0 +1,24 @@
+module github.com/OlyaIvanovs/interpreter_in_go/lint
+
+go 1.19
+
+replace github.com/OlyaIvanovs/interpreter_in_go/ast => ../ast
+
+replace github.com/OlyaIvanovs/interpreter_in_go/token => ../token
+
+replace github.com/OlyaIvanovs/interpreter_in_go/lexer => ../lexer
+
+replace github.com/OlyaIvanovs/interpreter_in_go/parser => ../parser
+
+replace github.com/OlyaIvanovs/interpreter_in_go/evaluator => ../evaluator
+
+replace github.com/OlyaIvanovs/interpreter_in_go/object => ../object
+
+require (
+	github.com/OlyaIvanovs/interpreter_in_go/ast v0.0.0-00010101000000-000000000000
+	github.com/OlyaIvanovs/interpreter_in_go/evaluator v0.0.0-00010101000000-000000000000
+	github.com/OlyaIvanovs/interpreter_in_go/lexer v0.0.0-00010101000000-000000000000
+	github.com/OlyaIvanovs/interpreter_in_go/object v0.0.0-00010101000000-000000000000
+	github.com/OlyaIvanovs/interpreter_in_go/parser v0.0.0-00010101000000-000000000000
+	github.com/OlyaIvanovs/interpreter_in_go/token v0.0.0-00010101000000-000000000000
+)
@@ -0,0 +1,635 @@
+// Package lint reports suspicious constructs in Monkey programs.
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
+
+// Names of the checks, as reported in Finding.Check and accepted by
+// ignore comments.
+const (
+	Undefined         = "undefined"
+	Unused            = "unused"
+	Shadow            = "shadow"
+	Unreachable       = "unreachable"
+	Arity             = "arity"
+	DuplicateKey      = "duplicate-key"
+	ConstantCondition = "constant-condition"
+	Redeclare         = "redeclare"
+)
+
+// IgnoreDirective in a comment suppresses the findings on its line, or on
+// the next line if the comment is on a line of its own. It may be followed
+// by the names of the checks to suppress, by default all of them:
+//
+//	let unusedOnPurpose = 1; // vet:ignore unused
+const IgnoreDirective = "vet:ignore"
+
+// A Finding is a problem found in a program.
+type Finding struct {
+	Line, Column int
+	Check        string
+	Message      string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", f.Line, f.Column, f.Message, f.Check)
+}
+
+// Source parses src and checks it, leaving out findings suppressed by
+// ignore comments.
+func Source(src []byte) ([]Finding, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	ignored := ignoredLines(string(src), l.Comments())
+	findings := []Finding{}
+	for _, f := range Check(program) {
+		if checks, ok := ignored[f.Line]; ok && (len(checks) == 0 || checks[f.Check]) {
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// ignoredLines maps each line with an ignore comment to the checks it
+// suppresses, an empty set meaning all of them.
+func ignoredLines(src string, comments []token.Token) map[int]map[string]bool {
+	lines := strings.Split(src, "\n")
+	ignored := map[int]map[string]bool{}
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Literal, "//"))
+		if !strings.HasPrefix(text, IgnoreDirective) {
+			continue
+		}
+
+		checks := map[string]bool{}
+		for _, name := range strings.Fields(strings.TrimPrefix(text, IgnoreDirective)) {
+			checks[strings.TrimSuffix(name, ",")] = true
+		}
+
+		line := c.Line
+		if strings.TrimSpace(lines[c.Line-1][:c.Column-1]) == "" {
+			line++
+		}
+		ignored[line] = checks
+	}
+	return ignored
+}
+
+// Check runs every check over program and returns the findings ordered by
+// position.
+func Check(program *ast.Program) []Finding {
+	c := &checker{}
+	c.statements(program.Statements, newScope(nil))
+	for len(c.pending) > 0 {
+		fn := c.pending[0]
+		c.pending = c.pending[1:]
+		c.function(fn)
+	}
+
+	for _, b := range c.bindings {
+		if b.used || strings.HasPrefix(b.name, "_") {
+			continue
+		}
+		switch b.kind {
+		case letBinding:
+			c.report(b.tok, Unused, "%s is declared but never used", b.name)
+		case paramBinding:
+			c.report(b.tok, Unused, "parameter %s is never used", b.name)
+		case importBinding:
+			c.report(b.tok, Unused, "module %s is imported but never used", b.name)
+		}
+	}
+
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		a, b := c.findings[i], c.findings[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.findings
+}
+
+type bindingKind int
+
+const (
+	letBinding bindingKind = iota
+	paramBinding
+	importBinding
+	exportBinding
+)
+
+type binding struct {
+	name  string
+	kind  bindingKind
+	tok   token.Token
+	value    ast.Expression // the value of a let binding
+	used     bool
+	constant bool // bound by const, so it cannot be bound again in its scope
+	declared bool // a declared function, which no const can bind in its scope
+}
+
+// A scope holds the names bound by one environment: the program or a
+// function call. Blocks don't have their own scope, just like at run time.
+type scope struct {
+	outer *scope
+	names map[string]*binding
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, names: map[string]*binding{}}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.names[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// A pendingFunction is a function body checked once the scopes around it
+// are complete, since it runs only when called and so can refer to names
+// bound after it.
+type pendingFunction struct {
+	params []ast.Pattern
+	rest   *ast.Identifier
+	body   *ast.BlockStatement
+	scope  *scope
+	self   *ast.FunctionLiteral // a named function, which sees its own name
+}
+
+type checker struct {
+	findings []Finding
+	bindings []*binding
+	pending  []pendingFunction
+}
+
+func (c *checker) report(tok token.Token, check, format string, a ...interface{}) {
+	c.findings = append(c.findings, Finding{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Check:   check,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *checker) declare(s *scope, ident *ast.Identifier, kind bindingKind, value ast.Expression) {
+	if b, ok := s.names[ident.Value]; ok && b.constant {
+		c.report(ident.Token, Redeclare, "cannot redeclare constant %s", ident.Value)
+	}
+	if _, ok := s.outer.lookup(ident.Value); ok {
+		c.report(ident.Token, Shadow, "%s shadows a name from an enclosing scope", ident.Value)
+	} else if isBuiltin(ident.Value) {
+		c.report(ident.Token, Shadow, "%s shadows the builtin function", ident.Value)
+	}
+
+	b := &binding{name: ident.Value, kind: kind, tok: ident.Token, value: value, used: kind == exportBinding}
+	s.names[ident.Value] = b
+	c.bindings = append(c.bindings, b)
+}
+
+// bind declares the names pattern p binds. Defaults are checked before
+// the names next to them are declared, as they can use the names before
+// them but not their own.
+func (c *checker) bind(s *scope, p ast.Pattern, kind bindingKind, value ast.Expression) {
+	c.expression(ast.DefaultOf(p), s)
+	switch p := p.(type) {
+	case *ast.Identifier:
+		c.declare(s, p, kind, value)
+	case *ast.ArrayPattern:
+		for _, el := range p.Elements {
+			c.bind(s, el, kind, nil)
+		}
+		if p.Rest != nil {
+			c.declare(s, p.Rest, kind, nil)
+		}
+	case *ast.HashPattern:
+		for _, pair := range p.Pairs {
+			c.bind(s, pair.Value, kind, nil)
+		}
+		if p.Rest != nil {
+			c.declare(s, p.Rest, kind, nil)
+		}
+	}
+}
+
+// let declares the names stmt binds, as constants if it is a const
+// statement.
+func (c *checker) let(s *scope, stmt *ast.LetStatement, kind bindingKind) {
+	if stmt.IsConst() {
+		for _, ident := range ast.PatternNames(stmt.Name) {
+			if b, ok := s.names[ident.Value]; ok && b.declared {
+				c.report(ident.Token, Redeclare, "cannot redeclare constant %s", ident.Value)
+			}
+		}
+	}
+	c.bind(s, stmt.Name, kind, stmt.Value)
+	if stmt.IsConst() {
+		for _, ident := range ast.PatternNames(stmt.Name) {
+			s.names[ident.Value].constant = true
+		}
+	}
+}
+
+func isBuiltin(name string) bool {
+	return evaluator.IsBuiltin(name) || name == "quote" || name == "unquote"
+}
+
+func (c *checker) function(fn pendingFunction) {
+	s := newScope(fn.scope)
+	if fn.self != nil && fn.self.Name != nil {
+		// calls to itself don't count as uses of the function
+		name := fn.self.Name
+		s.names[name.Value] = &binding{name: name.Value, kind: letBinding, tok: name.Token, value: fn.self, used: true}
+	}
+	for _, param := range fn.params {
+		c.bind(s, param, paramBinding, nil)
+	}
+	if fn.rest != nil {
+		c.declare(s, fn.rest, paramBinding, nil)
+	}
+	if fn.body != nil {
+		c.statements(fn.body.Statements, s)
+	}
+}
+
+func (c *checker) statements(stmts []ast.Statement, s *scope) {
+	// declared functions are bound before the statements around them run
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			if stmt.IsDeclaration() {
+				c.bind(s, stmt.Name, letBinding, stmt.Value)
+				s.names[stmt.Name.(*ast.Identifier).Value].declared = true
+			}
+		case *ast.ExportStatement:
+			if stmt.Statement.IsDeclaration() {
+				c.bind(s, stmt.Statement.Name, exportBinding, stmt.Statement.Value)
+				s.names[stmt.Statement.Name.(*ast.Identifier).Value].declared = true
+			}
+		}
+	}
+
+	terminated, reported := false, false
+	for _, stmt := range stmts {
+		if terminated && !reported && !isDeclaration(stmt) {
+			tok, _ := ast.TokenOf(stmt)
+			c.report(tok, Unreachable, "unreachable code")
+			reported = true
+		}
+
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			c.expression(stmt.Value, s)
+			if !stmt.IsDeclaration() {
+				c.let(s, stmt, letBinding)
+			}
+		case *ast.ExportStatement:
+			c.expression(stmt.Statement.Value, s)
+			if !stmt.Statement.IsDeclaration() {
+				c.let(s, stmt.Statement, exportBinding)
+			}
+		case *ast.ImportStatement:
+			name := stmt.Alias
+			if name == nil {
+				base := filepath.Base(stmt.Path.Value)
+				name = &ast.Identifier{Token: stmt.Path.Token, Value: strings.TrimSuffix(base, filepath.Ext(base))}
+			}
+			c.declare(s, name, importBinding, nil)
+		case *ast.TestStatement:
+			// tests run once the whole file has, like a function
+			c.pending = append(c.pending, pendingFunction{nil, nil, stmt.Body, s, nil})
+		case *ast.ReturnStatement:
+			c.expression(stmt.ReturnValue, s)
+		case *ast.ExpressionStatement:
+			c.expression(stmt.Expression, s)
+		}
+
+		if terminates(stmt) {
+			terminated = true
+		}
+	}
+}
+
+// isDeclaration reports whether stmt declares a function, which is bound
+// however far the statements before it get.
+func isDeclaration(stmt ast.Statement) bool {
+	if export, ok := stmt.(*ast.ExportStatement); ok {
+		return export.Statement.IsDeclaration()
+	}
+	let, ok := stmt.(*ast.LetStatement)
+	return ok && let.IsDeclaration()
+}
+
+// terminates reports whether running stmt always returns from the function.
+func terminates(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		ie, ok := stmt.Expression.(*ast.IfExpression)
+		return ok && ie.Alternative != nil && blockTerminates(ie.Consequence) && blockTerminates(ie.Alternative)
+	}
+	return false
+}
+
+func blockTerminates(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		if terminates(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) expression(exp ast.Expression, s *scope) {
+	if exp == nil {
+		return
+	}
+
+	ast.Inspect(exp, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			c.use(n, s)
+			return false
+
+		case *ast.FunctionLiteral:
+			c.pending = append(c.pending, pendingFunction{n.Parameters, n.Rest, n.Body, s, n})
+			return false
+
+		case *ast.MacroLiteral:
+			c.pending = append(c.pending, pendingFunction{macroParameters(n), nil, n.Body, s, nil})
+			return false
+
+		case *ast.MemberExpression:
+			c.expression(n.Object, s)
+			return false
+
+		case *ast.IfExpression:
+			if isConstant(n.Condition) {
+				tok, _ := ast.TokenOf(n.Condition)
+				if value, ok := constantBoolean(n.Condition); ok {
+					c.report(tok, ConstantCondition, "condition is always %t", value)
+				} else {
+					c.report(tok, ConstantCondition, "condition is constant")
+				}
+			}
+			c.expression(n.Condition, s)
+			c.statements(n.Consequence.Statements, s)
+			if n.Alternative != nil {
+				c.statements(n.Alternative.Statements, s)
+			}
+			return false
+
+		case *ast.MatchExpression:
+			c.expression(n.Value, s)
+			c.arms(n, s)
+			return false
+
+		case *ast.HashLiteral:
+			c.duplicateKeys(n)
+			return true
+
+		case *ast.CallExpression:
+			c.call(n, s)
+			return false
+		}
+		return true
+	})
+}
+
+// arms checks the arms of a match, each of which binds its names in a
+// scope of its own. The arms after one that matches every value are
+// unreachable.
+func (c *checker) arms(me *ast.MatchExpression, s *scope) {
+	reported := false
+	for i, arm := range me.Arms {
+		armScope := newScope(s)
+		c.bind(armScope, arm.Pattern, letBinding, nil)
+		c.expression(arm.Guard, armScope)
+		c.expression(arm.Body, armScope)
+
+		if _, ok := arm.Pattern.(*ast.Identifier); ok && arm.Guard == nil && i+1 < len(me.Arms) && !reported {
+			tok, _ := ast.TokenOf(me.Arms[i+1])
+			c.report(tok, Unreachable, "unreachable code")
+			reported = true
+		}
+	}
+}
+
+func (c *checker) use(ident *ast.Identifier, s *scope) *binding {
+	b, ok := s.lookup(ident.Value)
+	if !ok {
+		if !isBuiltin(ident.Value) {
+			c.report(ident.Token, Undefined, "undefined: %s", ident.Value)
+		}
+		return nil
+	}
+	b.used = true
+	return b
+}
+
+func (c *checker) call(call *ast.CallExpression, s *scope) {
+	var fn ast.Expression = call.Function
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		if ident.Value == "quote" && !isShadowed("quote", s) {
+			c.quoted(call.Arguments, s)
+			return
+		}
+		b := c.use(ident, s)
+		fn = nil
+		if b != nil {
+			fn = b.value
+		}
+	} else {
+		c.expression(call.Function, s)
+	}
+
+	switch fn := fn.(type) {
+	case *ast.FunctionLiteral:
+		c.arity(call, fn.Parameters, fn.Rest)
+	case *ast.MacroLiteral:
+		// Macro arguments are code, not values, so they aren't checked.
+		c.arity(call, macroParameters(fn), nil)
+		return
+	default:
+		for _, arg := range call.Arguments {
+			c.expression(arg, s)
+		}
+		return
+	}
+
+	for _, arg := range call.Arguments {
+		c.expression(arg, s)
+	}
+}
+
+func (c *checker) arity(call *ast.CallExpression, params []ast.Pattern, rest *ast.Identifier) {
+	required := 0
+	for _, param := range params {
+		if ast.DefaultOf(param) == nil {
+			required++
+		}
+	}
+	// a spread array can hold any number of arguments, so only the others
+	// are counted
+	n, spread := 0, false
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.SpreadExpression); ok {
+			spread = true
+		} else {
+			n++
+		}
+	}
+	tooFew := !spread && n < required
+	tooMany := rest == nil && n > len(params)
+	if !tooFew && !tooMany {
+		return
+	}
+
+	want := strconv.Itoa(required)
+	if rest != nil {
+		want = "at least " + want
+	} else if required < len(params) {
+		want += " to " + strconv.Itoa(len(params))
+	}
+	tok, _ := ast.TokenOf(call)
+	name := "function literal"
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		tok, name = ident.Token, ident.Value
+	}
+	c.report(tok, Arity, "wrong number of arguments in call to %s: got %d, want %s",
+		name, n, want)
+}
+
+// macroParameters returns the parameters of a macro, which are all names,
+// as patterns like those of a function.
+func macroParameters(macro *ast.MacroLiteral) []ast.Pattern {
+	params := []ast.Pattern{}
+	for _, param := range macro.Parameters {
+		params = append(params, param)
+	}
+	return params
+}
+
+func isShadowed(name string, s *scope) bool {
+	_, ok := s.lookup(name)
+	return ok
+}
+
+// quoted checks the arguments of quote, where only the arguments of
+// unquote calls are evaluated.
+func (c *checker) quoted(args []ast.Expression, s *scope) {
+	for _, arg := range args {
+		ast.Inspect(arg, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+			if ident, ok := call.Function.(*ast.Identifier); ok && ident.Value == "unquote" {
+				for _, arg := range call.Arguments {
+					c.expression(arg, s)
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func (c *checker) duplicateKeys(hash *ast.HashLiteral) {
+	seen := map[string]bool{}
+	for _, key := range hash.Keys {
+		var id string
+		switch key := key.(type) {
+		case *ast.StringLiteral:
+			id = "string " + key.Value
+		case *ast.IntegerLiteral:
+			id = fmt.Sprint("integer ", key.Value)
+		case *ast.Boolean:
+			id = fmt.Sprint("boolean ", key.Value)
+		default:
+			continue
+		}
+
+		if seen[id] {
+			tok, _ := ast.TokenOf(key)
+			c.report(tok, DuplicateKey, "duplicate key %s in hash literal", key.String())
+		}
+		seen[id] = true
+	}
+}
+
+// isConstant reports whether exp has the same value every time it runs.
+func isConstant(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.FunctionLiteral:
+		return true
+	case *ast.ArrayLiteral:
+		for _, el := range exp.Elements {
+			if !isConstant(el) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		for _, key := range exp.Keys {
+			if !isConstant(key) || !isConstant(exp.Pairs[key]) {
+				return false
+			}
+		}
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(exp.Right)
+	case *ast.InfixExpression:
+		return isConstant(exp.Left) && isConstant(exp.Right)
+	}
+	return false
+}
+
+// constantBoolean returns the value of exp if it is a boolean literal,
+// possibly negated or combined with && and ||.
+func constantBoolean(exp ast.Expression) (bool, bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.PrefixExpression:
+		if exp.Operator == "!" {
+			value, ok := constantBoolean(exp.Right)
+			return !value, ok
+		}
+	case *ast.InfixExpression:
+		if exp.Operator != "&&" && exp.Operator != "||" {
+			break
+		}
+		left, ok := constantBoolean(exp.Left)
+		if !ok {
+			return false, false
+		}
+		right, ok := constantBoolean(exp.Right)
+		if !ok {
+			return false, false
+		}
+		if exp.Operator == "&&" {
+			return left && right, true
+		}
+		return left || right, true
+	}
+	return false, false
+}
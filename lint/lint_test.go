@@ -0,0 +1,184 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; puts(x);", nil},
+		{"puts(y);", []string{"1:6: undefined: y (undefined)"}},
+		{"puts(x); let x = 1; puts(x);", []string{"1:6: undefined: x (undefined)"}},
+		{"let x = x + 1; puts(x);", []string{"1:9: undefined: x (undefined)"}},
+		{"let x = 1;", []string{"1:5: x is declared but never used (unused)"}},
+		{"let _x = 1; export let y = 2;", nil},
+		{`import "lib/strings";`, []string{"1:8: module strings is imported but never used (unused)"}},
+		{`import "lib" as l; puts(l.x);`, nil},
+
+		// closures see names bound after them, including their own
+		{"let f = fn() { g() }; let g = fn() { f() }; f();", nil},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(3);", nil},
+		{"let f = fn(a, b) { a }; f(1, 2);", []string{"1:15: parameter b is never used (unused)"}},
+		{"let f = fn() { if (x > 1) { let y = 2; } y }; let x = 1; f();", nil},
+		{`test "t" { assert_eq(f(), 1); let y = 2; }; let f = fn() { 1 };`,
+			[]string{"1:35: y is declared but never used (unused)"}},
+
+		// shadowing
+		{"let x = 1; let f = fn(x) { x }; f(x);",
+			[]string{"1:23: x shadows a name from an enclosing scope (shadow)"}},
+		{"let f = fn() { let f = 1; f }; f();",
+			[]string{"1:20: f shadows a name from an enclosing scope (shadow)"}},
+		{"let len = fn(a) { 0 }; len([]);", []string{
+			"1:5: len shadows the builtin function (shadow)",
+			"1:14: parameter a is never used (unused)",
+		}},
+
+		// unreachable code
+		{"let f = fn() { return 1; puts(2); puts(3); }; f();", []string{"1:26: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1; } else { return 2; } x }; f(1);",
+			[]string{"1:57: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1; } x }; f(1);", nil},
+
+		// arity
+		{"let add = fn(a, b) { a + b }; add(1);",
+			[]string{"1:31: wrong number of arguments in call to add: got 1, want 2 (arity)"}},
+		{"fn(a) { a }(1, 2);", []string{"1:12: wrong number of arguments in call to function literal: got 2, want 1 (arity)"}},
+		{"let add = fn(a, b) { a + b }; let g = add; g(1);", nil},
+		{"let f = fn(a, b = a) { b }; f(1); f(1, 2);", nil},
+		{"let f = fn(a, b = 1) { a + b }; f(); f(1, 2, 3);", []string{
+			"1:33: wrong number of arguments in call to f: got 0, want 1 to 2 (arity)",
+			"1:38: wrong number of arguments in call to f: got 3, want 1 to 2 (arity)",
+		}},
+		{"let f = fn(a, ...r) { a + r }; f(); f(1, 2, 3);",
+			[]string{"1:32: wrong number of arguments in call to f: got 0, want at least 1 (arity)"}},
+		{"let f = fn(a, b) { a + b }; let x = [1, 2]; f(...x); f(1, ...x); f(1, 2, 3, ...x);",
+			[]string{"1:66: wrong number of arguments in call to f: got 3, want 2 (arity)"}},
+
+		// function declarations
+		{"main(); fn main() { helper(1) } fn helper(x) { x }", nil},
+		{"fn f() { return g(); fn g() { 1 } }; f();", nil},
+		{"fn f() { return 1; fn g() { 1 } puts(2); }; f();", []string{
+			"1:23: g is declared but never used (unused)",
+			"1:33: unreachable code (unreachable)",
+		}},
+		{"fn loop(n) { loop(n - 1) }", []string{"1:4: loop is declared but never used (unused)"}},
+		{"fn add(a, b) { a + b } add(1);", []string{"1:24: wrong number of arguments in call to add: got 1, want 2 (arity)"}},
+		{"let f = fn go(n) { go(n, 1) }; f(1);", []string{"1:20: wrong number of arguments in call to go: got 2, want 1 (arity)"}},
+
+		// parameters
+		{"let f = fn(a = b) { a }; f();", []string{"1:16: undefined: b (undefined)"}},
+		{"let f = fn(...others) { 1 }; f();", []string{"1:15: parameter others is never used (unused)"}},
+
+		// patterns
+		{"let [a, b, ...c] = [1]; puts(a, c);", []string{"1:9: b is declared but never used (unused)"}},
+		{`let {name, age: years = name} = {}; puts(years);`, nil},
+		{`let {a = b, b} = {}; puts(a, b);`, []string{"1:10: undefined: b (undefined)"}},
+		{"let x = 1; let f = fn([x], {y} = {}) { x }; f([x]);", []string{
+			"1:24: x shadows a name from an enclosing scope (shadow)",
+			"1:29: parameter y is never used (unused)",
+		}},
+		{"let f = fn([a], b = 1) { a + b }; f(); f([1]);",
+			[]string{"1:35: wrong number of arguments in call to f: got 0, want 1 to 2 (arity)"}},
+
+		// match
+		{"let v = 1; puts(match (v) { [a, ...r] if a => r, {k} => k, 0 => 1, _ => 2 });", nil},
+		{"let v = 1; puts(match (v) { [a] => b, n => 1 });", []string{
+			"1:30: a is declared but never used (unused)",
+			"1:36: undefined: b (undefined)",
+			"1:39: n is declared but never used (unused)",
+		}},
+		{"let v = 1; puts(match (v) { n => n, 0 => n, _ => 0 });", []string{
+			"1:37: unreachable code (unreachable)",
+			"1:42: undefined: n (undefined)",
+		}},
+		{"let v = 1; puts(match (v) { v if v => v, _ => 0 });", []string{"1:29: v shadows a name from an enclosing scope (shadow)"}},
+
+		// constants
+		{"const x = 1; puts(x); let x = 2; puts(x);", []string{"1:27: cannot redeclare constant x (redeclare)"}},
+		{"const [a, {b}] = [1, {}]; puts(b); const b = a; puts(b);", []string{"1:42: cannot redeclare constant b (redeclare)"}},
+		{"const f = 1; if (f) { fn f() {} }", []string{
+			"1:26: cannot redeclare constant f (redeclare)",
+			"1:26: f is declared but never used (unused)",
+		}},
+		{"const X = 1; fn X() { 1 } puts(X);", []string{
+			"1:7: cannot redeclare constant X (redeclare)",
+			"1:17: X is declared but never used (unused)",
+		}},
+		{"fn X() { 1 } export const X = 1; puts(X);", []string{
+			"1:4: X is declared but never used (unused)",
+			"1:27: cannot redeclare constant X (redeclare)",
+		}},
+		{`const m = 1; import "m"; puts(m);`, []string{
+			"1:7: m is declared but never used (unused)",
+			"1:21: cannot redeclare constant m (redeclare)",
+		}},
+		{"let x = 1; const x = 2; let f = fn() { let x = 3; x }; puts(x, f);", []string{
+			"1:5: x is declared but never used (unused)",
+			"1:44: x shadows a name from an enclosing scope (shadow)",
+		}},
+		{"export const answer = 42;", nil},
+
+		// macros and quote
+		{"let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) }; unless(x > y, puts(z));", nil},
+		{"let m = macro(a) { quote(unquote(a) + b) }; m(1);", nil},
+		{"quote(unquote(y));", []string{"1:15: undefined: y (undefined)"}},
+
+		// duplicate keys
+		{`puts({"a": 1, "b": 2, "a": 3, 1: 1, true: 2, 1: 3});`, []string{
+			`1:23: duplicate key "a" in hash literal (duplicate-key)`,
+			"1:46: duplicate key 1 in hash literal (duplicate-key)",
+		}},
+
+		// constant conditions
+		{"if (true) { 1 }", []string{"1:5: condition is always true (constant-condition)"}},
+		{"if (!true) { 1 }", []string{"1:5: condition is always false (constant-condition)"}},
+		{"if (1 < 2) { 1 }", []string{"1:7: condition is constant (constant-condition)"}},
+		{"if (true && !false) { 1 }", []string{"1:10: condition is always true (constant-condition)"}},
+		{"if (false || 1 > 2) { 1 }", []string{"1:11: condition is constant (constant-condition)"}},
+		{"let x = 1; if (x < 2) { 1 }", nil},
+	}
+
+	for _, tt := range tests {
+		findings, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("Source(%q) failed: %s", tt.input, err)
+		}
+
+		var got []string
+		for _, f := range findings {
+			got = append(got, f.String())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong findings for %q.\nwant=%q\ngot= %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestIgnoreComments(t *testing.T) {
+	input := `let a = 1; // vet:ignore
+let b = 2; // vet:ignore shadow
+// vet:ignore unused, undefined
+let c = d;
+let e = 3;`
+
+	findings, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source failed: %s", err)
+	}
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	expected := []string{
+		"2:5: b is declared but never used (unused)",
+		"5:5: e is declared but never used (unused)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong findings.\nwant=%q\ngot= %q", expected, got)
+	}
+}
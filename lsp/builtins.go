@@ -0,0 +1,48 @@
+package lsp
+
+// builtinDoc documents a builtin function for hovers and completions.
+type builtinDoc struct {
+	signature string
+	doc       string
+}
+
+var builtinDocs = map[string]builtinDoc{
+	"len":            {"len(value) -> int", "Returns the number of characters in a string, elements in an array or pairs in a hash."},
+	"first":          {"first(array)", "Returns the first element of an array, or null if it is empty."},
+	"last":           {"last(array)", "Returns the last element of an array, or null if it is empty."},
+	"rest":           {"rest(array) -> array", "Returns a new array with all but the first element, or null if the array is empty."},
+	"push":           {"push(array, value) -> array", "Returns a new array with value appended."},
+	"split":          {"split(s: string, sep: string) -> [string]", "Splits s around each sep, or around runs of whitespace if sep is left out."},
+	"join":           {"join(parts: [string], sep: string) -> string", "Joins the strings in parts with sep between them, which defaults to \"\"."},
+	"trim":           {"trim(s: string, cutset: string) -> string", "Removes the characters in cutset, or whitespace if it is left out, from both ends of s."},
+	"upper":          {"upper(s: string) -> string", "Returns s in upper case."},
+	"lower":          {"lower(s: string) -> string", "Returns s in lower case."},
+	"contains":       {"contains(s: string, substr: string) -> bool", "Reports whether substr is within s."},
+	"starts_with":    {"starts_with(s: string, prefix: string) -> bool", "Reports whether s begins with prefix."},
+	"ends_with":      {"ends_with(s: string, suffix: string) -> bool", "Reports whether s ends with suffix."},
+	"replace":        {"replace(s: string, old: string, new: string) -> string", "Replaces every old in s with new."},
+	"repeat":         {"repeat(s: string, count: int) -> string", "Returns count copies of s joined together."},
+	"substr":         {"substr(s: string, start: int, end: int) -> string", "Returns the characters of s from start up to end, or to the end of s if end is left out."},
+	"chars":          {"chars(s: string) -> [string]", "Splits s into its characters."},
+	"to_int":         {"to_int(value) -> int", "Converts a string, float or boolean to an integer."},
+	"to_string":      {"to_string(value) -> string", "Returns the printed form of value."},
+	"keys":           {"keys(hash) -> array", "Returns the keys of a hash in insertion order."},
+	"values":         {"values(hash) -> array", "Returns the values of a hash in insertion order."},
+	"items":          {"items(hash) -> array", "Returns the [key, value] pairs of a hash in insertion order."},
+	"has":            {"has(hash, key) -> bool", "Reports whether hash contains key."},
+	"set":            {"set(hash, key, value) -> hash", "Returns a copy of hash with key set to value."},
+	"delete":         {"delete(hash, key) -> hash", "Returns a copy of hash without key."},
+	"merge":          {"merge(hash, ...) -> hash", "Returns a new hash with the pairs of all its arguments, later ones winning."},
+	"filter":         {"filter(array, predicate: fn(value) -> any) -> array", "Returns the elements of array for which predicate returns a true condition."},
+	"json_parse":     {"json_parse(s: string)", "Parses JSON text into Monkey values."},
+	"json_stringify": {"json_stringify(value, indent) -> string", "Encodes value as JSON, indented by indent spaces or the indent string if given."},
+	"assert":         {"assert(condition: bool, message: string)", "Fails the test with message unless condition is true."},
+	"assert_eq":      {"assert_eq(actual, expected, message: string)", "Fails the test with a diff of the two values unless they are equal."},
+	"assert_error":   {"assert_error(f: fn() -> any, substring: string)", "Calls f and fails the test unless it returns an error containing substring."},
+	"puts":           {"puts(value, ...)", "Prints each argument on its own line and returns null."},
+	"quote":          {"quote(expression)", "Returns expression unevaluated, with unquote calls inside it evaluated."},
+	"unquote":        {"unquote(expression)", "Evaluates expression inside a quote."},
+}
+
+// keywords are offered as completions.
+var keywords = []string{"fn", "let", "if", "else", "return", "true", "false", "import", "export", "macro", "match", "const"}
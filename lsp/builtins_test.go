@@ -0,0 +1,29 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
+
+func TestBuiltinDocs(t *testing.T) {
+	for _, name := range evaluator.BuiltinNames() {
+		if _, ok := builtinDocs[name]; !ok {
+			t.Errorf("builtin %s has no documentation", name)
+		}
+	}
+	for name := range builtinDocs {
+		if !evaluator.IsBuiltin(name) && name != "quote" && name != "unquote" {
+			t.Errorf("documentation for %s, which isn't a builtin", name)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	for _, keyword := range keywords {
+		if token.LookupIdent(keyword) == token.IDENT {
+			t.Errorf("%s isn't a keyword", keyword)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package lsp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/format"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/lint"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+	"github.com/OlyaIvanovs/interpreter_in_go/types"
+)
+
+// A document is an open source file and what is known about it.
+type document struct {
+	uri   string
+	text  string
+	lines []string
+
+	parseErrors []parser.Error
+	program     *ast.Program // nil if the text does not parse
+	resolution  *resolution
+	types       *types.Result
+
+	// names are the bindings offered as completions. They are kept from
+	// the last version that parsed while the text is being edited.
+	names []nameCompletion
+}
+
+// A nameCompletion offers a binding where its scope reaches.
+type nameCompletion struct {
+	item  CompletionItem
+	scope *Range // nil for a name bound by the program
+	depth int    // of the scope, so that inner names win over outer ones
+}
+
+// newDocument analyses text. The previous version of the document, if
+// any, provides completions while text does not parse.
+func newDocument(uri, text string, previous *document) *document {
+	d := &document{uri: uri, text: text, lines: strings.Split(text, "\n")}
+
+	p := parser.New(lexer.New(text))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		d.parseErrors = p.ErrorList()
+		if previous != nil {
+			d.names = previous.names
+		}
+		return d
+	}
+
+	d.program = program
+	d.resolution = resolve(program)
+	d.types = types.Check(program)
+	d.names = d.bindingCompletions()
+	return d
+}
+
+// position converts a 1-based line and byte column, as in tokens, to an
+// LSP position.
+func (d *document) position(line, column int) Position {
+	if line < 1 || line > len(d.lines) {
+		return Position{Line: line - 1}
+	}
+
+	text := d.lines[line-1]
+	n := column - 1
+	if n < 0 {
+		n = 0
+	} else if n > len(text) {
+		n = len(text)
+	}
+	return Position{Line: line - 1, Character: utf16Len(text[:n])}
+}
+
+// location converts an LSP position to a 1-based line and byte column.
+func (d *document) location(pos Position) (line, column int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, 1
+	}
+
+	text := d.lines[pos.Line]
+	units := 0
+	for i, r := range text {
+		if units >= pos.Character {
+			return pos.Line + 1, i + 1
+		}
+		units += utf16Len(string(r))
+	}
+	return pos.Line + 1, len(text) + 1
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	return Range{
+		Start: d.position(tok.Line, tok.Column),
+		End:   d.position(tok.Line, tokenEnd(tok)),
+	}
+}
+
+// tokenEnd returns the column just after tok.
+func tokenEnd(tok token.Token) int {
+	if tok.Type == token.STRING {
+		return tok.Column + len(tok.Literal) + 2 // the quotes
+	}
+	return tok.Column + len(tok.Literal)
+}
+
+// wordRange covers the word starting at line and column, or is empty if
+// there is none.
+func (d *document) wordRange(line, column int) Range {
+	end := column
+	if line >= 1 && line <= len(d.lines) {
+		text := d.lines[line-1]
+		for end-1 < len(text) && end >= 1 {
+			r, size := utf8.DecodeRuneInString(text[end-1:])
+			if !isWordRune(r) {
+				break
+			}
+			end += size
+		}
+	}
+	return Range{Start: d.position(line, column), End: d.position(line, end)}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+}
+
+// nodeRange covers the tokens of node, from the first to the last,
+// closing brackets included.
+func (d *document) nodeRange(node ast.Node) Range {
+	start, _ := ast.TokenOf(node)
+	line, column, ok := ast.StartOf(node)
+	if !ok {
+		line, column = start.Line, start.Column
+	}
+	endLine, endColumn, ok := ast.EndOf(node)
+	if !ok {
+		endLine, endColumn = start.Line, tokenEnd(start)
+	}
+
+	return Range{
+		Start: d.position(line, column),
+		End:   d.position(endLine, endColumn),
+	}
+}
+
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, e := range d.parseErrors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.wordRange(e.Line, e.Column),
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  e.Message,
+		})
+	}
+	if d.program == nil {
+		return diagnostics
+	}
+
+	findings, err := lint.Source([]byte(d.text))
+	if err != nil {
+		return diagnostics
+	}
+	for _, f := range findings {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.wordRange(f.Line, f.Column),
+			Severity: SeverityWarning,
+			Code:     f.Check,
+			Source:   "vet",
+			Message:  f.Message,
+		})
+	}
+	return diagnostics
+}
+
+// identifierAt returns the identifier under pos, leaving out the
+// properties of member expressions, which aren't names in scope.
+func (d *document) identifierAt(pos Position) *ast.Identifier {
+	if d.program == nil {
+		return nil
+	}
+
+	line, column := d.location(pos)
+	properties := map[*ast.Identifier]bool{}
+	keys := map[*ast.Identifier]bool{}
+	var found *ast.Identifier
+	ast.Inspect(d.program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.MemberExpression:
+			properties[n.Property] = true
+		case *ast.KeyPattern:
+			// a key names a part of a hash, and {name} binds the name
+			// written at the same place
+			if key, ok := n.Key.(*ast.Identifier); ok {
+				keys[key] = true
+			}
+		case *ast.Identifier:
+			tok := n.Token
+			if found == nil && !keys[n] && tok.Line == line && tok.Column <= column && column <= tok.Column+len(n.Value) {
+				found = n
+			}
+		}
+		return found == nil
+	})
+
+	if properties[found] {
+		return nil
+	}
+	return found
+}
+
+func (d *document) hover(pos Position) *Hover {
+	ident := d.identifierAt(pos)
+	if ident == nil {
+		return nil
+	}
+	r := d.tokenRange(ident.Token)
+
+	b := d.resolution.binding(ident)
+	if b == nil {
+		doc, ok := builtinDocs[ident.Value]
+		if !ok {
+			return nil
+		}
+		return &Hover{Contents: markdown(code(doc.signature) + "\n\n" + doc.doc), Range: &r}
+	}
+	return &Hover{Contents: markdown(code(d.describe(b))), Range: &r}
+}
+
+// describe shows a binding with its inferred type, and its value if that
+// is a literal.
+func (d *document) describe(b *binding) string {
+	switch b.kind {
+	case paramBinding:
+		return "(parameter) " + b.name + ": " + d.typeOf(b.ident)
+	case functionBinding:
+		return "(function) " + b.name + ": " + d.typeOf(b.ident)
+	case armBinding:
+		return "(match) " + b.name + ": " + d.typeOf(b.ident)
+	case importBinding:
+		return strings.TrimSuffix(b.imp.String(), ";")
+	}
+
+	keyword := "let "
+	if b.let.IsConst() {
+		keyword = "const "
+	}
+	s := keyword + b.name + ": " + d.typeOf(b.ident)
+	if b.let.Name != b.ident {
+		// bound to a part of the value
+		return s
+	}
+	switch v := b.let.Value.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean:
+		s += " = " + v.String()
+	case *ast.StringLiteral:
+		s += " = " + strconv.Quote(v.Value)
+	}
+	return s
+}
+
+func (d *document) typeOf(ident *ast.Identifier) string {
+	if t, ok := d.types.Types[ident]; ok {
+		return t.String()
+	}
+	return types.Any.String()
+}
+
+func markdown(s string) MarkupContent {
+	return MarkupContent{Kind: "markdown", Value: s}
+}
+
+func code(s string) string {
+	return "```monkey\n" + s + "\n```"
+}
+
+func (d *document) definition(pos Position) *Location {
+	ident := d.identifierAt(pos)
+	if ident == nil {
+		return nil
+	}
+
+	b := d.resolution.binding(ident)
+	if b == nil {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.tokenRange(b.token)}
+}
+
+func (d *document) symbols() []DocumentSymbol {
+	if d.program == nil {
+		return []DocumentSymbol{}
+	}
+	return d.statementSymbols(d.program.Statements)
+}
+
+// statementSymbols lists the bindings made by stmts, with the bindings
+// in function bodies as their children.
+func (d *document) statementSymbols(stmts []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, stmt := range stmts {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			kind := SymbolVariable
+			if stmt.IsConst() {
+				kind = SymbolConstant
+			}
+			name, ok := stmt.Name.(*ast.Identifier)
+			if !ok {
+				// a pattern binds a variable for each of its names
+				for _, name := range ast.PatternNames(stmt.Name) {
+					symbols = append(symbols, DocumentSymbol{
+						Name:           name.Value,
+						Detail:         d.typeOf(name),
+						Kind:           kind,
+						Range:          d.nodeRange(stmt),
+						SelectionRange: d.tokenRange(name.Token),
+					})
+				}
+				continue
+			}
+			symbol := DocumentSymbol{
+				Name:           name.Value,
+				Detail:         d.typeOf(name),
+				Kind:           kind,
+				Range:          d.nodeRange(stmt),
+				SelectionRange: d.tokenRange(name.Token),
+			}
+			switch v := stmt.Value.(type) {
+			case *ast.FunctionLiteral:
+				symbol.Kind = SymbolFunction
+				symbol.Children = d.statementSymbols(v.Body.Statements)
+			case *ast.MacroLiteral:
+				symbol.Kind = SymbolFunction
+				symbol.Children = d.statementSymbols(v.Body.Statements)
+			}
+			symbols = append(symbols, symbol)
+		case *ast.ImportStatement:
+			b := d.resolution.bindingOf(stmt)
+			symbols = append(symbols, DocumentSymbol{
+				Name:           b.name,
+				Detail:         strconv.Quote(stmt.Path.Value),
+				Kind:           SymbolModule,
+				Range:          d.nodeRange(stmt),
+				SelectionRange: d.tokenRange(b.token),
+			})
+		case *ast.TestStatement:
+			symbols = append(symbols, DocumentSymbol{
+				Name:           stmt.Name.Value,
+				Detail:         "test",
+				Kind:           SymbolFunction,
+				Range:          d.nodeRange(stmt),
+				SelectionRange: d.tokenRange(stmt.Name.Token),
+				Children:       d.statementSymbols(stmt.Body.Statements),
+			})
+		}
+	}
+	return symbols
+}
+
+// completion offers the names bound in the scopes around pos, builtins
+// and keywords. Clients filter them by what has been typed.
+func (d *document) completion(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	depths := []int{}         // of the scopes of items
+	shown := map[string]int{} // index in items of each label
+	for _, name := range d.names {
+		if name.scope != nil && !contains(*name.scope, pos) {
+			continue
+		}
+		if i, ok := shown[name.item.Label]; !ok {
+			shown[name.item.Label] = len(items)
+			items = append(items, name.item)
+			depths = append(depths, name.depth)
+		} else if name.depth > depths[i] {
+			items[i], depths[i] = name.item, name.depth
+		}
+	}
+
+	builtins := make([]string, 0, len(builtinDocs))
+	for name := range builtinDocs {
+		builtins = append(builtins, name)
+	}
+	sort.Strings(builtins)
+	for _, name := range builtins {
+		doc := builtinDocs[name]
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          CompletionFunction,
+			Detail:        doc.signature,
+			Documentation: &MarkupContent{Kind: "markdown", Value: doc.doc},
+		})
+	}
+
+	for _, keyword := range keywords {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionKeyword})
+	}
+	return items
+}
+
+func (d *document) bindingCompletions() []nameCompletion {
+	names := []nameCompletion{}
+	for _, b := range d.resolution.bindings {
+		item := CompletionItem{Label: b.name, Kind: CompletionVariable}
+		switch b.kind {
+		case importBinding:
+			item.Kind = CompletionModule
+		case letBinding:
+			if b.let.IsConst() {
+				item.Kind = CompletionConstant
+			}
+			switch b.let.Value.(type) {
+			case *ast.FunctionLiteral, *ast.MacroLiteral:
+				if b.let.Name == b.ident {
+					item.Kind = CompletionFunction
+				}
+			}
+			item.Detail = d.typeOf(b.ident)
+		case paramBinding, armBinding:
+			item.Detail = d.typeOf(b.ident)
+		case functionBinding:
+			item.Kind = CompletionFunction
+			item.Detail = d.typeOf(b.ident)
+		}
+
+		name := nameCompletion{item: item, depth: b.scope.depth()}
+		if b.scope.node != nil {
+			r := d.nodeRange(b.scope.node)
+			name.scope = &r
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// contains reports whether pos is in r or at its end, where a name being
+// typed at the end of a match arm is.
+func contains(r Range, pos Position) bool {
+	return !before(pos, r.Start) && !before(r.End, pos)
+}
+
+func before(a, b Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+}
+
+// formatting returns the edits that format the document, or an error if
+// it does not parse.
+func (d *document) formatting() ([]TextEdit, error) {
+	formatted, err := format.Source([]byte(d.text))
+	if err != nil {
+		return nil, err
+	}
+	if string(formatted) == d.text {
+		return []TextEdit{}, nil
+	}
+
+	last := len(d.lines) - 1
+	end := Position{Line: last, Character: utf16Len(d.lines[last])}
+	return []TextEdit{{Range: Range{End: end}, NewText: string(formatted)}}, nil
+}
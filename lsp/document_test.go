@@ -0,0 +1,371 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testSource = `let greeting = "héllo";
+let add = fn(a: int, b) {
+    let sum = a + b;
+    sum
+};
+puts(add(1, 2), greeting);
+`
+
+func TestPositions(t *testing.T) {
+	d := newDocument("file:///a.mk", "let s = \"😀é\"; s;\nx", nil)
+
+	tests := []struct {
+		line, column int
+		expected     Position
+		roundTrip    bool
+	}{
+		{1, 1, Position{0, 0}, true},
+		{1, 9, Position{0, 8}, true},
+		{1, 14, Position{0, 11}, true},
+		{1, 16, Position{0, 12}, true},
+		{1, 19, Position{0, 15}, true},
+		{2, 1, Position{1, 0}, true},
+		{2, 9, Position{1, 1}, false},
+	}
+
+	for _, tt := range tests {
+		if got := d.position(tt.line, tt.column); got != tt.expected {
+			t.Errorf("position(%d, %d) wrong. expected=%+v, got=%+v", tt.line, tt.column, tt.expected, got)
+		}
+		if tt.roundTrip {
+			line, column := d.location(tt.expected)
+			if line != tt.line || column != tt.column {
+				t.Errorf("location(%+v) wrong. expected=%d:%d, got=%d:%d", tt.expected, tt.line, tt.column, line, column)
+			}
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	d := newDocument("file:///a.mk", testSource, nil)
+
+	tests := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{0, 5}, "```monkey\nlet greeting: string = \"héllo\"\n```"},
+		{Position{5, 18}, "```monkey\nlet greeting: string = \"héllo\"\n```"},
+		{Position{5, 6}, "```monkey\nlet add: fn(int, any) -> any\n```"},
+		{Position{2, 15}, "```monkey\n(parameter) a: int\n```"},
+		{Position{3, 6}, "```monkey\nlet sum: any\n```"},
+		{Position{5, 1}, "```monkey\nputs(value, ...)\n```\n\nPrints each argument on its own line and returns null."},
+		{Position{5, 10}, ""},
+		{Position{1, 10}, ""},
+	}
+
+	for _, tt := range tests {
+		hover := d.hover(tt.pos)
+		got := ""
+		if hover != nil {
+			got = hover.Contents.Value
+		}
+		if got != tt.expected {
+			t.Errorf("wrong hover at %+v.\nexpected=%q\ngot=%q", tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func TestHoverFunctions(t *testing.T) {
+	d := newDocument("file:///a.mk", "let f = fn go(n: int) -> int { go(n) };\nfn twice(x) { x * 2 }", nil)
+
+	tests := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{0, 32}, "```monkey\n(function) go: fn(int) -> int\n```"},
+		{Position{1, 4}, "```monkey\nlet twice: fn(any) -> any\n```"},
+	}
+
+	for _, tt := range tests {
+		hover := d.hover(tt.pos)
+		got := ""
+		if hover != nil {
+			got = hover.Contents.Value
+		}
+		if got != tt.expected {
+			t.Errorf("wrong hover at %+v.\nexpected=%q\ngot=%q", tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func TestHoverPatterns(t *testing.T) {
+	d := newDocument("file:///a.mk", `let {name, age: years} = {"name": "a", "age": "b"};`+"\nlet [[x]] = [[1]];", nil)
+
+	tests := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{0, 6}, "```monkey\nlet name: string\n```"},
+		{Position{0, 12}, ""},
+		{Position{0, 18}, "```monkey\nlet years: string\n```"},
+		{Position{1, 6}, "```monkey\nlet x: int\n```"},
+	}
+
+	for _, tt := range tests {
+		hover := d.hover(tt.pos)
+		got := ""
+		if hover != nil {
+			got = hover.Contents.Value
+		}
+		if got != tt.expected {
+			t.Errorf("wrong hover at %+v.\nexpected=%q\ngot=%q", tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func TestHoverMatch(t *testing.T) {
+	d := newDocument("file:///a.mk", "let v = [1, 2];\nmatch (v) { [a, ...r] if a > 0 => r, n => n };", nil)
+
+	tests := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{1, 13}, "```monkey\n(match) a: int\n```"},
+		{Position{1, 19}, "```monkey\n(match) r: [int]\n```"},
+		{Position{1, 37}, "```monkey\n(match) n: [int]\n```"},
+	}
+
+	for _, tt := range tests {
+		hover := d.hover(tt.pos)
+		got := ""
+		if hover != nil {
+			got = hover.Contents.Value
+		}
+		if got != tt.expected {
+			t.Errorf("wrong hover at %+v.\nexpected=%q\ngot=%q", tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	d := newDocument("file:///a.mk", "const limit = 10;\nconst [lo, hi] = [0, limit];", nil)
+
+	hovers := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{0, 7}, "```monkey\nconst limit: int = 10\n```"},
+		{Position{1, 8}, "```monkey\nconst lo: int\n```"},
+	}
+	for _, tt := range hovers {
+		hover := d.hover(tt.pos)
+		got := ""
+		if hover != nil {
+			got = hover.Contents.Value
+		}
+		if got != tt.expected {
+			t.Errorf("wrong hover at %+v.\nexpected=%q\ngot=%q", tt.pos, tt.expected, got)
+		}
+	}
+
+	for _, symbol := range d.symbols() {
+		if symbol.Kind != SymbolConstant {
+			t.Errorf("wrong kind of symbol %s. expected=%d, got=%d", symbol.Name, SymbolConstant, symbol.Kind)
+		}
+	}
+	for _, item := range d.completion(Position{}) {
+		if (item.Label == "limit" || item.Label == "hi") && item.Kind != CompletionConstant {
+			t.Errorf("wrong kind of completion %s. expected=%d, got=%d", item.Label, CompletionConstant, item.Kind)
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	d := newDocument("file:///a.mk", testSource, nil)
+
+	tests := []struct {
+		pos      Position
+		expected *Range
+	}{
+		{Position{5, 7}, &Range{Position{1, 4}, Position{1, 7}}},
+		{Position{3, 5}, &Range{Position{2, 8}, Position{2, 11}}},
+		{Position{2, 14}, &Range{Position{1, 13}, Position{1, 14}}},
+		{Position{5, 2}, nil},
+	}
+
+	for _, tt := range tests {
+		loc := d.definition(tt.pos)
+		if tt.expected == nil {
+			if loc != nil {
+				t.Errorf("expected no definition at %+v, got=%+v", tt.pos, loc)
+			}
+			continue
+		}
+		if loc == nil || loc.URI != "file:///a.mk" || loc.Range != *tt.expected {
+			t.Errorf("wrong definition at %+v. expected=%+v, got=%+v", tt.pos, tt.expected, loc)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	d := newDocument("file:///a.mk", `import "lib/strings";`+"\n"+testSource, nil)
+
+	got, _ := json.Marshal(d.symbols())
+	expected := `[` +
+		`{"name":"strings","detail":"\"lib/strings\"","kind":2,` +
+		`"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":20}},` +
+		`"selectionRange":{"start":{"line":0,"character":7},"end":{"line":0,"character":20}}},` +
+		`{"name":"greeting","detail":"string","kind":13,` +
+		`"range":{"start":{"line":1,"character":0},"end":{"line":1,"character":22}},` +
+		`"selectionRange":{"start":{"line":1,"character":4},"end":{"line":1,"character":12}}},` +
+		`{"name":"add","detail":"fn(int, any) -\u003e any","kind":12,` +
+		`"range":{"start":{"line":2,"character":0},"end":{"line":5,"character":1}},` +
+		`"selectionRange":{"start":{"line":2,"character":4},"end":{"line":2,"character":7}},` +
+		`"children":[{"name":"sum","detail":"any","kind":13,` +
+		`"range":{"start":{"line":3,"character":4},"end":{"line":3,"character":19}},` +
+		`"selectionRange":{"start":{"line":3,"character":8},"end":{"line":3,"character":11}}}]}` +
+		`]`
+	if string(got) != expected {
+		t.Errorf("wrong symbols.\nexpected=%s\ngot=%s", expected, got)
+	}
+	d = newDocument("file:///a_test.mk", `test "adds" { let x = 1; assert_eq(x, 1) }`, nil)
+	got, _ = json.Marshal(d.symbols())
+	expected = `[{"name":"adds","detail":"test","kind":12,` +
+		`"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":42}},` +
+		`"selectionRange":{"start":{"line":0,"character":5},"end":{"line":0,"character":11}},` +
+		`"children":[{"name":"x","detail":"int","kind":13,` +
+		`"range":{"start":{"line":0,"character":14},"end":{"line":0,"character":23}},` +
+		`"selectionRange":{"start":{"line":0,"character":18},"end":{"line":0,"character":19}}}]}]`
+	if string(got) != expected {
+		t.Errorf("wrong test symbols.\nexpected=%s\ngot=%s", expected, got)
+	}
+
+	// the range takes in the closing brace of the function
+	d = newDocument("file:///a.mk", "let f = fn(a) { a + x };", nil)
+	symbols := d.symbols()
+	if len(symbols) != 1 || symbols[0].Range.End != (Position{Line: 0, Character: 23}) {
+		t.Errorf("wrong range for f. got=%+v", symbols)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	d := newDocument("file:///a.mk", testSource, nil)
+
+	// inside the body of add
+	items := map[string]CompletionItem{}
+	for _, item := range d.completion(Position{Line: 3, Character: 4}) {
+		items[item.Label] = item
+	}
+
+	tests := []struct {
+		label  string
+		kind   int
+		detail string
+	}{
+		{"greeting", CompletionVariable, "string"},
+		{"add", CompletionFunction, "fn(int, any) -> any"},
+		{"a", CompletionVariable, "int"},
+		{"sum", CompletionVariable, "any"},
+		{"len", CompletionFunction, "len(value) -> int"},
+		{"return", CompletionKeyword, ""},
+	}
+	for _, tt := range tests {
+		item, ok := items[tt.label]
+		if !ok {
+			t.Errorf("no completion for %s", tt.label)
+			continue
+		}
+		if item.Kind != tt.kind || item.Detail != tt.detail {
+			t.Errorf("wrong completion for %s. expected kind=%d detail=%q, got kind=%d detail=%q",
+				tt.label, tt.kind, tt.detail, item.Kind, item.Detail)
+		}
+	}
+
+	// at the top level, on puts(add(1, 2), greeting)
+	top := map[string]bool{}
+	for _, item := range d.completion(Position{Line: 5, Character: 5}) {
+		top[item.Label] = true
+	}
+	if !top["greeting"] || !top["add"] || top["a"] || top["sum"] {
+		t.Errorf("wrong names offered at the top level. got=%v", top)
+	}
+
+	// an inner name hides an outer one, and names of a match arm are only
+	// offered in that arm
+	shadowed := newDocument("file:///a.mk", `let x = "s"; let f = fn(x) { match (x) { [y] => y, _ => x } };`, nil)
+	for _, tt := range []struct {
+		character int
+		x         string
+		y         bool
+	}{
+		{29, "any", false},
+		{47, "any", true},
+		{56, "any", false},
+		{63, "string", false},
+	} {
+		x, y := "", false
+		for _, item := range shadowed.completion(Position{Line: 0, Character: tt.character}) {
+			switch item.Label {
+			case "x":
+				x = item.Detail
+			case "y":
+				y = true
+			}
+		}
+		if x != tt.x || y != tt.y {
+			t.Errorf("wrong names at character %d. expected x=%q y=%t, got x=%q y=%t", tt.character, tt.x, tt.y, x, y)
+		}
+	}
+
+	// names are kept while the document doesn't parse
+	broken := newDocument("file:///a.mk", testSource+"let = ", d)
+	found := false
+	for _, item := range broken.completion(Position{Line: 6}) {
+		found = found || item.Label == "greeting"
+	}
+	if !found {
+		t.Errorf("names of the last version that parsed not offered")
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Diagnostic
+	}{
+		{"let x = 1; puts(x);", []Diagnostic{}},
+		{"let = 1;", []Diagnostic{
+			{Range{Position{0, 4}, Position{0, 4}}, SeverityError, "", "monkey", "expected next token to be IDENT, got '=' instead"},
+			{Range{Position{0, 4}, Position{0, 4}}, SeverityError, "", "monkey", "no prefix parse function for = found"},
+		}},
+		{"let unused = 1;\nputs(missing);", []Diagnostic{
+			{Range{Position{0, 4}, Position{0, 10}}, SeverityWarning, "unused", "vet", "unused is declared but never used"},
+			{Range{Position{1, 5}, Position{1, 12}}, SeverityWarning, "undefined", "vet", "undefined: missing"},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, _ := json.Marshal(newDocument("file:///a.mk", tt.input, nil).diagnostics())
+		expected, _ := json.Marshal(tt.expected)
+		if string(got) != string(expected) {
+			t.Errorf("wrong diagnostics for %q.\nexpected=%s\ngot=%s", tt.input, expected, got)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	edits, err := newDocument("file:///a.mk", "let x=1;\nputs( x )", nil).formatting()
+	if err != nil {
+		t.Fatalf("formatting failed: %s", err)
+	}
+	expected := []TextEdit{{Range{Position{0, 0}, Position{1, 9}}, "let x = 1;\nputs(x);\n"}}
+	if len(edits) != 1 || edits[0] != expected[0] {
+		t.Errorf("wrong edits. expected=%+v, got=%+v", expected, edits)
+	}
+
+	edits, err = newDocument("file:///a.mk", "let x = 1;\n", nil).formatting()
+	if err != nil || len(edits) != 0 {
+		t.Errorf("expected no edits for formatted text, got=%+v, %v", edits, err)
+	}
+
+	if _, err := newDocument("file:///a.mk", "let = 1;", nil).formatting(); err == nil {
+		t.Errorf("expected an error formatting text that doesn't parse")
+	}
+}
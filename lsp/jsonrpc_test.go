@@ -0,0 +1,64 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConnFraming(t *testing.T) {
+	var buf bytes.Buffer
+	c := newConn(&buf, &buf)
+
+	if err := c.notify("window/logMessage", map[string]string{"message": "héllo"}); err != nil {
+		t.Fatalf("notify failed: %s", err)
+	}
+	if err := c.reply(json.RawMessage("7"), nil, nil); err != nil {
+		t.Fatalf("reply failed: %s", err)
+	}
+
+	expected := "Content-Length: 76\r\n\r\n" +
+		`{"jsonrpc":"2.0","method":"window/logMessage","params":{"message":"héllo"}}` +
+		"Content-Length: 38\r\n\r\n" +
+		`{"jsonrpc":"2.0","id":7,"result":null}`
+	if got := buf.String(); got != expected {
+		t.Fatalf("wrong output.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	msg, err := c.read()
+	if err != nil {
+		t.Fatalf("read failed: %s", err)
+	}
+	if msg.Method != "window/logMessage" || string(msg.Params) != `{"message":"héllo"}` {
+		t.Errorf("wrong message read: %+v", msg)
+	}
+
+	msg, err = c.read()
+	if err != nil {
+		t.Fatalf("read failed: %s", err)
+	}
+	if string(msg.ID) != "7" || string(msg.Result) != "null" {
+		t.Errorf("wrong response read: %+v", msg)
+	}
+}
+
+func TestConnReadErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Length: x\r\n\r\n{}", `invalid Content-Length "x"`},
+		{"Content-Type: text\r\n\r\n{}", `invalid Content-Length ""`},
+		{"Content-Length: 2\r\n\r\n{]", "invalid character ']' looking for beginning of object key string"},
+		{"Content-Length: 10\r\n\r\n{}", "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		c := newConn(strings.NewReader(tt.input), nil)
+		_, err := c.read()
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/token"
+)
+
+type bindingKind int
+
+const (
+	letBinding bindingKind = iota
+	paramBinding
+	importBinding
+	functionBinding
+	armBinding
+)
+
+// A binding is a name introduced by a let statement, a function parameter,
+// an import, a named function, inside which its name is bound, or the
+// pattern of a match arm.
+type binding struct {
+	name  string
+	kind  bindingKind
+	token token.Token // where the name is introduced
+	scope *scope      // where the name is bound
+
+	ident *ast.Identifier      // nil for imports without an alias
+	let   *ast.LetStatement    // for let bindings
+	imp   *ast.ImportStatement // for imports
+}
+
+// A resolution links the identifiers of a program to the bindings they
+// refer to.
+type resolution struct {
+	bindings []*binding
+	uses     map[*ast.Identifier]*binding
+	decls    map[*ast.Identifier]*binding
+}
+
+// A scope holds the names bound by the program, a function, a test or a
+// match arm, which is its node.
+type scope struct {
+	names map[string]*binding
+	outer *scope
+	node  ast.Node // nil for the program
+}
+
+func newScope(outer *scope, node ast.Node) *scope {
+	return &scope{names: map[string]*binding{}, outer: outer, node: node}
+}
+
+// depth counts the scopes around s.
+func (s *scope) depth() int {
+	n := 0
+	for ; s.outer != nil; s = s.outer {
+		n++
+	}
+	return n
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.names[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// A pendingFunction is a function body resolved once the scope around it
+// is complete, so that it sees names bound after it.
+type pendingFunction struct {
+	params []ast.Pattern
+	rest   *ast.Identifier
+	body   *ast.BlockStatement
+	scope  *scope
+	self   *ast.Identifier // the name of a named function, bound inside it
+	node   ast.Node        // the function, macro or test
+}
+
+type resolver struct {
+	res     *resolution
+	pending []pendingFunction
+}
+
+// resolve binds every identifier in program to its let statement,
+// parameter or import. Builtins and undefined names are left unbound.
+func resolve(program *ast.Program) *resolution {
+	r := &resolver{res: &resolution{
+		uses:  map[*ast.Identifier]*binding{},
+		decls: map[*ast.Identifier]*binding{},
+	}}
+	r.statements(program.Statements, newScope(nil, nil))
+
+	for len(r.pending) > 0 {
+		fn := r.pending[0]
+		r.pending = r.pending[1:]
+
+		inner := newScope(fn.scope, fn.node)
+		// a declared function is already bound by its declaration
+		if fn.self != nil && r.res.decls[fn.self] == nil {
+			r.declare(inner, &binding{name: fn.self.Value, kind: functionBinding, token: fn.self.Token, ident: fn.self})
+		}
+		for _, param := range fn.params {
+			r.bind(inner, param, paramBinding, nil)
+		}
+		if fn.rest != nil {
+			r.declare(inner, &binding{name: fn.rest.Value, kind: paramBinding, token: fn.rest.Token, ident: fn.rest})
+		}
+		if fn.body != nil {
+			r.statements(fn.body.Statements, inner)
+		}
+	}
+	return r.res
+}
+
+func (r *resolver) declare(s *scope, b *binding) {
+	b.scope = s
+	s.names[b.name] = b
+	r.res.bindings = append(r.res.bindings, b)
+	if b.ident != nil {
+		r.res.decls[b.ident] = b
+	}
+}
+
+func (r *resolver) statements(stmts []ast.Statement, s *scope) {
+	// declared functions are bound before the statements around them run
+	for _, stmt := range stmts {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+		if let, ok := stmt.(*ast.LetStatement); ok && let.IsDeclaration() {
+			r.declareLet(let, s)
+		}
+	}
+
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			r.let(stmt, s)
+		case *ast.ExportStatement:
+			r.let(stmt.Statement, s)
+		case *ast.ImportStatement:
+			b := &binding{kind: importBinding, token: stmt.Path.Token, imp: stmt}
+			if stmt.Alias != nil {
+				b.name, b.token, b.ident = stmt.Alias.Value, stmt.Alias.Token, stmt.Alias
+			} else {
+				base := filepath.Base(stmt.Path.Value)
+				b.name = strings.TrimSuffix(base, filepath.Ext(base))
+			}
+			r.declare(s, b)
+		case *ast.TestStatement:
+			r.pending = append(r.pending, pendingFunction{nil, nil, stmt.Body, s, nil, stmt})
+		case *ast.ReturnStatement:
+			r.expression(stmt.ReturnValue, s)
+		case *ast.ExpressionStatement:
+			r.expression(stmt.Expression, s)
+		}
+	}
+}
+
+func (r *resolver) let(stmt *ast.LetStatement, s *scope) {
+	r.expression(stmt.Value, s)
+	if !stmt.IsDeclaration() {
+		r.declareLet(stmt, s)
+	}
+}
+
+func (r *resolver) declareLet(stmt *ast.LetStatement, s *scope) {
+	r.bind(s, stmt.Name, letBinding, stmt)
+}
+
+// bind declares the names pattern p binds, resolving each default in it
+// before the names next to it, as it sees only those before it.
+func (r *resolver) bind(s *scope, p ast.Pattern, kind bindingKind, let *ast.LetStatement) {
+	r.expression(ast.DefaultOf(p), s)
+	switch p := p.(type) {
+	case *ast.Identifier:
+		r.declare(s, &binding{name: p.Value, kind: kind, token: p.Token, ident: p, let: let})
+	case *ast.ArrayPattern:
+		for _, el := range p.Elements {
+			r.bind(s, el, kind, let)
+		}
+		if p.Rest != nil {
+			r.bind(s, p.Rest, kind, let)
+		}
+	case *ast.HashPattern:
+		for _, pair := range p.Pairs {
+			r.bind(s, pair.Value, kind, let)
+		}
+		if p.Rest != nil {
+			r.bind(s, p.Rest, kind, let)
+		}
+	}
+}
+
+func (r *resolver) expression(e ast.Expression, s *scope) {
+	if e == nil {
+		return
+	}
+
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			if b := s.lookup(n.Value); b != nil {
+				r.res.uses[n] = b
+			}
+			return false
+		case *ast.FunctionLiteral:
+			r.pending = append(r.pending, pendingFunction{n.Parameters, n.Rest, n.Body, s, n.Name, n})
+			return false
+		case *ast.MacroLiteral:
+			params := []ast.Pattern{}
+			for _, param := range n.Parameters {
+				params = append(params, param)
+			}
+			r.pending = append(r.pending, pendingFunction{params, nil, n.Body, s, nil, n})
+			return false
+		case *ast.MemberExpression:
+			r.expression(n.Object, s)
+			return false
+		case *ast.MatchExpression:
+			r.expression(n.Value, s)
+			// each arm binds its names in a scope of its own
+			for _, arm := range n.Arms {
+				inner := newScope(s, arm)
+				r.bind(inner, arm.Pattern, armBinding, nil)
+				r.expression(arm.Guard, inner)
+				r.expression(arm.Body, inner)
+			}
+			return false
+		case *ast.BlockStatement:
+			// blocks share the scope of the function they are in
+			r.statements(n.Statements, s)
+			return false
+		}
+		return true
+	})
+}
+
+// binding returns what ident, a use or a declaration, refers to.
+func (res *resolution) binding(ident *ast.Identifier) *binding {
+	if b, ok := res.decls[ident]; ok {
+		return b
+	}
+	return res.uses[ident]
+}
+
+// bindingOf returns the binding made by an import statement.
+func (res *resolution) bindingOf(stmt *ast.ImportStatement) *binding {
+	for _, b := range res.bindings {
+		if b.imp == stmt {
+			return b
+		}
+	}
+	return nil
+}
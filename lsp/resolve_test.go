@@ -0,0 +1,67 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // use -> declaration, as line:column pairs
+	}{
+		{"let x = 1; x;", []string{"1:12 -> 1:5"}},
+		{"let x = 1; let x = x; x;", []string{"1:20 -> 1:5", "1:23 -> 1:16"}},
+		{"let f = fn(x) { x + y }; let y = 2;", []string{"1:17 -> 1:12", "1:21 -> 1:30"}},
+		{"let f = fn() { f() };", []string{"1:16 -> 1:5"}},
+		{"let f = fn() { if (true) { let a = 1; } a };", []string{"1:41 -> 1:32"}},
+		{`import "lib/strings"; import "x" as y; strings.upper; y.z;`,
+			[]string{"1:40 -> 1:8", "1:55 -> 1:37"}},
+		{"len(puts);", nil},
+		{`test "t" { let a = f; a }; let f = 1;`, []string{"1:20 -> 1:32", "1:23 -> 1:16"}},
+		{"let m = macro(a) { quote(unquote(a)) };", []string{"1:34 -> 1:15"}},
+		{"let f = fn(a, b = a, ...r) { r }; f(...[1]);", []string{"1:19 -> 1:12", "1:30 -> 1:25", "1:35 -> 1:5"}},
+		{"let f = fn go(n) { go(n) }; g(); fn g() { g }", []string{"1:20 -> 1:12", "1:23 -> 1:15", "1:29 -> 1:37", "1:43 -> 1:37"}},
+		{"let [a, b = a, ...r] = x; r;", []string{"1:13 -> 1:6", "1:27 -> 1:19"}},
+		{"let f = fn({k, v: w}) { k + w };", []string{"1:25 -> 1:13", "1:29 -> 1:19"}},
+		{"let x = 1; match (x) { [x] => x, y if y => x };",
+			[]string{"1:19 -> 1:5", "1:31 -> 1:25", "1:39 -> 1:34", "1:44 -> 1:5"}},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		res := resolve(program)
+
+		var got []string
+		for use, b := range res.uses {
+			got = append(got, fmt.Sprintf("%d:%d -> %d:%d", use.Token.Line, use.Token.Column, b.token.Line, b.token.Column))
+		}
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("wrong resolution for %q.\nexpected=%v\ngot=%v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveDeclarations(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(a, b) { let c = a; c };")).ParseProgram()
+	res := resolve(program)
+
+	var names []string
+	for _, b := range res.bindings {
+		names = append(names, b.name)
+	}
+	if fmt.Sprint(names) != "[f a b c]" {
+		t.Errorf("wrong bindings. got=%v", names)
+	}
+
+	let := program.Statements[0].(*ast.LetStatement)
+	if b := res.binding(let.Name.(*ast.Identifier)); b == nil || b.let != let {
+		t.Errorf("declaration of f not resolved to its let statement")
+	}
+}
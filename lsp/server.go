@@ -0,0 +1,187 @@
+// Package lsp implements a Language Server Protocol server for Monkey,
+// providing diagnostics, hovers, go-to-definition, document symbols,
+// completion and formatting.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrNoShutdown is returned by Serve when the client exits without asking
+// the server to shut down first.
+var ErrNoShutdown = errors.New("lsp: exit before shutdown")
+
+type server struct {
+	conn        *conn
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// Serve answers the requests read from r, writing responses and
+// notifications to w, until the client sends exit.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{conn: newConn(r, w), docs: map[string]*document{}}
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			var rerr *responseError
+			if errors.As(err, &rerr) {
+				if err := s.conn.reply(json.RawMessage("null"), nil, rerr); err != nil {
+					return err
+				}
+				continue
+			}
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		switch {
+		case msg.Method == "exit":
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		case msg.ID == nil:
+			s.notification(msg)
+		case msg.Method != "":
+			result, rerr := s.request(msg)
+			if err := s.conn.reply(msg.ID, result, rerr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *server) request(msg *message) (interface{}, *responseError) {
+	if !s.initialized && msg.Method != "initialize" {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:           TextDocumentSyncFull,
+				HoverProvider:              true,
+				DefinitionProvider:         true,
+				DocumentSymbolProvider:     true,
+				CompletionProvider:         struct{}{},
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "monkey"},
+		}, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.hover(params.Position), nil
+		}
+		return nil, nil
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.definition(params.Position), nil
+		}
+		return nil, nil
+
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.symbols(), nil
+		}
+		return []DocumentSymbol{}, nil
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.completion(params.Position), nil
+		}
+		return []CompletionItem{}, nil
+
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			// a document that doesn't parse is left alone; its diagnostics
+			// already say why
+			if edits, err := d.formatting(); err == nil {
+				return edits, nil
+			}
+		}
+		return nil, nil
+
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// notification handles a message that expects no response. Malformed
+// notifications are ignored.
+func (s *server) notification(msg *message) {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if decode(msg.Params, &params) == nil {
+			s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if decode(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			last := params.ContentChanges[len(params.ContentChanges)-1]
+			s.update(params.TextDocument.URI, last.Text)
+		}
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if decode(msg.Params, &params) == nil {
+			delete(s.docs, params.TextDocument.URI)
+			s.publish(params.TextDocument.URI, []Diagnostic{})
+		}
+	}
+}
+
+func (s *server) update(uri, text string) {
+	d := newDocument(uri, text, s.docs[uri])
+	s.docs[uri] = d
+	s.publish(uri, d.diagnostics())
+}
+
+func (s *server) publish(uri string, diagnostics []Diagnostic) {
+	s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+func decode(raw json.RawMessage, v interface{}) *responseError {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
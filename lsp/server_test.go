@@ -0,0 +1,173 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// client drives a server over pipes, as an editor would.
+type client struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{t: t, conn: newConn(clientIn, clientOut), done: make(chan error, 1)}
+	go func() {
+		c.done <- Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+	return c
+}
+
+func (c *client) request(method string, params interface{}) *message {
+	c.t.Helper()
+
+	c.nextID++
+	raw, _ := json.Marshal(params)
+	id := json.RawMessage(fmt.Sprint(c.nextID))
+	if err := c.conn.write(&message{ID: id, Method: method, Params: raw}); err != nil {
+		c.t.Fatalf("writing %s failed: %s", method, err)
+	}
+
+	msg := c.read()
+	if string(msg.ID) != string(id) {
+		c.t.Fatalf("expected response to %s, got=%+v", method, msg)
+	}
+	return msg
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatalf("writing %s failed: %s", method, err)
+	}
+}
+
+func (c *client) read() *message {
+	c.t.Helper()
+
+	msg, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("reading from server failed: %s", err)
+	}
+	return msg
+}
+
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+
+	msg := c.read()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got=%+v", msg)
+	}
+	var params PublishDiagnosticsParams
+	json.Unmarshal(msg.Params, &params)
+	return params
+}
+
+func TestServerSession(t *testing.T) {
+	c := newClient(t)
+	uri := "file:///work/main.mk"
+
+	if msg := c.request("textDocument/hover", nil); msg.Error == nil || msg.Error.Code != codeServerNotInitialized {
+		t.Fatalf("expected a not initialized error, got=%+v", msg)
+	}
+
+	var init InitializeResult
+	json.Unmarshal(c.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}).Result, &init)
+	if init.Capabilities.TextDocumentSync != TextDocumentSyncFull || !init.Capabilities.HoverProvider ||
+		!init.Capabilities.DefinitionProvider || !init.Capabilities.DocumentFormattingProvider {
+		t.Fatalf("wrong capabilities: %+v", init.Capabilities)
+	}
+	c.notify("initialized", struct{}{})
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+		URI: uri, LanguageID: "monkey", Version: 1, Text: "let x = ;",
+	}})
+	diags := c.diagnostics()
+	if diags.URI != uri || len(diags.Diagnostics) == 0 || diags.Diagnostics[0].Severity != SeverityError {
+		t.Fatalf("expected parse errors, got=%+v", diags)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x=1;\nputs(x + 1)"}},
+	})
+	if diags := c.diagnostics(); len(diags.Diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics, got=%+v", diags)
+	}
+
+	at := TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{1, 5}}
+
+	var hover Hover
+	json.Unmarshal(c.request("textDocument/hover", at).Result, &hover)
+	if hover.Contents.Value != "```monkey\nlet x: int = 1\n```" {
+		t.Errorf("wrong hover: %+v", hover)
+	}
+
+	var loc Location
+	json.Unmarshal(c.request("textDocument/definition", at).Result, &loc)
+	if loc.URI != uri || loc.Range != (Range{Position{0, 4}, Position{0, 5}}) {
+		t.Errorf("wrong definition: %+v", loc)
+	}
+
+	var symbols []DocumentSymbol
+	json.Unmarshal(c.request("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: at.TextDocument}).Result, &symbols)
+	if len(symbols) != 1 || symbols[0].Name != "x" || symbols[0].Kind != SymbolVariable {
+		t.Errorf("wrong symbols: %+v", symbols)
+	}
+
+	var items []CompletionItem
+	json.Unmarshal(c.request("textDocument/completion", at).Result, &items)
+	if len(items) == 0 || items[0].Label != "x" {
+		t.Errorf("wrong completions: %+v", items)
+	}
+
+	var edits []TextEdit
+	json.Unmarshal(c.request("textDocument/formatting", DocumentFormattingParams{TextDocument: at.TextDocument}).Result, &edits)
+	if len(edits) != 1 || edits[0].NewText != "let x = 1;\nputs(x + 1);\n" {
+		t.Errorf("wrong formatting edits: %+v", edits)
+	}
+
+	if msg := c.request("textDocument/rename", at); msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got=%+v", msg)
+	}
+	if msg := c.request("textDocument/hover", "nonsense"); msg.Error == nil || msg.Error.Code != codeInvalidParams {
+		t.Errorf("expected invalid params, got=%+v", msg)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: at.TextDocument})
+	if diags := c.diagnostics(); len(diags.Diagnostics) != 0 {
+		t.Errorf("expected diagnostics to be cleared, got=%+v", diags)
+	}
+	if msg := c.request("textDocument/hover", at); string(msg.Result) != "null" {
+		t.Errorf("expected no hover for a closed document, got=%s", msg.Result)
+	}
+
+	if msg := c.request("shutdown", nil); msg.Error != nil || string(msg.Result) != "null" {
+		t.Errorf("wrong shutdown response: %+v", msg)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("server failed: %s", err)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	c.request("initialize", struct{}{})
+	c.notify("exit", nil)
+
+	if err := <-c.done; err != ErrNoShutdown {
+		t.Errorf("expected ErrNoShutdown, got=%v", err)
+	}
+}
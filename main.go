@@ -1,14 +1,64 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/coverage"
+	"github.com/OlyaIvanovs/interpreter_in_go/debugger"
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/format"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/lint"
+	"github.com/OlyaIvanovs/interpreter_in_go/lsp"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+	"github.com/OlyaIvanovs/interpreter_in_go/profile"
 	repl "github.com/OlyaIvanovs/interpreter_in_go/repl"
+	"github.com/OlyaIvanovs/interpreter_in_go/testrunner"
+	"github.com/OlyaIvanovs/interpreter_in_go/types"
 )
 
 func main() {
+	evaluator.ModulePath = filepath.SplitList(os.Getenv("MONKEY_PATH"))
+	evaluator.Strict, _ = strconv.ParseBool(os.Getenv("MONKEY_STRICT"))
+	
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "ast":
+			os.Exit(runAST(os.Args[2:]))
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "vet":
+			os.Exit(runVet(os.Args[2:]))
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "lsp":
+			os.Exit(runLSP())
+		case "debug":
+			os.Exit(runDebug(os.Args[2:]))
+		case "dap":
+			os.Exit(runDAP())
+		case "profile":
+			os.Exit(runProfile(os.Args[2:]))
+		case "cover":
+			os.Exit(runCover(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		}
+		os.Exit(runFile(os.Args[1]))
+	}
+	
 	user , err := user.Current()
 	if err != nil {
 		panic(err)
@@ -19,3 +69,434 @@ func main() {
 	repl.Start(os.Stdin, os.Stdout)
 }
 
+func runFile(path string) int {
+	evaluated := evaluator.EvalFile(path)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(os.Stderr, errObj.Inspect())
+		return 1
+	}
+	
+	return 0
+}
+
+// runAST prints the syntax tree of a source file, as JSON with --json.
+func runAST(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the syntax tree as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey ast [--json] file.mk")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	
+	source, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(os.Stderr, "parse errors in %s:\n\t%s\n", flags.Arg(0), strings.Join(p.Errors(), "\n\t"))
+		return 1
+	}
+	
+	if !*asJSON {
+		fmt.Println(program.String())
+		return 0
+	}
+	
+	data, err := ast.ToJSON(program)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	
+	var out bytes.Buffer
+	json.Indent(&out, data, "", "  ")
+	fmt.Println(out.String())
+	return 0
+}
+
+// runFmt formats source files, or standard input if none are given, and
+// prints the result. With -w files are rewritten instead, and with -d a diff
+// is printed.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of standard output")
+	showDiff := flags.Bool("d", false, "print a diff instead of the formatted source")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey fmt [-w] [-d] [file.mk ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			return 2
+		}
+		source, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return formatSource("<standard input>", source, false, *showDiff)
+	}
+	
+	status := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		if code := formatSource(path, source, *write, *showDiff); code != 0 {
+			status = code
+		}
+	}
+	return status
+}
+
+func formatSource(path string, source []byte, write, showDiff bool) int {
+	formatted, err := format.Source(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 1
+	}
+	
+	if showDiff {
+		os.Stdout.Write(format.Diff(path+".orig", source, path, formatted))
+	}
+	
+	if write {
+		if bytes.Equal(source, formatted) {
+			return 0
+		}
+		if err := os.WriteFile(path, formatted, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	
+	if !showDiff {
+		os.Stdout.Write(formatted)
+	}
+	return 0
+}
+
+// runVet reports suspicious code in source files. It exits with 1 if
+// anything was found.
+func runVet(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey vet file.mk ...")
+		return 2
+	}
+	
+	status := 0
+	for _, path := range args {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		
+		findings, err := lint.Source(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+			continue
+		}
+		for _, finding := range findings {
+			fmt.Printf("%s:%s\n", path, finding)
+			status = 1
+		}
+	}
+	return status
+}
+
+// runCheck reports type errors in source files. It exits with 1 if there
+// were any.
+func runCheck(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey check file.mk ...")
+		return 2
+	}
+	
+	status := 0
+	for _, path := range args {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		
+		errs, err := types.Source(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+			continue
+		}
+		for _, e := range errs {
+			fmt.Printf("%s:%s\n", path, e)
+			status = 1
+		}
+	}
+	return status
+}
+
+// runLSP serves the Language Server Protocol over stdin and stdout.
+func runLSP() int {
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runDebug runs a source file under the command-line debugger.
+func runDebug(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey debug file.mk")
+		return 2
+	}
+	return repl.DebugFile(args[0], os.Stdin, os.Stdout)
+}
+
+// runDAP serves the Debug Adapter Protocol over stdin and stdout. What the
+// program prints is sent to the editor as output events, so it can't get
+// mixed up with the protocol.
+func runDAP() int {
+	protocol := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout = w
+	
+	server := debugger.NewDAPServer(os.Stdin, protocol)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				server.Output("stdout", string(buf[:n]))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	
+	if err := server.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runProfile runs a source file while profiling it, then reports where
+// the time or memory went on standard error. With -o the profile is also
+// written for go tool pprof.
+func runProfile(args []string) int {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	output := flags.String("o", "", "write a pprof profile to `file`")
+	sample := flags.String("sample", "wall", "report wall, alloc_space or alloc_objects")
+	byLine := flags.Bool("lines", false, "report by source line instead of by function")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey profile [-o file] [-sample value] [-lines] file.mk")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	value, ok := profile.ParseValue(*sample)
+	if flags.NArg() != 1 || !ok {
+		flags.Usage()
+		return 2
+	}
+	
+	p := profile.New()
+	evaluated := p.Run(func() object.Object { return evaluator.EvalFile(flags.Arg(0)) })
+	status := 0
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(os.Stderr, errObj.Inspect())
+		status = 1
+	}
+	
+	p.WriteText(os.Stderr, value, *byLine)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		if err := p.WritePprof(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return status
+}
+
+// runCover runs source files recording their coverage, adds in saved
+// profiles given with -merge and reports the total on standard error. The
+// result can be saved with -o, and written as HTML or LCOV.
+func runCover(args []string) int {
+	flags := flag.NewFlagSet("cover", flag.ContinueOnError)
+	output := flags.String("o", "", "save the coverage profile to `file`")
+	merge := flags.String("merge", "", "add the comma-separated saved `profiles`")
+	htmlOutput := flags.String("html", "", "write the source annotated with coverage to `file`")
+	lcovOutput := flags.String("lcov", "", "write an LCOV tracefile to `file`")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey cover [-o file] [-merge profiles] [-html file] [-lcov file] [file.mk ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 && *merge == "" {
+		flags.Usage()
+		return 2
+	}
+	
+	p := coverage.New()
+	status := 0
+	if *merge != "" {
+		for _, path := range strings.Split(*merge, ",") {
+			f, err := os.Open(path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			saved, err := coverage.ReadProfile(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+				return 1
+			}
+			p.Merge(saved)
+		}
+	}
+	
+	for _, path := range flags.Args() {
+		evaluated := p.Run(func() object.Object { return evaluator.EvalFile(path) })
+		if errObj, ok := evaluated.(*object.Error); ok {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, errObj.Inspect())
+			status = 1
+		}
+	}
+	
+	p.WriteText(os.Stderr)
+	outputs := []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{*output, p.WriteProfile},
+		{*htmlOutput, p.WriteHTML},
+		{*lcovOutput, p.WriteLCOV},
+	}
+	for _, out := range outputs {
+		if out.path == "" {
+			continue
+		}
+		if err := writeFile(out.path, out.write); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return status
+}
+
+// runTest runs the tests in the *_test.mk files among the files and
+// directories given, or under the current directory, and reports the
+// failures. With -format the results are written as TAP or JUnit XML
+// instead, to standard output or the file given with -o.
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	run := flags.String("run", "", "run only the tests whose names match `regexp`")
+	verbose := flags.Bool("v", false, "list the tests that pass too")
+	reportFormat := flags.String("format", "text", "write the results as text, tap or junit")
+	output := flags.String("o", "", "write the results to `file`")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey test [-run regexp] [-v] [-format text|tap|junit] [-o file] [path ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -run: %s\n", err)
+			return 2
+		}
+	}
+	var results []testrunner.Result
+	var write func(io.Writer) error
+	switch *reportFormat {
+	case "text":
+		write = func(w io.Writer) error { return testrunner.WriteText(w, results, *verbose) }
+	case "tap":
+		write = func(w io.Writer) error { return testrunner.WriteTAP(w, results) }
+	case "junit":
+		write = func(w io.Writer) error { return testrunner.WriteJUnit(w, results) }
+	default:
+		flags.Usage()
+		return 2
+	}
+	
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var err error
+	results, err = testrunner.Run(paths, filter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	
+	if *output != "" {
+		err = writeFile(*output, write)
+	} else {
+		err = write(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if testrunner.Failed(results) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
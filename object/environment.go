@@ -1,8 +1,15 @@
 package object
 
+import (
+	"fmt"
+	"sort"
+)
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store     map[string]Object
+	constants map[string]bool // names of store bound by const
+	outer     *Environment
+	file      string
 }
 
 func NewEnvironment() *Environment {
@@ -18,14 +25,72 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Set binds name to val in e and returns val. A constant of e cannot be
+// bound again, so Set returns an error instead if name is one.
 func (e *Environment) Set(name string, val Object) Object {
+	if e.constants[name] {
+		return &Error{Message: fmt.Sprintf("cannot redeclare constant %s", name)}
+	}
 	e.store[name] = val
 	return val
 }
 
+// SetConst binds name to val in e as a constant, freezing val, and returns
+// val, or an error if name is already a constant of e. Names bound by Set
+// before may become constants.
+func (e *Environment) SetConst(name string, val Object) Object {
+	if result := e.Set(name, val); result != val {
+		return result
+	}
+	if e.constants == nil {
+		e.constants = map[string]bool{}
+	}
+	e.constants[name] = true
+	Freeze(val)
+	return val
+}
+
+// IsConst reports whether name is a constant of e itself.
+func (e *Environment) IsConst(name string) bool {
+	return e.constants[name]
+}
+
+// Names returns the names bound in e itself, not its outer environments,
+// sorted.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Outer returns the environment e is enclosed in, or nil for a top-level
+// environment.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
 	return env
 }
 
+// NewFileEnvironment returns the top-level environment for the source file
+// at path, against which its imports are resolved.
+func NewFileEnvironment(path string) *Environment {
+	env := NewEnvironment()
+	env.file = path
+	return env
+}
+
+// File returns the path of the source file whose top-level environment
+// encloses e, or "" for code that didn't come from a file.
+func (e *Environment) File() string {
+	if e.file == "" && e.outer != nil {
+		return e.outer.File()
+	}
+	return e.file
+}
@@ -1,8 +1,10 @@
 package object
  
 import (
+	"encoding/binary"
 	"fmt" 
-	"hash/fnv"
+	"hash/maphash"
+	"strconv"
 	"strings"
 	
 	"github.com/OlyaIvanovs/interpreter_in_go/ast"
@@ -12,6 +14,7 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ		= "INTEGER"
+	FLOAT_OBJ		= "FLOAT"
 	BOOLEAN_OBJ 	= "BOOLEAN"
 	NULL_OBJ    	= "NULL"
 	RETURN_OBJ    	= "RETURN_VALUE"
@@ -21,6 +24,9 @@ const (
 	BUILTIN_OBJ	    = "BUILTIN"
 	ARRAY_OBJ	    = "ARRAY"
 	HASH_OBJ	    = "HASH"
+	MODULE_OBJ	    = "MODULE"
+	QUOTE_OBJ	    = "QUOTE"
+	MACRO_OBJ	    = "MACRO"
 )
 
 type Object interface {
@@ -28,7 +34,10 @@ type Object interface {
 	Inspect() string
 }
 
+// Hashable objects can be used as hash keys. Distinct keys may share a
+// HashKey; Hash compares the keys themselves to tell them apart.
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 type HashKey struct {
@@ -36,6 +45,13 @@ type HashKey struct {
 	Value uint64
 }
 
+// hashSeed seeds the keys of strings and arrays per process, so colliding
+// keys can't be precomputed.
+var hashSeed = maphash.MakeSeed()
+var stringHash = func(s string) uint64 {
+	return maphash.String(hashSeed, s)
+}
+
 // Integer
 type Integer struct {
 	Value int64
@@ -51,6 +67,22 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: INTEGER_OBJ, Value: uint64(i.Value)}
 }
 
+// Float
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eIN") {
+		str += ".0"
+	}
+	return str
+}
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
 // String
 type String struct {
 	Value string
@@ -63,9 +95,7 @@ func (i *String) Type() ObjectType {
 	return STRING_OBJ
 }
 func (i *String) HashKey() HashKey {
-	h := fnv.New64()
-	h.Write([]byte(i.Value))
-	return HashKey{Type: STRING_OBJ, Value: h.Sum64()}
+	return HashKey{Type: STRING_OBJ, Value: stringHash(i.Value)}
 }
 
 // Boolean
@@ -122,7 +152,9 @@ func (e *Error) Inspect() string {
 
 // Function
 type Function struct {
-	Parameters 	[]*ast.Identifier
+	Name		string // empty for an anonymous function
+	Parameters 	[]ast.Pattern
+	Rest		*ast.Identifier // collects the arguments past Parameters, if any
 	Body		*ast.BlockStatement
 	Env 		*Environment
 }
@@ -133,16 +165,15 @@ func (f *Function) Type() ObjectType {
 func (f *Function) Inspect() string {
 	var out strings.Builder
 	
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
+	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
 	}
-	
-	out.WriteString("fn(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString("(")
+	out.WriteString(ast.ParameterList(f.Parameters, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
-	out.WriteString("\n")
+	out.WriteString("\n}")
 	
 	return out.String()
 }
@@ -160,8 +191,27 @@ func (b *Builtin) Inspect() string { return "builtin function" }
 // Array
 type Array struct {
 	Elements []Object
+	frozen   bool // set by Freeze; the elements must not change
 }
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
+func (ao *Array) HashKey() HashKey {
+	// A seeded hash over the element keys, so equal arrays hash alike but
+	// colliding ones can't be precomputed from the integer keys.
+	var h maphash.Hash
+	h.SetSeed(hashSeed)
+	var value [8]byte
+	for _, e := range ao.Elements {
+		var key HashKey
+		if k, ok := e.(Hashable); ok {
+			key = k.HashKey()
+		}
+		h.WriteString(string(key.Type))
+		h.WriteByte(0)
+		binary.LittleEndian.PutUint64(value[:], key.Value)
+		h.Write(value[:])
+	}
+	return HashKey{Type: ARRAY_OBJ, Value: h.Sum64()}
+}
 func (ao *Array) Inspect() string {
 	var out strings.Builder
 	
@@ -177,21 +227,86 @@ func (ao *Array) Inspect() string {
 	return out.String()
 }
 
-// Hash
+// Frozen reports whether ao has been frozen by Freeze.
+func (ao *Array) Frozen() bool {
+	return ao.frozen
+}
+
+// Quote wraps an unevaluated AST node produced by quote().
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+// Macro
+type Macro struct {
+	Parameters 	[]*ast.Identifier
+	Body		*ast.BlockStatement
+	Env 		*Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out strings.Builder
+	
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	
+	out.WriteString("macro(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+	
+	return out.String()
+}
+
+// Module is the value bound by an import statement. Only bindings the
+// module exported are reachable through it.
+type Module struct {
+	Name    string
+	Path    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string { return fmt.Sprintf("module %s", m.Name) }
+
+// frozenError is what changing obj in place fails with once it is frozen.
+func frozenError(obj Object) *Error {
+	return &Error{Message: fmt.Sprintf("cannot change frozen %s", obj.Type())}
+}
+
+// Hash keeps its pairs in insertion order, so printing and iterating a hash
+// is deterministic. Pairs are bucketed by HashKey and keys within a bucket
+// are compared with Equal, so colliding keys never overwrite each other.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	buckets map[HashKey][]*HashPair
+	order   []*HashPair
+	frozen  bool
 }
 type HashPair struct {
 	Key		Object	
 	Value   Object
 }
+
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]*HashPair)}
+}
+
 func (h *Hash) Type() ObjectType {return HASH_OBJ}
 func (h *Hash) Inspect() string {
 	var out strings.Builder
 	
 	pairs := []string{}
 	
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Pairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 	
@@ -202,4 +317,228 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+func (h *Hash) lookup(key Hashable) (HashKey, int) {
+	hashed := key.HashKey()
+	for i, pair := range h.buckets[hashed] {
+		if Equal(pair.Key, key) {
+			return hashed, i
+		}
+	}
+	return hashed, -1
+}
+
+// Get returns the pair stored under key.
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+	hashed, i := h.lookup(key)
+	if i < 0 {
+		return HashPair{}, false
+	}
+	return *h.buckets[hashed][i], true
+}
+
+// Set stores value under key. A new key is appended to the iteration order,
+// an existing one keeps its position. Set fails if h is frozen.
+func (h *Hash) Set(key Hashable, value Object) *Error {
+	if h.frozen {
+		return frozenError(h)
+	}
+	hashed, i := h.lookup(key)
+	if i >= 0 {
+		h.buckets[hashed][i].Value = value
+		return nil
+	}
+	
+	pair := &HashPair{Key: key, Value: value}
+	h.buckets[hashed] = append(h.buckets[hashed], pair)
+	h.order = append(h.order, pair)
+	return nil
+}
+
+// Delete removes key from the hash and reports whether it was present.
+// Delete fails if h is frozen.
+func (h *Hash) Delete(key Hashable) (bool, *Error) {
+	if h.frozen {
+		return false, frozenError(h)
+	}
+	hashed, i := h.lookup(key)
+	if i < 0 {
+		return false, nil
+	}
+	
+	bucket := h.buckets[hashed]
+	pair := bucket[i]
+	if len(bucket) == 1 {
+		delete(h.buckets, hashed)
+	} else {
+		h.buckets[hashed] = append(bucket[:i:i], bucket[i+1:]...)
+	}
+	
+	for j, p := range h.order {
+		if p == pair {
+			h.order = append(h.order[:j:j], h.order[j+1:]...)
+			break
+		}
+	}
+	return true, nil
+}
+
+func (h *Hash) Len() int {
+	return len(h.order)
+}
+
+// Pairs returns the pairs of the hash in insertion order.
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.order))
+	for i, p := range h.order {
+		pairs[i] = *p
+	}
+	return pairs
+}
 
+// Frozen reports whether h has been frozen by Freeze.
+func (h *Hash) Frozen() bool {
+	return h.frozen
+}
+
+// Copy returns a shallow copy of the hash with the same iteration order.
+// The copy is not frozen, even if h is.
+func (h *Hash) Copy() *Hash {
+	copied := NewHash()
+	for _, p := range h.order {
+		copied.Set(p.Key.(Hashable), p.Value)
+	}
+	return copied
+}
+
+// Freeze makes obj immutable along with the arrays and hashes inside it,
+// as the value of a constant is. The builtins never change their
+// arguments, making changed copies instead, so freezing only guards
+// against code that would: Hash.Set and Hash.Delete fail on a frozen
+// hash, and code changing the elements of an array in place must check
+// Frozen first.
+func Freeze(obj Object) {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.frozen {
+			return
+		}
+		obj.frozen = true
+		for _, el := range obj.Elements {
+			Freeze(el)
+		}
+	case *Hash:
+		if obj.frozen {
+			return
+		}
+		obj.frozen = true
+		for _, pair := range obj.order {
+			Freeze(pair.Key)
+			Freeze(pair.Value)
+		}
+	}
+}
+
+// AsHashable returns obj as a Hashable if it can be used as a hash key.
+// Arrays are hashable only when all of their elements are.
+func AsHashable(obj Object) (Hashable, bool) {
+	h, ok := obj.(Hashable)
+	if !ok {
+		return nil, false
+	}
+	
+	if arr, ok := obj.(*Array); ok {
+		for _, e := range arr.Elements {
+			if _, ok := AsHashable(e); !ok {
+				return nil, false
+			}
+		}
+	}
+	
+	return h, true
+}
+
+// Truthy reports whether obj counts as true where a condition is expected:
+// in if expressions, with the ! && and || operators and in the results of
+// filter predicates. False, null, zero, the empty string, the empty array
+// and the empty hash are false; everything else, including functions,
+// builtins, modules, quotes and macros, is true.
+func Truthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	case *Integer:
+		return obj.Value != 0
+	case *Float:
+		return obj.Value != 0
+	case *String:
+		return obj.Value != ""
+	case *Array:
+		return len(obj.Elements) != 0
+	case *Hash:
+		return obj.Len() != 0
+	case *ReturnValue:
+		return Truthy(obj.Value)
+	default:
+		return true
+	}
+}
+
+// Equal reports whether two objects hold the same value. Integers and
+// floats are compared by their numeric value, as == compares them. Arrays
+// and hashes are compared structurally; functions and builtins only equal
+// themselves.
+func Equal(a, b Object) bool {
+	if f, ok := a.(*Float); ok {
+		if i, ok := b.(*Integer); ok {
+			return f.Value == float64(i.Value)
+		}
+	}
+	if i, ok := a.(*Integer); ok {
+		if f, ok := b.(*Float); ok {
+			return float64(i.Value) == f.Value
+		}
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Float:
+		return a.Value == b.(*Float).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *Null:
+		return true
+	case *Array:
+		other := b.(*Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, e := range a.Elements {
+			if !Equal(e, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		other := b.(*Hash)
+		if a.Len() != other.Len() {
+			return false
+		}
+		for _, pair := range a.order {
+			otherPair, ok := other.Get(pair.Key.(Hashable))
+			if !ok || !Equal(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
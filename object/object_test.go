@@ -1,7 +1,11 @@
 package object
 
 import (
+	"hash/maphash"
+	"strings"
 	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
 )
 
 func TestStringHashKey(t *testing.T) {
@@ -25,3 +29,239 @@ func TestStringHashKey(t *testing.T) {
 
 
 }
+
+func TestHashKeepsInsertionOrder(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&String{Value: "b"}, &Integer{Value: 1})
+	hash.Set(&String{Value: "a"}, &Integer{Value: 2})
+	hash.Set(&Integer{Value: 3}, &Integer{Value: 3})
+	hash.Set(&String{Value: "b"}, &Integer{Value: 4})
+	
+	if hash.Inspect() != "{b: 4, a: 2, 3: 3}" {
+		t.Errorf("hash.Inspect() wrong. got=%q", hash.Inspect())
+	}
+	
+	if ok, _ := hash.Delete(&String{Value: "a"}); !ok {
+		t.Errorf("Delete reported missing key")
+	}
+	if ok, _ := hash.Delete(&String{Value: "a"}); ok {
+		t.Errorf("Delete reported deleted key as present")
+	}
+	
+	copied := hash.Copy()
+	copied.Set(&String{Value: "c"}, &Integer{Value: 5})
+	
+	if hash.Inspect() != "{b: 4, 3: 3}" {
+		t.Errorf("hash.Inspect() wrong after Delete. got=%q", hash.Inspect())
+	}
+	if copied.Inspect() != "{b: 4, 3: 3, c: 5}" {
+		t.Errorf("copied.Inspect() wrong. got=%q", copied.Inspect())
+	}
+}
+
+func TestHashKeepsCollidingKeysApart(t *testing.T) {
+	original := stringHash
+	stringHash = func(s string) uint64 { return uint64(len(s)) }
+	defer func() { stringHash = original }()
+	
+	ab := &String{Value: "ab"}
+	cd := &String{Value: "cd"}
+	if ab.HashKey() != cd.HashKey() {
+		t.Fatalf("test keys should collide")
+	}
+	
+	hash := NewHash()
+	hash.Set(ab, &Integer{Value: 1})
+	hash.Set(cd, &Integer{Value: 2})
+	
+	if hash.Len() != 2 {
+		t.Fatalf("colliding keys overwrote each other. got=%s", hash.Inspect())
+	}
+	
+	for key, expected := range map[string]int64{"ab": 1, "cd": 2} {
+		pair, ok := hash.Get(&String{Value: key})
+		if !ok {
+			t.Errorf("no pair for key %q", key)
+			continue
+		}
+		if pair.Value.(*Integer).Value != expected {
+			t.Errorf("wrong value for key %q. want=%d, got=%s", key, expected, pair.Value.Inspect())
+		}
+	}
+	
+	if _, ok := hash.Get(&String{Value: "ef"}); ok {
+		t.Errorf("found pair for missing colliding key")
+	}
+	
+	hash.Delete(ab)
+	if _, ok := hash.Get(cd); !ok {
+		t.Errorf("deleting a colliding key removed its neighbour")
+	}
+	if hash.Inspect() != "{cd: 2}" {
+		t.Errorf("hash.Inspect() wrong after Delete. got=%q", hash.Inspect())
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	point1 := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}
+	point2 := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}
+	swapped := &Array{Elements: []Object{&Integer{Value: 2}, &Integer{Value: 1}}}
+	
+	if point1.HashKey() != point2.HashKey() {
+		t.Errorf("arrays with same elements have different hash keys")
+	}
+	if point1.HashKey() == swapped.HashKey() {
+		t.Errorf("arrays with different elements have same hash keys")
+	}
+	
+	if _, ok := AsHashable(point1); !ok {
+		t.Errorf("array of integers is not hashable")
+	}
+	unhashable := &Array{Elements: []Object{&Integer{Value: 1}, &Array{Elements: []Object{&Null{}}}}}
+	if _, ok := AsHashable(unhashable); ok {
+		t.Errorf("array containing null is hashable")
+	}
+	
+	// the key depends on the seed of the process
+	seeded := point1.HashKey()
+	original := hashSeed
+	hashSeed = maphash.MakeSeed()
+	defer func() { hashSeed = original }()
+	if point1.HashKey() == seeded {
+		t.Errorf("array hash key does not depend on the seed")
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	full := NewHash()
+	full.Set(&String{Value: "a"}, &Integer{Value: 1})
+	
+	tests := []struct {
+		obj      Object
+		expected bool
+	}{
+		{&Boolean{Value: true}, true},
+		{&Boolean{Value: false}, false},
+		{&Null{}, false},
+		{&Integer{Value: 0}, false},
+		{&Integer{Value: -1}, true},
+		{&Float{Value: 0}, false},
+		{&Float{Value: 0.5}, true},
+		{&String{Value: ""}, false},
+		{&String{Value: "0"}, true},
+		{&Array{}, false},
+		{&Array{Elements: []Object{&Null{}}}, true},
+		{NewHash(), false},
+		{full, true},
+		{&ReturnValue{Value: &Integer{Value: 0}}, false},
+		{&Function{}, true},
+		{&Builtin{}, true},
+		{&Module{Name: "lib"}, true},
+		{&Quote{}, true},
+		{&Macro{}, true},
+	}
+	
+	for _, tt := range tests {
+		if got := Truthy(tt.obj); got != tt.expected {
+			t.Errorf("Truthy(%s %s) wrong. expected=%t, got=%t", tt.obj.Type(), tt.obj.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestFunctionInspect(t *testing.T) {
+	body := &ast.BlockStatement{Statements: []ast.Statement{
+		&ast.ExpressionStatement{Expression: &ast.Identifier{Value: "a"}},
+	}}
+	tests := []struct {
+		fn       *Function
+		expected string
+	}{
+		{&Function{Parameters: []ast.Pattern{&ast.Identifier{Value: "a"}}, Body: body}, "fn(a) {\na\n}"},
+		{&Function{Name: "first", Parameters: []ast.Pattern{&ast.Identifier{Value: "a"}}, Rest: &ast.Identifier{Value: "r"}, Body: body},
+			"fn first(a, ...r) {\na\n}"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn.Inspect(); got != tt.expected {
+			t.Errorf("wrong Inspect(). want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("b", &Integer{Value: 1})
+	outer.Set("a", &Integer{Value: 2})
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("c", &Integer{Value: 3})
+
+	if got := strings.Join(outer.Names(), " "); got != "a b" {
+		t.Errorf("wrong outer names. got=%q", got)
+	}
+	if got := strings.Join(inner.Names(), " "); got != "c" {
+		t.Errorf("wrong inner names. got=%q", got)
+	}
+	if inner.Outer() != outer || outer.Outer() != nil {
+		t.Errorf("wrong outer environments")
+	}
+}
+
+func TestEnvironmentConstants(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	if result := outer.SetConst("x", &Integer{Value: 2}); result.Type() == ERROR_OBJ {
+		t.Fatalf("a let binding could not become a constant: %s", result.Inspect())
+	}
+	if !outer.IsConst("x") {
+		t.Errorf("x is not a constant")
+	}
+
+	for _, set := range []func(string, Object) Object{outer.Set, outer.SetConst} {
+		errObj, ok := set("x", &Integer{Value: 3}).(*Error)
+		if !ok {
+			t.Fatalf("rebinding a constant did not fail")
+		}
+		if errObj.Message != "cannot redeclare constant x" {
+			t.Errorf("wrong error message. got=%q", errObj.Message)
+		}
+	}
+	if value, _ := outer.Get("x"); value.Inspect() != "2" {
+		t.Errorf("constant changed to %s", value.Inspect())
+	}
+
+	// an enclosed environment may bind the name again
+	inner := NewEnclosedEnvironment(outer)
+	if result := inner.Set("x", &Integer{Value: 4}); result.Type() == ERROR_OBJ {
+		t.Errorf("shadowing a constant failed: %s", result.Inspect())
+	}
+	if inner.IsConst("x") {
+		t.Errorf("x of the inner environment is a constant")
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	inner := NewHash()
+	inner.Set(&String{Value: "a"}, &Array{Elements: []Object{&Integer{Value: 1}}})
+	array := &Array{Elements: []Object{inner}}
+
+	env := NewEnvironment()
+	env.SetConst("c", array)
+
+	nested, _ := inner.Get(&String{Value: "a"})
+	if !array.Frozen() || !inner.Frozen() || !nested.Value.(*Array).Frozen() {
+		t.Fatalf("constant not frozen all the way down")
+	}
+	if inner.Copy().Frozen() {
+		t.Errorf("copy of a frozen hash is frozen")
+	}
+
+	if err := inner.Set(&String{Value: "b"}, &Integer{Value: 2}); err == nil || err.Message != "cannot change frozen HASH" {
+		t.Errorf("setting a key of a frozen hash did not fail. got=%v", err)
+	}
+	if ok, err := inner.Delete(&String{Value: "a"}); ok || err == nil {
+		t.Errorf("deleting a key of a frozen hash did not fail")
+	}
+	if inner.Len() != 1 {
+		t.Errorf("frozen hash changed. got=%s", inner.Inspect())
+	}
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+)
+
+var fuzzSeeds = []string{
+	"",
+	"let five = 5; let add = fn(x, y) { x + y; }; add(five, 10);",
+	`!-a * b; a + b * c - d / e; (1 + 2) * 3; -(5 + 5); !(true == true); a < b != c > d;`,
+	`if (x < y) { x } else { y }; if (x) { return; }`,
+	"a || b && !c || d == e && f(g) || h[0] && i.j;",
+	`[1, 2 * 2][0]; {"one": 1, true: [2], 3: {}}["one"]; add(a, b)[c](d);`,
+	`"str" + "ing"; lib.member.call(1).x; import "path/lib" as l; export let x = 1;`,
+	"// comment\nlet f: fn(int, [string]) -> {string: bool} = fn(a: int, b) -> int { a };",
+	"let f = fn(a, b: int = a + 1, ...c: [int]) { f(...c, ...[a, b]) };",
+	"let m = macro(a, b) { quote(unquote(a) + unquote(b)) }; m(1, 2);",
+	`test "adds" { assert_eq(1 + 1, 2); } let test = 1; test + 1;`,
+	"fn f(a) { g(a) } fn g() { f() }; let h = fn go(n) { go(n) } export fn e() {} fn x() {}(1)",
+	`let [a, [b = 1], ...c] = x; let {d, "e f": {g: h = 2}, ...i} = y; fn([j], {k} = {}) { j };`,
+	`match (x) { 0 => a, -1 if b => c, "s" => d, [e, ...f] => e, {g: true, h = 1} => h, _ => match (i) { j => j } }`,
+	"const a = 1; const [b, {c}] = d; export const e: int = a; const f = fn f() { f };",
+	"let = ; fn( { ] } if else return",
+}
+
+// FuzzParseProgram checks that the parser never panics, and leaves no
+// nil nodes behind even in programs it rejects, which tools still walk.
+// A program it accepts must print as source that parses back to the same
+// program.
+func FuzzParseProgram(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		printed := program.String()
+		if len(p.Errors()) != 0 {
+			return
+		}
+
+		p = New(lexer.New(printed))
+		reparsed := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("printed program does not parse.\ninput=%q\nprinted=%q\nerrors=%q", input, printed, p.Errors())
+		}
+		if reparsed.String() != printed {
+			t.Fatalf("reparsed program differs.\ninput=%q\nprinted= %q\nreparsed=%q", input, printed, reparsed.String())
+		}
+	})
+}
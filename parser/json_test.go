@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	corpus := parserTestInputs(t)
+	if len(corpus) < 40 {
+		t.Fatalf("too few inputs found in parser_test.go. got=%d", len(corpus))
+	}
+
+	for _, input := range corpus {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		data, err := ast.ToJSON(program)
+		if err != nil {
+			t.Fatalf("ToJSON(%q) failed: %s", input, err)
+		}
+
+		decoded, err := ast.FromJSON(data)
+		if err != nil {
+			t.Fatalf("FromJSON failed for %q: %s\n%s", input, err, data)
+		}
+
+		if decoded.String() != program.String() {
+			t.Errorf("round trip of %q changed the program. want=%q, got=%q", input, program.String(), decoded.String())
+		}
+
+		again, err := ast.ToJSON(decoded)
+		if err != nil {
+			t.Fatalf("ToJSON of decoded %q failed: %s", input, err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("round trip of %q changed the JSON.\nwant=%s\ngot= %s", input, data, again)
+		}
+	}
+}
+
+func TestJSONPositions(t *testing.T) {
+	p := New(lexer.New("let x = 1;\nx + 2;"))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	data, err := ast.ToJSON(program.Statements[1])
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+
+	expected := `{"kind":"ExpressionStatement","pos":{"line":2,"column":1},` +
+		`"span":{"start":{"line":2,"column":1},"end":{"line":2,"column":6}},` +
+		`"expression":{"kind":"InfixExpression","pos":{"line":2,"column":3},` +
+		`"span":{"start":{"line":2,"column":1},"end":{"line":2,"column":6}},` +
+		`"left":{"kind":"Identifier","pos":{"line":2,"column":1},` +
+		`"span":{"start":{"line":2,"column":1},"end":{"line":2,"column":2}},"value":"x"},"operator":"+",` +
+		`"right":{"kind":"IntegerLiteral","pos":{"line":2,"column":5},` +
+		`"span":{"start":{"line":2,"column":5},"end":{"line":2,"column":6}},"value":2}}}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot= %s", expected, data)
+	}
+}
+
+func TestJSONSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the source the span of the first statement covers
+	}{
+		{"let x = 1;", "let x = 1"},
+		{"foo + bar(1, 2)[0];", "foo + bar(1, 2)[0]"},
+		{"add(1, fn(x) { x * 2 });", "add(1, fn(x) { x * 2 })"},
+		{"puts(\"a\nbc\");", "puts(\"a\nbc\")"},
+		{"if (x) {\n  [1, {\"k\": y}]\n}", "if (x) {\n  [1, {\"k\": y}]\n}"},
+		{"match (x) { [a, ...r] => r }", "match (x) { [a, ...r] => r }"},
+		{"let f = fn([a, {b}], c: [int]) -> {string: int} { a };", "let f = fn([a, {b}], c: [int]) -> {string: int} { a }"},
+		{"lib.x.y;", "lib.x.y"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		data, err := ast.ToJSON(program.Statements[0])
+		if err != nil {
+			t.Fatalf("ToJSON(%q) failed: %s", tt.input, err)
+		}
+		var node struct {
+			Span struct {
+				Start, End struct{ Line, Column int }
+			}
+		}
+		if err := json.Unmarshal(data, &node); err != nil {
+			t.Fatalf("cannot read span of %q: %s", tt.input, err)
+		}
+
+		start := offset(tt.input, node.Span.Start.Line, node.Span.Start.Column)
+		end := offset(tt.input, node.Span.End.Line, node.Span.End.Column)
+		if got := tt.input[start:end]; got != tt.expected {
+			t.Errorf("wrong span for %q. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// TestSpansHoldChildren checks that the span of every node in the parser
+// tests takes in the spans of its children.
+func TestSpansHoldChildren(t *testing.T) {
+	for _, input := range parserTestInputs(t) {
+		program := New(lexer.New(input)).ParseProgram()
+		ast.Inspect(program, func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			startLine, startColumn, ok := ast.StartOf(n)
+			if !ok {
+				return true
+			}
+			endLine, endColumn, _ := ast.EndOf(n)
+			start := offset(input, startLine, startColumn)
+			end := offset(input, endLine, endColumn)
+
+			ast.Walk(childVisitor(func(child ast.Node) {
+				childLine, childColumn, ok := ast.StartOf(child)
+				if !ok {
+					return
+				}
+				childEndLine, childEndColumn, _ := ast.EndOf(child)
+				if offset(input, childLine, childColumn) < start || offset(input, childEndLine, childEndColumn) > end {
+					t.Errorf("span of %T %q does not hold its child %T %q in %q",
+						n, input[start:end], child, child.String(), input)
+				}
+			}), n)
+			return true
+		})
+	}
+}
+
+// childVisitor calls f with each child of the node it visits first.
+type childVisitor func(ast.Node)
+
+func (f childVisitor) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		return nil
+	}
+	return childFunc(f)
+}
+
+type childFunc func(ast.Node)
+
+func (f childFunc) Visit(n ast.Node) ast.Visitor {
+	if n != nil {
+		f(n)
+	}
+	return nil
+}
+
+// offset returns the byte offset of a 1-based line and column in src.
+func offset(src string, line, column int) int {
+	i := 0
+	for ; line > 1; line-- {
+		i += strings.IndexByte(src[i:], '\n') + 1
+	}
+	return i + column - 1
+}
+
+// parserTestInputs collects the program sources used by the tests in
+// parser_test.go: strings assigned to input, or given as the input field
+// of a test table entry.
+func parserTestInputs(t *testing.T) []string {
+	fset := gotoken.NewFileSet()
+	file, err := goparser.ParseFile(fset, "parser_test.go", nil, 0)
+	if err != nil {
+		t.Fatalf("cannot parse parser_test.go: %s", err)
+	}
+
+	var inputs []string
+	add := func(e goast.Expr) {
+		lit, ok := e.(*goast.BasicLit)
+		if !ok || lit.Kind != gotoken.STRING {
+			return
+		}
+		s, err := strconv.Unquote(lit.Value)
+		if err == nil {
+			inputs = append(inputs, s)
+		}
+	}
+
+	goast.Inspect(file, func(n goast.Node) bool {
+		switch n := n.(type) {
+		case *goast.AssignStmt:
+			if id, ok := n.Lhs[0].(*goast.Ident); ok && id.Name == "input" && len(n.Rhs) == 1 {
+				add(n.Rhs[0])
+			}
+		case *goast.CompositeLit:
+			table, ok := n.Type.(*goast.ArrayType)
+			if !ok {
+				return true
+			}
+			entry, ok := table.Elt.(*goast.StructType)
+			if !ok || len(entry.Fields.List) == 0 || entry.Fields.List[0].Names[0].Name != "input" {
+				return true
+			}
+			for _, elt := range n.Elts {
+				fields, ok := elt.(*goast.CompositeLit)
+				if !ok || len(fields.Elts) == 0 {
+					continue
+				}
+				if kv, ok := fields.Elts[0].(*goast.KeyValueExpr); ok {
+					add(kv.Value)
+				} else {
+					add(fields.Elts[0])
+				}
+			}
+		}
+		return true
+	})
+
+	return inputs
+}
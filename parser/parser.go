@@ -15,6 +15,8 @@ type Precedence int
 const (
 	_ Precedence = iota
 	LOWEST
+	OR           // ||
+	AND          // &&
 	EQUALS       // ==
 	LESSGREATER // > or <
 	SUM 		// +
@@ -25,6 +27,8 @@ const (
 )
 
 var precedences = map[token.TokenType]Precedence{
+	token.OR:	    OR,
+	token.AND:	    AND,
 	token.EQ:	    EQUALS,
 	token.NOT_EQ:	EQUALS,
 	token.LT:		LESSGREATER,
@@ -35,6 +39,17 @@ var precedences = map[token.TokenType]Precedence{
 	token.SLASH: 	PRODUCT,
 	token.LPAREN:	CALL,
 	token.LBRACKET:	INDEX,
+	token.DOT:		INDEX,
+}
+
+// PrecedenceOf returns how tightly the infix operator t binds, or LOWEST if
+// t isn't one.
+func PrecedenceOf(t token.TokenType) Precedence {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	
+	return LOWEST
 }
 
 func (p *Parser) curPrecedence() Precedence {
@@ -60,6 +75,7 @@ type Parser struct {
 	peekToken token.Token
 	
 	errors []string
+	errorList []Error
 	
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -92,6 +108,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -100,31 +118,50 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 	
 	return p
 }
 
+// An Error is a parse error with the position of the token it is about.
+type Error struct {
+	Line, Column int
+	Message      string
+}
+
 func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// ErrorList returns the same errors as Errors, with their positions.
+func (p *Parser) ErrorList() []Error {
+	return p.errorList
+}
+
+func (p *Parser) errorAt(tok token.Token, msg string) {
+	p.errors = append(p.errors, msg)
+	p.errorList = append(p.errorList, Error{Line: tok.Line, Column: tok.Column, Message: msg})
+}
+
 func (p *Parser) PeekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got '%s' instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken, msg)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, msg)
 }
 
 func (p *Parser) noInfixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no infix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, msg)
 }
 
 
@@ -150,10 +187,33 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
-		return p.parseLetStatement()
+	case token.LET, token.CONST:
+		// a nil *ast.LetStatement would not be a nil ast.Statement
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.FUNCTION:
+		if !p.peekTokenIs(token.IDENT) {
+			return p.parseExpressionStatement()
+		}
+		if stmt := p.parseFunctionDeclaration(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.IDENT:
+		// "test" is not a keyword, so it stays available as an identifier
+		// unless a test name follows it.
+		if p.curToken.Literal == "test" && p.peekTokenIs(token.STRING) {
+			return p.parseTestStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -162,11 +222,10 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 	
-	if !p.expectPeek(token.IDENT) {
+	stmt.Name = p.parsePattern(false, false)
+	if stmt.Name == nil {
 		return nil
 	}
-	
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -197,6 +256,92 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseTestStatement() ast.Statement {
+	stmt := &ast.TestStatement{Token: p.curToken}
+	
+	p.nextToken()
+	stmt.Name = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+	
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	
+	// "as" is not a keyword, so it stays available as an identifier elsewhere.
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "as" {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	
+	return stmt
+}
+
+// parseFunctionDeclaration parses fn name() {}, which binds name to the
+// function like a let statement.
+func (p *Parser) parseFunctionDeclaration() *ast.LetStatement {
+	stmt := &ast.LetStatement{Token: p.curToken}
+	
+	lit, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok {
+		return nil
+	}
+	stmt.Name, stmt.Value = lit.Name, lit
+	
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	
+	return stmt
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+	
+	if p.peekTokenIs(token.FUNCTION) {
+		p.nextToken()
+		if !p.peekTokenIs(token.IDENT) {
+			p.PeekError(token.IDENT)
+			return nil
+		}
+		stmt.Statement = p.parseFunctionDeclaration()
+	} else {
+		if p.peekTokenIs(token.CONST) {
+			p.nextToken()
+		} else if !p.expectPeek(token.LET) {
+			return nil
+		}
+		stmt.Statement = p.parseLetStatement()
+	}
+	if stmt.Statement == nil {
+		return nil
+	}
+	
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 	
@@ -217,6 +362,9 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	
 	p.nextToken()
 	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
 	
 	return expression
 }
@@ -231,6 +379,9 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	precedence := p.curPrecedence()
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
 	
 	return expression
 }
@@ -244,7 +395,9 @@ func (p *Parser) parseExpression(precedence Precedence) ast.Expression {
 	
 	leftExp := prefix()
 	
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	// a failed operand leaves nothing to build on, and a nil node in the
+	// tree would break everything that walks it later
+	for leftExp != nil && !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			p.noInfixParseFnError(p.curToken.Type)
@@ -280,7 +433,7 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 	
-	if !p.expectPeek(token.RPAREN) {
+	if expression.Condition == nil || !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 	
@@ -306,19 +459,37 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseExpressionList(token.RPAREN, true)
+	if exp.Arguments == nil {
+		return nil
+	}
+	exp.Close = p.curToken
 	return exp
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 	
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 	
 	// Parse parameters
-	lit.Parameters = p.parseFunctionParameters()
+	lit.Parameters, lit.Rest = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return nil
+	}
+	
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		lit.ReturnType = p.parseType()
+	}
 	
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -329,42 +500,431 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
 	
-	if p.peekTokenIs(token.RPAREN) {
-		p.nextToken()
-		return identifiers
+	if !p.expectPeek(token.LPAREN) {
+		return nil
 	}
 	
-	p.nextToken()
+	params, rest := p.parseFunctionParameters()
+	if params == nil {
+		return nil
+	}
+	if rest != nil {
+		p.errorAt(rest.Token, "macros cannot have rest parameters")
+		return nil
+	}
+	lit.Parameters = []*ast.Identifier{}
+	for _, param := range params {
+		ident, ok := param.(*ast.Identifier)
+		if !ok {
+			tok, _ := ast.TokenOf(param)
+			p.errorAt(tok, "macro parameters cannot be patterns")
+			return nil
+		}
+		if ident.Default != nil {
+			p.errorAt(ident.Token, "macro parameters cannot have defaults")
+			return nil
+		}
+		lit.Parameters = append(lit.Parameters, ident)
+	}
 	
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
 	
-	for p.peekTokenIs(token.COMMA) {
+	lit.Body = p.parseBlockStatement()
+	
+	return lit
+}
+
+// parseFunctionParameters parses parameters up to the closing paren:
+// patterns with optional defaults, and last, optionally, a rest parameter
+// written ...name. The parameters are nil if the list is malformed.
+func (p *Parser) parseFunctionParameters() ([]ast.Pattern, *ast.Identifier) {
+	params := []ast.Pattern{}
+
+	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
+		return params, nil
+	}
+
+	var rest *ast.Identifier
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if rest = p.parseRest(); rest == nil {
+				return nil, nil
+			}
+			if p.peekTokenIs(token.COMMA) {
+				p.errorAt(p.peekToken, "the rest parameter must be the last one")
+				return nil, nil
+			}
+			break
+		}
+
+		param := p.parsePattern(true, false)
+		if param == nil {
+			return nil, nil
+		}
+		if n := len(params); ast.DefaultOf(param) == nil && n > 0 && ast.DefaultOf(params[n-1]) != nil {
+			tok, _ := ast.TokenOf(param)
+			p.errorAt(tok, fmt.Sprintf("parameter %s needs a default, as it follows one with a default", param))
+			return nil, nil
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
 	}
-	
+
 	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	return params, rest
+}
+
+// parsePattern parses the pattern after the current token: a name with
+// an optional type, or an array or hash pattern. If defaults is set, the
+// pattern may be followed by = and the value it takes when missing. If
+// literals is set, as in the arms of a match, it may be a literal.
+func (p *Parser) parsePattern(defaults, literals bool) ast.Pattern {
+	var pattern ast.Pattern
+	switch {
+	case p.peekTokenIs(token.LBRACKET):
+		p.nextToken()
+		pattern = p.parseArrayPattern(literals)
+	case p.peekTokenIs(token.LBRACE):
+		p.nextToken()
+		pattern = p.parseHashPattern(literals)
+	case literals && isLiteralStart(p.peekToken.Type):
+		p.nextToken()
+		if lit := p.parseLiteral(); lit != nil {
+			// a literal is never missing, so it has no default
+			return &ast.LiteralPattern{Value: lit}
+		}
+	case p.expectPeek(token.IDENT):
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		ident.Type = p.parseTypeAnnotation()
+		pattern = ident
+	}
+	if pattern == nil {
+		return nil
+	}
+
+	if !defaults || !p.peekTokenIs(token.ASSIGN) {
+		return pattern
+	}
+	p.nextToken()
+	p.nextToken()
+	def := p.parseExpression(LOWEST)
+	if def == nil {
 		return nil
 	}
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		pattern.Default = def
+	case *ast.ArrayPattern:
+		pattern.Default = def
+	case *ast.HashPattern:
+		pattern.Default = def
+	}
+	return pattern
+}
+
+// parseArrayPattern parses [a, b, ...rest] from its opening bracket.
+func (p *Parser) parseArrayPattern(literals bool) ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken, Elements: []ast.Pattern{}}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if pattern.Rest = p.parseRest(); pattern.Rest == nil {
+				return nil
+			}
+			if p.peekTokenIs(token.COMMA) {
+				p.errorAt(p.peekToken, "the rest element must be the last one")
+				return nil
+			}
+			break
+		}
+
+		el := p.parsePattern(true, literals)
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	pattern.Close = p.curToken
+	return pattern
+}
+
+// parseHashPattern parses {name, "key": value, ...rest} from its opening
+// brace. A key is a name or a string; a name alone binds the value under
+// it to itself.
+func (p *Parser) parseHashPattern(literals bool) ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken, Pairs: []*ast.KeyPattern{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if pattern.Rest = p.parseRest(); pattern.Rest == nil {
+				return nil
+			}
+			if p.peekTokenIs(token.COMMA) {
+				p.errorAt(p.peekToken, "the rest element must be the last one")
+				return nil
+			}
+			break
+		}
+
+		p.nextToken()
+		pair := &ast.KeyPattern{}
+		switch p.curToken.Type {
+		case token.IDENT:
+			pair.Key = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		case token.STRING:
+			pair.Key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		default:
+			p.errorAt(p.curToken, fmt.Sprintf("expected a key name or string, got '%s' instead", p.curToken.Type))
+			return nil
+		}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			if pair.Value = p.parsePattern(true, literals); pair.Value == nil {
+				return nil
+			}
+		} else if key, ok := pair.Key.(*ast.Identifier); ok {
+			value := &ast.Identifier{Token: key.Token, Value: key.Value}
+			if p.peekTokenIs(token.ASSIGN) {
+				p.nextToken()
+				p.nextToken()
+				if value.Default = p.parseExpression(LOWEST); value.Default == nil {
+					return nil
+				}
+			}
+			pair.Value = value
+		} else {
+			p.errorAt(p.curToken, fmt.Sprintf("key %q needs a name to bind its value to", p.curToken.Literal))
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	pattern.Close = p.curToken
+	return pattern
+}
+
+// isLiteralStart reports whether a literal pattern starts with a token
+// of type t.
+func isLiteralStart(t token.TokenType) bool {
+	switch t {
+	case token.INT, token.MINUS, token.STRING, token.TRUE, token.FALSE:
+		return true
+	}
+	return false
+}
+
+// parseLiteral parses the literal of a literal pattern at the current
+// token. A negative number is a single literal.
+func (p *Parser) parseLiteral() ast.Expression {
+	switch p.curToken.Type {
+	case token.MINUS:
+		minus := p.curToken
+		if !p.expectPeek(token.INT) {
+			return nil
+		}
+		lit, ok := p.parseIntegerLiteral().(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+		lit.Value = -lit.Value
+		lit.Token = token.Token{Type: token.INT, Literal: "-" + lit.Token.Literal, Line: minus.Line, Column: minus.Column}
+		return lit
+	case token.INT:
+		if lit, ok := p.parseIntegerLiteral().(*ast.IntegerLiteral); ok {
+			return lit
+		}
+		return nil
+	case token.STRING:
+		return p.parseString()
+	}
+	return p.parseBoolean()
+}
+
+// parseMatchExpression parses match (value) { arm, ... }, where each arm
+// is pattern [if guard] => body.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	match := &ast.MatchExpression{Token: p.curToken, Arms: []*ast.MatchArm{}}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	if match.Value = p.parseExpression(LOWEST); match.Value == nil {
+		return nil
+	}
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		arm := &ast.MatchArm{}
+		if arm.Pattern = p.parsePattern(false, true); arm.Pattern == nil {
+			return nil
+		}
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			if arm.Guard = p.parseExpression(LOWEST); arm.Guard == nil {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.FAT_ARROW) {
+			return nil
+		}
+		p.nextToken()
+		if arm.Body = p.parseExpression(LOWEST); arm.Body == nil {
+			return nil
+		}
+		match.Arms = append(match.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	match.Close = p.curToken
+	if len(match.Arms) == 0 {
+		p.errorAt(match.Token, "match needs at least one arm")
+		return nil
+	}
+	return match
+}
+
+// parseRest parses the name after the current ... token, with its
+// optional type.
+func (p *Parser) parseRest() *ast.Identifier {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	rest := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	rest.Type = p.parseTypeAnnotation()
+	return rest
+}
+
+// parseTypeAnnotation parses the optional ": type" after a name.
+func (p *Parser) parseTypeAnnotation() ast.TypeExpression {
+	if !p.peekTokenIs(token.COLON) {
+		return nil
+	}
+	
+	p.nextToken()
+	p.nextToken()
+	return p.parseType()
+}
+
+// parseType parses a type starting at the current token: a name such as
+// int, [element], {key: value} or fn(parameters) -> result.
+func (p *Parser) parseType() ast.TypeExpression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+		
+	case token.LBRACKET:
+		typ := &ast.ArrayType{Token: p.curToken}
+		p.nextToken()
+		typ.Element = p.parseType()
+		if typ.Element == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		typ.Close = p.curToken
+		return typ
+		
+	case token.LBRACE:
+		typ := &ast.HashType{Token: p.curToken}
+		p.nextToken()
+		typ.Key = p.parseType()
+		if typ.Key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		typ.Value = p.parseType()
+		if typ.Value == nil || !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		typ.Close = p.curToken
+		return typ
+		
+	case token.FUNCTION:
+		typ := &ast.FunctionType{Token: p.curToken}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for !p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+			param := p.parseType()
+			if param == nil {
+				return nil
+			}
+			typ.Parameters = append(typ.Parameters, param)
+			if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		typ.ReturnType = p.parseType()
+		if typ.ReturnType == nil {
+			return nil
+		}
+		return typ
+	}
 	
-	return identifiers
+	p.errorAt(p.curToken, fmt.Sprintf("expected a type, got %s", p.curToken.Type))
+	return nil
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Elements = p.parseExpressionList(token.RBRACKET, false)
+	if array.Elements == nil {
+		return nil
+	}
+	array.Close = p.curToken
 	
 	return array
 }
 
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+// parseExpressionList parses expressions separated by commas up to end.
+// With spread, they may be ...array, as in the arguments of a call.
+func (p *Parser) parseExpressionList(end token.TokenType, spread bool) []ast.Expression {
 	list := []ast.Expression{}
 	
 	if p.peekTokenIs(end) {
@@ -373,12 +933,18 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 	
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement(spread))
 	
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement(spread))
+	}
+	
+	for _, exp := range list {
+		if exp == nil {
+			return nil
+		}
 	}
 	
 	if !p.expectPeek(end) {
@@ -388,16 +954,43 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+func (p *Parser) parseListElement(spread bool) ast.Expression {
+	if !spread || !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+	
+	exp := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	if exp.Value == nil {
+		return nil
+	}
+	return exp
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 	p.nextToken()
 	
 	exp.Index = p.parseExpression(LOWEST)
 	
-	if !p.expectPeek(token.RBRACKET) {
+	if exp.Index == nil || !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.Close = p.curToken
+	
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+	
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 	
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	
 	return exp
 }
 
@@ -414,6 +1007,11 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		
 		p.nextToken()
 	}
+	if !p.curTokenIs(token.RBRACE) {
+		p.errorAt(p.curToken, fmt.Sprintf("expected next token to be %s, got '%s' instead", token.RBRACE, p.curToken.Type))
+		return block
+	}
+	block.Close = p.curToken
 	
 	return block
 }
@@ -436,7 +1034,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, msg)
 		return nil
 	}
 	
@@ -452,14 +1050,18 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		key := p.parseExpression(LOWEST)
 		
-		if !p.expectPeek(token.COLON) {
+		if key == nil || !p.expectPeek(token.COLON) {
 			return nil
 		}
 		
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
 		
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 		
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -469,6 +1071,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.Close = p.curToken
 	
 	return hash
 	
@@ -68,8 +68,14 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 		return false
 	}
 	
-	if letSmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not %s, got=%s", name, letSmt.Name.Value)
+	ident, ok := letSmt.Name.(*ast.Identifier)
+	if !ok {
+		t.Errorf("letStmt.Name not *ast.Identifier. got=%T", letSmt.Name)
+		return false
+	}
+	
+	if ident.Value != name {
+		t.Errorf("letStmt.Name.Value not %s, got=%s", name, ident.Value)
 		return false
 	}
 	
@@ -385,6 +391,8 @@ func TestParsingInfixExpression(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"true && false", true, "&&", false},
+		{"false || true", false, "||", true},
 	}
 	
 	for _, tt := range prefixTests {
@@ -460,7 +468,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		},
 		{
 			"3 + 4; -5 * 5",	
-			"(3 + 4)((-5) * 5)",
+			"(3 + 4);((-5) * 5)",
 		},
 		{
 			"5 < 4 != 3 > 4",	
@@ -522,6 +530,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a || b && c || d",
+			"((a || (b && c)) || d)",
+		},
+		{
+			"a == b && c < d + 1",
+			"((a == b) && (c < (d + 1)))",
+		},
+		{
+			"!a && !b",
+			"((!a) && (!b))",
+		},
 	}
 	
 	for _, tt := range tests {
@@ -661,8 +681,8 @@ func TestFunctionLiteralParsing(t *testing.T) {
 		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(function.Parameters))
 	}
 	
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
+	testLiteralExpression(t, function.Parameters[0].(*ast.Identifier), "x")
+	testLiteralExpression(t, function.Parameters[1].(*ast.Identifier), "y")
 	
 	if len(function.Body.Statements) != 1 {
 		t.Fatalf("function.Body.Statements should contain 1 stmt, got=%d", len(function.Body.Statements))	
@@ -700,7 +720,289 @@ func TestFunctionParemeters(t *testing.T) {
 		}
 		
 		for i, ident := range tt.expectedParams {
-			testLiteralExpression(t, function.Parameters[i], ident)
+			testLiteralExpression(t, function.Parameters[i].(*ast.Identifier), ident)
+		}
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct{
+		input    string
+		defaults []string // printed default of each parameter, "" for none
+		rest     string
+	}{
+		{"fn(a, b = 10) {}", []string{"", "10"}, ""},
+		{"fn(a: int = -1, b = [a]) {}", []string{"(-1)", "[a]"}, ""},
+		{"fn(first, ...rest) {}", []string{""}, "rest"},
+		{"fn(...all: [int]) {}", []string{}, "all: [int]"},
+		{"fn(a = fn(x = 1) { x }, ...r) {}", []string{"fn(x = 1) { x }"}, "r"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if len(function.Parameters) != len(tt.defaults) {
+			t.Fatalf("wrong number of parameters for %q. want=%d, got=%d", tt.input, len(tt.defaults), len(function.Parameters))
+		}
+		for i, expected := range tt.defaults {
+			got := ""
+			if def := ast.DefaultOf(function.Parameters[i]); def != nil {
+				got = def.String()
+			}
+			if got != expected {
+				t.Errorf("wrong default of parameter %d in %q. want=%q, got=%q", i, tt.input, expected, got)
+			}
+		}
+		
+		rest := ""
+		if function.Rest != nil {
+			rest = function.Rest.String()
+		}
+		if rest != tt.rest {
+			t.Errorf("wrong rest parameter in %q. want=%q, got=%q", tt.input, tt.rest, rest)
+		}
+	}
+}
+
+func TestParameterErrors(t *testing.T) {
+	tests := []struct{
+		source   string
+		expected string
+	}{
+		{"fn(...rest, a) {}", "the rest parameter must be the last one"},
+		{"fn(a = 1, b) {}", "parameter b needs a default, as it follows one with a default"},
+		{"fn(a = ) {}", "no prefix parse function for ) found"},
+		{"fn(...) {}", "expected next token to be IDENT, got ')' instead"},
+		{"macro(...rest) {}", "macros cannot have rest parameters"},
+		{"macro(a = 1) {}", "macro parameters cannot have defaults"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.source))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.source, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	p := New(lexer.New("f(a, ...b, ...[1, 2] + c)"))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if len(call.Arguments) != 3 {
+		t.Fatalf("wrong number of arguments. want=3, got=%d", len(call.Arguments))
+	}
+	testIdentifier(t, call.Arguments[0], "a")
+	for i, expected := range []string{"b", "([1, 2] + c)"} {
+		spread, ok := call.Arguments[i+1].(*ast.SpreadExpression)
+		if !ok {
+			t.Fatalf("argument %d is not ast.SpreadExpression. got=%T", i+1, call.Arguments[i+1])
+		}
+		if spread.Value.String() != expected {
+			t.Errorf("wrong spread value. want=%q, got=%q", expected, spread.Value.String())
+		}
+	}
+	
+	// spreading is only for arguments
+	p = New(lexer.New("[...a]"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for a spread in an array literal")
+	}
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string // the program printed
+	}{
+		{"fn add(a, b) { a + b }", "fn add(a, b) { (a + b) }"},
+		{"fn f() { g() } fn g() { f() };", "fn f() { g() }fn g() { f() }"},
+		{"fn sum(first, ...others) -> int { first }", "fn sum(first, ...others) -> int { first }"},
+		{"export fn f(x) { x }", "export fn f(x) { x }"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+		for _, stmt := range program.Statements {
+			if export, ok := stmt.(*ast.ExportStatement); ok {
+				stmt = export.Statement
+			}
+			let, ok := stmt.(*ast.LetStatement)
+			if !ok || !let.IsDeclaration() {
+				t.Errorf("statement of %q is not a function declaration. got=%T", tt.input, stmt)
+			}
+		}
+	}
+	
+	// a name in an expression only names the function
+	p := New(lexer.New("let g = fn go(n) { go(n) }; fn(x) { x }(1); let f = fn f(x) { x };"))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	let := program.Statements[0].(*ast.LetStatement)
+	if let.IsDeclaration() {
+		t.Errorf("let of a function with another name should not be a declaration")
+	}
+	if same := program.Statements[2].(*ast.LetStatement); same.IsDeclaration() || same.String() != "let f = fn f(x) { x };" {
+		t.Errorf("let of a function with the same name should not be a declaration. got=%q", same.String())
+	}
+	if name := let.Value.(*ast.FunctionLiteral).Name; name == nil || name.Value != "go" {
+		t.Errorf("wrong function name. want=%q, got=%v", "go", name)
+	}
+	if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+		t.Errorf("anonymous function is not an expression statement. got=%T", program.Statements[1])
+	}
+	
+	p = New(lexer.New("export fn (x) { x }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected parse error for export of an anonymous function")
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string // the program printed
+	}{
+		{"let [a, b] = pair;", "let [a, b] = pair;"},
+		{"let [first, ...others] = list", "let [first, ...others] = list;"},
+		{"let [] = x; let {} = y;", "let [] = x;let {} = y;"},
+		{"let {name, age: years} = person;", "let {name, age: years} = person;"},
+		{`let {"full name": name, ...other} = person;`, `let {"full name": name, ...other} = person;`},
+		{"let [x = 1, {y: [z] = [2], w = 3} = {}] = v;", "let [x = 1, {y: [z] = [2], w = 3} = {}] = v;"},
+		{"let {p: {q}} = r;", "let {p: {q}} = r;"},
+		{"fn([a, b], {c} = {}, d = 1) { a }", "fn([a, b], {c} = {}, d = 1) { a }"},
+		{"fn swap([a, b]) { [b, a] }", "fn swap([a, b]) { [b, a] }"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+	
+	p := New(lexer.New("let {name, age: [years]} = person;"))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	hash, ok := program.Statements[0].(*ast.LetStatement).Name.(*ast.HashPattern)
+	if !ok {
+		t.Fatalf("let name is not *ast.HashPattern. got=%T", program.Statements[0].(*ast.LetStatement).Name)
+	}
+	if len(hash.Pairs) != 2 {
+		t.Fatalf("wrong number of pairs. want=2, got=%d", len(hash.Pairs))
+	}
+	testIdentifier(t, hash.Pairs[0].Key, "name")
+	testIdentifier(t, hash.Pairs[0].Value.(*ast.Identifier), "name")
+	if _, ok := hash.Pairs[1].Value.(*ast.ArrayPattern); !ok {
+		t.Errorf("value of age is not *ast.ArrayPattern. got=%T", hash.Pairs[1].Value)
+	}
+}
+
+func TestPatternErrors(t *testing.T) {
+	tests := []struct{
+		source   string
+		expected string
+	}{
+		{"let [a, ...b, c] = x;", "the rest element must be the last one"},
+		{"let {...b, c} = x;", "the rest element must be the last one"},
+		{"let [a b] = x;", "expected next token to be ,, got 'IDENT' instead"},
+		{"let [1] = x;", "expected next token to be IDENT, got 'INT' instead"},
+		{"let {1: a} = x;", "expected a key name or string, got 'INT' instead"},
+		{`let {"a"} = x;`, `key "a" needs a name to bind its value to`},
+		{"let [a] = 1 = x;", "no prefix parse function for = found"},
+		{"fn([a] = 1, b) {}", "parameter b needs a default, as it follows one with a default"},
+		{"macro([a]) {}", "macro parameters cannot be patterns"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.source))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.source, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string // the program printed
+	}{
+		{"match (x) { 1 => a, _ => b }", "match (x) { 1 => a, _ => b }"},
+		{"match (x) { -1 => a, \"s\" => b, true => c, }", "match (x) { -1 => a, \"s\" => b, true => c }"},
+		{"match (x) { [1, y, ...z] => y, {k: 0, v} => v }", "match (x) { [1, y, ...z] => y, {k: 0, v} => v }"},
+		{"match (x) { n if n > 1 => n * 2, n => n }", "match (x) { n if (n > 1) => (n * 2), n => n }"},
+		{"match (f(x)) { [a = 1] => a } + 1", "(match (f(x)) { [a = 1] => a } + 1)"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+	
+	p := New(lexer.New("match (x) { -5 if y => 1 }"))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	match, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("expression is not *ast.MatchExpression. got=%T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+	if len(match.Arms) != 1 {
+		t.Fatalf("wrong number of arms. want=1, got=%d", len(match.Arms))
+	}
+	lit, ok := match.Arms[0].Pattern.(*ast.LiteralPattern)
+	if !ok {
+		t.Fatalf("pattern is not *ast.LiteralPattern. got=%T", match.Arms[0].Pattern)
+	}
+	testIntegerLiteral(t, lit.Value, -5)
+	testIdentifier(t, match.Arms[0].Guard, "y")
+	testIntegerLiteral(t, match.Arms[0].Body, 1)
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct{
+		source   string
+		expected string
+	}{
+		{"match (x) {}", "match needs at least one arm"},
+		{"match x { _ => 1 }", "expected next token to be (, got 'IDENT' instead"},
+		{"match (x) { _ 1 }", "expected next token to be =>, got 'INT' instead"},
+		{"match (x) { a => 1 b => 2 }", "expected next token to be ,, got 'IDENT' instead"},
+		{"match (x) { 1 = 2 => 1 }", "expected next token to be =>, got '=' instead"},
+		{"match (x) { - a => 1 }", "expected next token to be INT, got 'IDENT' instead"},
+		{"let 1 = x;", "expected next token to be IDENT, got 'INT' instead"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.source))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.source, tt.expected, p.Errors())
 		}
 	}
 }
@@ -818,11 +1120,40 @@ func TestParsingHashLiteralsStrings(t *testing.T) {
 			t.Errorf("key is not ast.StringLiteral, got=%T", key)
 		}
 		
-		expectedValue := expected[literal.String()]
+		expectedValue := expected[literal.Value]
 		testIntegerLiteral(t, value, expectedValue)
 	}
 }
 
+func TestParsingHashLiteralKeepsKeyOrder(t *testing.T) {
+	input := `{"b": 1, "a": 2, "c": 3}`
+	
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+	
+	expected := []string{"b", "a", "c"}
+	if len(hash.Keys) != len(expected) {
+		t.Fatalf("hash.Keys has wrong length, got=%d", len(hash.Keys))
+	}
+	for i, key := range hash.Keys {
+		if key.(*ast.StringLiteral).Value != expected[i] {
+			t.Errorf("hash.Keys[%d] wrong. want=%q, got=%q", i, expected[i], key.String())
+		}
+	}
+	
+	if hash.String() != `{"b":1, "a":2, "c":3}` {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
+}
+
 
 func TestParsingEmptyHashLiteral(t *testing.T) {
 	input := "{}"
@@ -842,3 +1173,339 @@ func TestParsingEmptyHashLiteral(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 }
+
+func TestImportStatement(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedPath  string
+		expectedAlias string
+		expectedString string
+	}{
+		{`import "lib";`, "lib", "", `import "lib";`},
+		{`import "path/to/lib" as l`, "path/to/lib", "l", `import "path/to/lib" as l;`},
+	}
+	
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements should contain 1 statement. got=%d", len(program.Statements))
+		}
+		
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		
+		if stmt.Path.Value != tt.expectedPath {
+			t.Errorf("stmt.Path.Value not %q. got=%q", tt.expectedPath, stmt.Path.Value)
+		}
+		
+		if tt.expectedAlias == "" {
+			if stmt.Alias != nil {
+				t.Errorf("stmt.Alias not nil. got=%q", stmt.Alias.Value)
+			}
+		} else if !testIdentifier(t, stmt.Alias, tt.expectedAlias) {
+			return
+		}
+		
+		if stmt.String() != tt.expectedString {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expectedString, stmt.String())
+		}
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	input := "export let answer = 42;"
+	
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	stmt, ok := program.Statements[0].(*ast.ExportStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ExportStatement. got=%T", program.Statements[0])
+	}
+	
+	if !testLetStatement(t, stmt.Statement, "answer") {
+		return
+	}
+	testLiteralExpression(t, stmt.Statement.Value, 42)
+	
+	p = New(lexer.New("export 42;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected parse error for export without let")
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string // the program printed
+	}{
+		{"const x = 5", "const x = 5;"},
+		{"const [a, ...b] = c; const {d} = e;", "const [a, ...b] = c;const {d} = e;"},
+		{"const f = fn f() { f() };", "const f = fn f() { f() };"},
+		{"export const answer: int = 42;", "export const answer: int = 42;"},
+	}
+	
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+		for _, stmt := range program.Statements {
+			if export, ok := stmt.(*ast.ExportStatement); ok {
+				stmt = export.Statement
+			}
+			let := stmt.(*ast.LetStatement)
+			if !let.IsConst() {
+				t.Errorf("%q is not const", let)
+			}
+			if let.IsDeclaration() {
+				t.Errorf("%q is a function declaration", let)
+			}
+		}
+	}
+	
+	p := New(lexer.New("const = 1;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be IDENT, got '=' instead" {
+		t.Errorf("wrong errors for a const without a name. got=%q", p.Errors())
+	}
+}
+
+func TestTestStatement(t *testing.T) {
+	input := `test "adds numbers" { let x = 1; x + 1 }`
+	
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements should contain 1 statement. got=%d", len(program.Statements))
+	}
+	
+	stmt, ok := program.Statements[0].(*ast.TestStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.TestStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "adds numbers" {
+		t.Errorf("stmt.Name.Value not %q. got=%q", "adds numbers", stmt.Name.Value)
+	}
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("stmt.Body should contain 2 statements. got=%d", len(stmt.Body.Statements))
+	}
+	if !testLetStatement(t, stmt.Body.Statements[0], "x") {
+		return
+	}
+	
+	// test is not a keyword
+	p = New(lexer.New("let test = 1; test + 1"))
+	program = p.ParseProgram()
+	checkParseErrors(t, p)
+	if program.String() != "let test = 1;(test + 1)" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+	
+	p = New(lexer.New(`test "missing body"`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected parse error for test without a body")
+	}
+}
+
+func TestMemberExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"lib.answer", "(lib.answer)"},
+		{"lib.add(1, 2)", "(lib.add)(1, 2)"},
+		{"a.b.c", "((a.b).c)"},
+		{"-lib.x", "(-(lib.x))"},
+		{"lib.list[0]", "((lib.list)[0])"},
+	}
+	
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+	
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+	
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+	
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d", len(macro.Parameters))
+	}
+	
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+	
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d", len(macro.Body.Statements))
+	}
+	
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+	
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let xs: [string] = [];", "let xs: [string] = [];"},
+		{`let h: {string: [int]} = {};`, "let h: {string: [int]} = {};"},
+		{"let f: fn(int, string) -> bool = g;", "let f: fn(int, string) -> bool = g;"},
+		{"let f: fn() -> fn(int) -> int = g;", "let f: fn() -> fn(int) -> int = g;"},
+		{"fn(a: string, b: [int]) -> bool { true }", "fn(a: string, b: [int]) -> bool { true }"},
+		{"fn(a, b: int) { a }", "fn(a, b: int) { a }"},
+		{"fn() -> any { 1 }", "fn() -> any { 1 }"},
+	}
+	
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+	
+	program := New(lexer.New("let x: int = 5;")).ParseProgram()
+	name := program.Statements[0].(*ast.LetStatement).Name.(*ast.Identifier)
+	if name.Value != "x" {
+		t.Errorf("name.Value not %q. got=%q", "x", name.Value)
+	}
+	if typ, ok := name.Type.(*ast.NamedType); !ok || typ.Name != "int" {
+		t.Errorf("name.Type not int. got=%v", name.Type)
+	}
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	tests := []string{
+		"let x: = 5;",
+		"let x: [int = 5;",
+		"let f: fn(int) = g;",
+		"fn(a: 5) { a }",
+	}
+	
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parse errors for %q", input)
+		}
+	}
+}
+
+func TestErrorsLeaveNoNilNodes(t *testing.T) {
+	tests := []string{
+		"-",
+		"!;",
+		"1 +",
+		"if () { 1 }",
+		"add(1, )",
+		"[1, ]",
+		"a[]",
+		`{"a": }`,
+		"{: 1}",
+		"fn(1) { 1 }",
+		"macro(a, ) { a }",
+		"if (true) { 1",
+		"let f = fn(x) { x",
+		"test \"t\" { 1",
+	}
+	
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parse errors for %q", input)
+		}
+		// String panics on nil nodes
+		_ = program.String()
+	}
+}
+
+func TestUnclosedBlock(t *testing.T) {
+	p := New(lexer.New("let f = fn(x) {\n  x"))
+	p.ParseProgram()
+
+	expected := []Error{{Line: 2, Column: 4, Message: "expected next token to be }, got 'EOF' instead"}}
+	errors := p.ErrorList()
+	if len(errors) != 1 || errors[0] != expected[0] {
+		t.Errorf("wrong errors. expected=%+v, got=%+v", expected, errors)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	source := `let x = 5;
+let = 10;
+let y: = 1;`
+
+	p := New(lexer.New(source))
+	p.ParseProgram()
+
+	expected := []Error{
+		{Line: 2, Column: 5, Message: "expected next token to be IDENT, got '=' instead"},
+		{Line: 2, Column: 5, Message: "no prefix parse function for = found"},
+		{Line: 3, Column: 8, Message: "expected a type, got ="},
+		{Line: 3, Column: 10, Message: "expected next token to be =, got 'INT' instead"},
+	}
+	errors := p.ErrorList()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%v)", len(expected), len(errors), errors)
+	}
+	for i, e := range expected {
+		if errors[i] != e {
+			t.Errorf("errors[%d] wrong. expected=%+v, got=%+v", i, e, errors[i])
+		}
+		if p.Errors()[i] != e.Message {
+			t.Errorf("Errors()[%d] wrong. expected=%q, got=%q", i, e.Message, p.Errors()[i])
+		}
+	}
+}
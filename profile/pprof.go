@@ -0,0 +1,160 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format read
+// by go tool pprof. Its frames are Monkey functions and lines, not those
+// of the interpreter.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	strings := []string{""}
+	stringIndex := map[string]int64{"": 0}
+	str := func(s string) int64 {
+		i, ok := stringIndex[s]
+		if !ok {
+			i = int64(len(strings))
+			strings = append(strings, s)
+			stringIndex[s] = i
+		}
+		return i
+	}
+
+	var b protobuf
+	for i := range valueNames {
+		b.message(1, func(b *protobuf) { // sample_type
+			b.int64(1, str(valueNames[i]))
+			b.int64(2, str(valueUnits[i]))
+		})
+	}
+
+	functionIDs := map[*Function]uint64{}
+	functions := []*Function{}
+	locationIDs := map[location]uint64{}
+	locations := []location{}
+	for _, s := range p.Samples() {
+		ids := []uint64{}
+		for _, f := range s.Stack {
+			loc := location{f.Function, f.Line}
+			id, ok := locationIDs[loc]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locationIDs[loc] = id
+				locations = append(locations, loc)
+			}
+			ids = append(ids, id)
+
+			if _, ok := functionIDs[f.Function]; !ok {
+				functionIDs[f.Function] = uint64(len(functions) + 1)
+				functions = append(functions, f.Function)
+			}
+		}
+
+		b.message(2, func(b *protobuf) { // sample
+			b.packedUint64(1, ids)
+			b.packedInt64(2, s.Values[:])
+		})
+	}
+
+	for i, loc := range locations {
+		b.message(4, func(b *protobuf) { // location
+			b.uint64(1, uint64(i+1))
+			b.message(4, func(b *protobuf) { // line
+				b.uint64(1, functionIDs[loc.fn])
+				b.int64(2, int64(loc.line))
+			})
+		})
+	}
+
+	for i, fn := range functions {
+		b.message(5, func(b *protobuf) { // function
+			b.uint64(1, uint64(i+1))
+			b.int64(2, str(fn.Name))
+			b.int64(3, str(fn.Name))
+			b.int64(4, str(fn.File))
+			b.int64(5, int64(fn.Line))
+		})
+	}
+
+	b.int64(9, p.Start.UnixNano())    // time_nanos
+	b.int64(10, int64(p.Duration))    // duration_nanos
+	b.message(11, func(b *protobuf) { // period_type
+		b.int64(1, str(valueNames[Wall]))
+		b.int64(2, str(valueUnits[Wall]))
+	})
+	b.int64(12, 1)                     // period
+	b.int64(14, str(valueNames[Wall])) // default_sample_type
+
+	// The string table goes last, once everything has been named.
+	for _, s := range strings {
+		b.string(6, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protobuf encodes the few kinds of field a profile needs.
+type protobuf struct {
+	buf []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *protobuf) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.buf = append(b.buf, data...)
+}
+
+func (b *protobuf) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protobuf) packedUint64(field int, xs []uint64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytes(field, packed.buf)
+}
+
+func (b *protobuf) packedInt64(field int, xs []int64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	b.bytes(field, packed.buf)
+}
+
+func (b *protobuf) message(field int, encode func(b *protobuf)) {
+	var m protobuf
+	encode(&m)
+	b.bytes(field, m.buf)
+}
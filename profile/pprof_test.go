@@ -0,0 +1,166 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type field struct {
+	num    int
+	varint uint64
+	data   []byte
+}
+
+// decode splits a protocol buffer message into its fields.
+func decode(t *testing.T, data []byte) []field {
+	t.Helper()
+
+	fields := []field{}
+	for len(data) > 0 {
+		key, n := readVarint(data)
+		data = data[n:]
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.varint, n = readVarint(data)
+			data = data[n:]
+		case 2:
+			length, n := readVarint(data)
+			data = data[n:]
+			f.data, data = data[:length], data[length:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func readVarint(data []byte) (uint64, int) {
+	var x uint64
+	for i, b := range data {
+		x |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return x, i + 1
+		}
+	}
+	return x, len(data)
+}
+
+func packed(data []byte) []uint64 {
+	xs := []uint64{}
+	for len(data) > 0 {
+		x, n := readVarint(data)
+		xs = append(xs, x)
+		data = data[n:]
+	}
+	return xs
+}
+
+func TestWritePprof(t *testing.T) {
+	var out bytes.Buffer
+	if err := recursiveProfile().WritePprof(&out); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("profile is not gzipped: %s", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strings := []string{}
+	var sampleTypes, samples, locations, functions [][]byte
+	var defaultType uint64
+	for _, f := range decode(t, data) {
+		switch f.num {
+		case 1:
+			sampleTypes = append(sampleTypes, f.data)
+		case 2:
+			samples = append(samples, f.data)
+		case 4:
+			locations = append(locations, f.data)
+		case 5:
+			functions = append(functions, f.data)
+		case 6:
+			strings = append(strings, string(f.data))
+		case 14:
+			defaultType = f.varint
+		}
+	}
+	if len(strings) == 0 || strings[0] != "" {
+		t.Fatalf("string table must start with \"\". got=%q", strings)
+	}
+	if strings[defaultType] != "wall" {
+		t.Errorf("wrong default sample type. got=%q", strings[defaultType])
+	}
+
+	types := []string{}
+	for _, st := range sampleTypes {
+		fields := decode(t, st)
+		types = append(types, strings[fields[0].varint]+"/"+strings[fields[1].varint])
+	}
+	if fmt.Sprint(types) != "[wall/nanoseconds alloc_space/bytes alloc_objects/count]" {
+		t.Errorf("wrong sample types. got=%v", types)
+	}
+
+	names := map[uint64]string{}
+	for _, fn := range functions {
+		var id uint64
+		var name, file string
+		var line uint64
+		for _, f := range decode(t, fn) {
+			switch f.num {
+			case 1:
+				id = f.varint
+			case 2:
+				name = strings[f.varint]
+			case 4:
+				file = strings[f.varint]
+			case 5:
+				line = f.varint
+			}
+		}
+		names[id] = fmt.Sprintf("%s %s:%d", name, file, line)
+	}
+
+	lines := map[uint64]string{}
+	for _, loc := range locations {
+		var id uint64
+		var line string
+		for _, f := range decode(t, loc) {
+			switch f.num {
+			case 1:
+				id = f.varint
+			case 4:
+				l := decode(t, f.data)
+				line = fmt.Sprintf("%s@%d", names[l[0].varint], l[1].varint)
+			}
+		}
+		lines[id] = line
+	}
+
+	got := []string{}
+	for _, s := range samples {
+		fields := decode(t, s)
+		stack := []string{}
+		for _, id := range packed(fields[0].data) {
+			stack = append(stack, lines[id])
+		}
+		got = append(got, fmt.Sprint(stack, packed(fields[1].data)))
+	}
+
+	expected := []string{
+		"[main /src/a.mk:1@5] [100 2048 4]",
+		"[fib /src/a.mk:2@3 main /src/a.mk:1@5] [300 0 0]",
+		"[fib /src/a.mk:2@2 fib /src/a.mk:2@3 main /src/a.mk:1@5] [600 1024 2]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("wrong samples.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
@@ -0,0 +1,267 @@
+// Package profile measures where Monkey programs spend their time and
+// allocate memory. It follows evaluation through the evaluator's tracer
+// hook, charging wall time and allocations to the script-level call stack:
+// the user functions being run and the source lines they are on.
+//
+// Allocations are read from the Go runtime's cumulative counters, which
+// are brought up to date in batches, so they are attributed less precisely
+// than time.
+package profile
+
+import (
+	"runtime/metrics"
+	"sort"
+	"time"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// A Value is something a profile measures.
+type Value int
+
+const (
+	Wall         Value = iota // wall time in nanoseconds
+	AllocSpace                // bytes allocated
+	AllocObjects              // objects allocated
+)
+
+var valueNames = []string{"wall", "alloc_space", "alloc_objects"}
+var valueUnits = []string{"nanoseconds", "bytes", "count"}
+
+func (v Value) String() string { return valueNames[v] }
+
+// ParseValue returns the Value called name, as in the pprof sample types.
+func ParseValue(name string) (Value, bool) {
+	for i, n := range valueNames {
+		if n == name {
+			return Value(i), true
+		}
+	}
+	return 0, false
+}
+
+// A Function is a user function, or the top level of a file, called
+// "main".
+type Function struct {
+	Name string
+	File string
+	Line int // where the function starts
+}
+
+type functionKey struct {
+	name, file string
+	line       int
+}
+
+// A location is a line being run in a function.
+type location struct {
+	fn   *Function
+	line int
+}
+
+// A node is a call stack, in a tree of all the stacks seen. Its values
+// were spent with exactly that stack.
+type node struct {
+	parent   *node
+	loc      location
+	children map[location]*node
+	values   [3]int64
+}
+
+func (n *node) child(loc location) *node {
+	c, ok := n.children[loc]
+	if !ok {
+		c = &node{parent: n, loc: loc, children: map[location]*node{}}
+		n.children[loc] = c
+	}
+	return c
+}
+
+// A Profiler records a profile of the programs it runs.
+type Profiler struct {
+	root      *node
+	stack     []*node // the current stack, outermost first
+	functions map[functionKey]*Function
+
+	metrics  []metrics.Sample
+	last     time.Time
+	lastUsed [2]uint64 // the allocation counters when last charged
+
+	Start    time.Time
+	Duration time.Duration
+}
+
+func New() *Profiler {
+	return &Profiler{
+		root:      &node{children: map[location]*node{}},
+		functions: map[functionKey]*Function{},
+		metrics: []metrics.Sample{
+			{Name: "/gc/heap/allocs:bytes"},
+			{Name: "/gc/heap/allocs:objects"},
+		},
+	}
+}
+
+// Run calls eval, which evaluates a program, while profiling it, and
+// returns its result. A Profiler can run several programs, adding them
+// to one profile.
+func (p *Profiler) Run(eval func() object.Object) object.Object {
+	if p.Start.IsZero() {
+		p.Start = time.Now()
+	}
+	p.stack = p.stack[:0]
+	p.last, p.lastUsed = time.Now(), p.allocated()
+	started := p.last
+
+	evaluator.SetTracer(p)
+	result := eval()
+	evaluator.SetTracer(nil)
+
+	p.charge()
+	p.Duration += time.Since(started)
+	return result
+}
+
+func (p *Profiler) allocated() [2]uint64 {
+	metrics.Read(p.metrics)
+	var used [2]uint64
+	for i, s := range p.metrics {
+		if s.Value.Kind() == metrics.KindUint64 {
+			used[i] = s.Value.Uint64()
+		}
+	}
+	return used
+}
+
+// charge adds what was spent since it was last called to the current
+// stack.
+func (p *Profiler) charge() {
+	now, used := time.Now(), p.allocated()
+	if len(p.stack) > 0 {
+		n := p.stack[len(p.stack)-1]
+		n.values[Wall] += int64(now.Sub(p.last))
+		n.values[AllocSpace] += int64(used[0] - p.lastUsed[0])
+		n.values[AllocObjects] += int64(used[1] - p.lastUsed[1])
+	}
+	p.last, p.lastUsed = now, used
+}
+
+func (p *Profiler) function(name, file string, line int) *Function {
+	key := functionKey{name, file, line}
+	fn, ok := p.functions[key]
+	if !ok {
+		fn = &Function{Name: name, File: file, Line: line}
+		p.functions[key] = fn
+	}
+	return fn
+}
+
+// moveTo sets the line of the innermost frame.
+func (p *Profiler) moveTo(fn *Function, line int) {
+	top := len(p.stack) - 1
+	parent := p.root
+	if top > 0 {
+		parent = p.stack[top-1]
+	}
+	p.stack[top] = parent.child(location{fn, line})
+}
+
+// Step implements evaluator.Tracer.
+func (p *Profiler) Step(node ast.Node, env *object.Environment) {
+	tok, ok := ast.TokenOf(node)
+	if !ok {
+		return
+	}
+	p.charge()
+
+	if len(p.stack) == 0 {
+		p.stack = append(p.stack, nil)
+		p.moveTo(p.function("main", env.File(), 1), tok.Line)
+		return
+	}
+
+	fn := p.stack[len(p.stack)-1].loc.fn
+	if len(p.stack) == 1 && fn.File != env.File() {
+		// the top level of an imported module
+		fn = p.function("main", env.File(), 1)
+	}
+	if p.stack[len(p.stack)-1].loc != (location{fn, tok.Line}) {
+		p.moveTo(fn, tok.Line)
+	}
+}
+
+// Enter implements evaluator.Tracer.
+func (p *Profiler) Enter(call *ast.CallExpression, fn *object.Function) {
+	p.charge()
+	if len(p.stack) == 0 {
+		return
+	}
+
+	// A function called back by a builtin is called from the line the
+	// caller is already on.
+	if call != nil {
+		if tok, ok := ast.TokenOf(call.Function); ok {
+			p.moveTo(p.stack[len(p.stack)-1].loc.fn, tok.Line)
+		}
+	}
+	callee := p.function(evaluator.CallName(call, fn), fn.Env.File(), fn.Body.Token.Line)
+	p.stack = append(p.stack, nil)
+	p.moveTo(callee, callee.Line)
+}
+
+// Leave implements evaluator.Tracer.
+func (p *Profiler) Leave(call *ast.CallExpression, result object.Object) {
+	p.charge()
+	if len(p.stack) > 1 {
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+}
+
+// A Sample is what was spent with one call stack.
+type Sample struct {
+	Stack  []Frame // innermost first
+	Values [3]int64
+}
+
+// A Frame is a line being run in a function.
+type Frame struct {
+	Function *Function
+	Line     int
+}
+
+// Samples returns what was spent with each call stack, in a fixed order.
+func (p *Profiler) Samples() []Sample {
+	samples := []Sample{}
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.values != [3]int64{} {
+			s := Sample{Values: n.values}
+			for m := n; m != p.root; m = m.parent {
+				s.Stack = append(s.Stack, Frame{m.loc.fn, m.loc.line})
+			}
+			samples = append(samples, s)
+		}
+		children := make([]*node, 0, len(n.children))
+		for _, c := range n.children {
+			children = append(children, c)
+		}
+		sort.Slice(children, func(i, j int) bool {
+			a, b := children[i].loc, children[j].loc
+			if a.fn.File != b.fn.File {
+				return a.fn.File < b.fn.File
+			}
+			if a.line != b.line {
+				return a.line < b.line
+			}
+			return a.fn.Name < b.fn.Name
+		})
+		for _, c := range children {
+			walk(c)
+		}
+	}
+	walk(p.root)
+	return samples
+}
+
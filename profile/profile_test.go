@@ -0,0 +1,152 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+func writeProgram(t *testing.T, source string) string {
+	path := filepath.Join(t.TempDir(), "main.mk")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// stackString shows a stack outermost first, as function:line.
+func stackString(stack []Frame) string {
+	s := ""
+	for i := len(stack) - 1; i >= 0; i-- {
+		if s != "" {
+			s += " > "
+		}
+		s += stack[i].Function.Name + ":" + strconv.Itoa(stack[i].Line)
+	}
+	return s
+}
+
+func TestProfileStacks(t *testing.T) {
+	path := writeProgram(t, `let square = fn(x) {
+  x * x
+};
+let twice = fn(f, x) { f(f(x)) };
+let a = twice(square, 3);
+a + 1;
+`)
+
+	p := New()
+	result := p.Run(func() object.Object { return evaluator.EvalFile(path) })
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 82 {
+		t.Fatalf("wrong result. got=%+v", result)
+	}
+
+	stacks := map[string]bool{}
+	var total int64
+	for _, s := range p.Samples() {
+		stacks[stackString(s.Stack)] = true
+		total += s.Values[Wall]
+		if s.Stack[len(s.Stack)-1].Function.File != path {
+			t.Errorf("wrong file for main. got=%q", s.Stack[len(s.Stack)-1].Function.File)
+		}
+	}
+
+	for _, expected := range []string{
+		"main:1",
+		"main:5 > twice:4",
+		"main:5 > twice:4 > f:2",
+		"main:6",
+	} {
+		if !stacks[expected] {
+			t.Errorf("stack %q was not sampled. got=%v", expected, stacks)
+		}
+	}
+	if total <= 0 || total > int64(p.Duration) {
+		t.Errorf("wall time not accounted for. total=%d, duration=%d", total, p.Duration)
+	}
+}
+
+func TestProfileFunctionNames(t *testing.T) {
+	path := writeProgram(t, `fn square(x) {
+  x * x
+}
+let fs = [square, fn(x) {
+  x + 1
+}];
+fs[0](3) + fs[1](3);
+`)
+
+	p := New()
+	p.Run(func() object.Object { return evaluator.EvalFile(path) })
+
+	stacks := map[string]bool{}
+	for _, s := range p.Samples() {
+		stacks[stackString(s.Stack)] = true
+	}
+	for _, expected := range []string{
+		"main:7 > square:2",
+		"main:7 > anonymous function:5",
+	} {
+		if !stacks[expected] {
+			t.Errorf("stack %q was not sampled. got=%v", expected, stacks)
+		}
+	}
+}
+
+func TestProfileBuiltinCallbacks(t *testing.T) {
+	path := writeProgram(t, `fn pred(x) {
+  x > 1
+}
+filter([1, 2, 3], pred);
+`)
+
+	p := New()
+	p.Run(func() object.Object { return evaluator.EvalFile(path) })
+
+	stacks := map[string]bool{}
+	for _, s := range p.Samples() {
+		stacks[stackString(s.Stack)] = true
+	}
+	if expected := "main:4 > pred:2"; !stacks[expected] {
+		t.Errorf("stack %q was not sampled. got=%v", expected, stacks)
+	}
+}
+
+func TestProfileModules(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.mk")
+	main := filepath.Join(dir, "main.mk")
+	os.WriteFile(lib, []byte("export let one = fn() { 1 };\nlet x = one();\n"), 0644)
+	os.WriteFile(main, []byte("import \"lib\";\nlib.one();\n"), 0644)
+
+	p := New()
+	p.Run(func() object.Object { return evaluator.EvalFile(main) })
+
+	functions := map[string]bool{}
+	for _, s := range p.Samples() {
+		for _, f := range s.Stack {
+			functions[f.Function.Name+" "+filepath.Base(f.Function.File)] = true
+		}
+	}
+	for _, expected := range []string{"main main.mk", "main lib.mk", "one lib.mk", "lib.one lib.mk"} {
+		if !functions[expected] {
+			t.Errorf("function %q not in profile. got=%v", expected, functions)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	for _, v := range []Value{Wall, AllocSpace, AllocObjects} {
+		parsed, ok := ParseValue(v.String())
+		if !ok || parsed != v {
+			t.Errorf("ParseValue(%q) wrong. got=%v, %t", v.String(), parsed, ok)
+		}
+	}
+	if _, ok := ParseValue("cpu"); ok {
+		t.Errorf("ParseValue accepted an unknown value")
+	}
+}
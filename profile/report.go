@@ -0,0 +1,118 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+type entry struct {
+	name      string
+	flat, cum int64
+}
+
+// WriteText writes a report of value like that of pprof's top command:
+// what was spent in each function, or each source line if byLine is set,
+// itself (flat) and together with what it called (cum), largest first.
+func (p *Profiler) WriteText(w io.Writer, value Value, byLine bool) error {
+	entries := map[string]*entry{}
+	var total int64
+	for _, s := range p.Samples() {
+		v := s.Values[value]
+		total += v
+
+		seen := map[string]bool{}
+		for i, f := range s.Stack {
+			name := frameName(f, byLine)
+			e, ok := entries[name]
+			if !ok {
+				e = &entry{name: name}
+				entries[name] = e
+			}
+			if i == 0 {
+				e.flat += v
+			}
+			if !seen[name] {
+				seen[name] = true
+				e.cum += v
+			}
+		}
+	}
+
+	sorted := []*entry{}
+	for _, e := range entries {
+		if e.flat != 0 || e.cum != 0 {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.flat != b.flat {
+			return a.flat > b.flat
+		}
+		if a.cum != b.cum {
+			return a.cum > b.cum
+		}
+		return a.name < b.name
+	})
+
+	if _, err := fmt.Fprintf(w, "Showing %s, total %s\n", value, formatValue(value, total)); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%10s %7s %7s %10s %7s\n", "flat", "flat%", "sum%", "cum", "cum%")
+	var sum int64
+	for _, e := range sorted {
+		sum += e.flat
+		_, err := fmt.Fprintf(w, "%10s %7s %7s %10s %7s  %s\n",
+			formatValue(value, e.flat), percent(e.flat, total), percent(sum, total),
+			formatValue(value, e.cum), percent(e.cum, total), e.name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameName names the function of f, or its line if byLine is set.
+func frameName(f Frame, byLine bool) string {
+	file := filepath.Base(f.Function.File)
+	if f.Function.File == "" {
+		file = "<input>"
+	}
+	line := f.Function.Line
+	if byLine {
+		line = f.Line
+	}
+	return fmt.Sprintf("%s %s:%d", f.Function.Name, file, line)
+}
+
+func percent(v, total int64) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.2f%%", float64(v)*100/float64(total))
+}
+
+func formatValue(value Value, v int64) string {
+	switch value {
+	case Wall:
+		return scale(v, 1000, []string{"ns", "us", "ms", "s"})
+	case AllocSpace:
+		return scale(v, 1024, []string{"B", "kB", "MB", "GB"})
+	}
+	return fmt.Sprint(v)
+}
+
+func scale(v, base int64, units []string) string {
+	if v < base {
+		return fmt.Sprintf("%d%s", v, units[0])
+	}
+	f := float64(v)
+	i := 0
+	for f >= float64(base) && i < len(units)-1 {
+		f /= float64(base)
+		i++
+	}
+	return fmt.Sprintf("%.2f%s", f, units[i])
+}
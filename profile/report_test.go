@@ -0,0 +1,83 @@
+package profile
+
+import (
+	"bytes"
+	"testing"
+)
+
+// recursiveProfile has main, at line 5, calling fib, which calls itself
+// from line 3.
+func recursiveProfile() *Profiler {
+	p := New()
+	main := p.function("main", "/src/a.mk", 1)
+	fib := p.function("fib", "/src/a.mk", 2)
+
+	top := p.root.child(location{main, 5})
+	top.values = [3]int64{100, 2048, 4}
+	call := top.child(location{fib, 3})
+	call.values = [3]int64{300, 0, 0}
+	recursive := call.child(location{fib, 2})
+	recursive.values = [3]int64{600, 1024, 2}
+	return p
+}
+
+func TestWriteText(t *testing.T) {
+	tests := []struct {
+		value    Value
+		byLine   bool
+		expected string
+	}{
+		{Wall, false, `Showing wall, total 1.00us
+      flat   flat%    sum%        cum    cum%
+     900ns  90.00%  90.00%      900ns  90.00%  fib a.mk:2
+     100ns  10.00% 100.00%     1.00us 100.00%  main a.mk:1
+`},
+		{Wall, true, `Showing wall, total 1.00us
+      flat   flat%    sum%        cum    cum%
+     600ns  60.00%  60.00%      600ns  60.00%  fib a.mk:2
+     300ns  30.00%  90.00%      900ns  90.00%  fib a.mk:3
+     100ns  10.00% 100.00%     1.00us 100.00%  main a.mk:5
+`},
+		{AllocSpace, false, `Showing alloc_space, total 3.00kB
+      flat   flat%    sum%        cum    cum%
+    2.00kB  66.67%  66.67%     3.00kB 100.00%  main a.mk:1
+    1.00kB  33.33% 100.00%     1.00kB  33.33%  fib a.mk:2
+`},
+		{AllocObjects, false, `Showing alloc_objects, total 6
+      flat   flat%    sum%        cum    cum%
+         4  66.67%  66.67%          6 100.00%  main a.mk:1
+         2  33.33% 100.00%          2  33.33%  fib a.mk:2
+`},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := recursiveProfile().WriteText(&out, tt.value, tt.byLine); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("wrong %s report (by line %t). expected:\n%s\ngot:\n%s", tt.value, tt.byLine, tt.expected, out.String())
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    Value
+		v        int64
+		expected string
+	}{
+		{Wall, 999, "999ns"},
+		{Wall, 1500, "1.50us"},
+		{Wall, 2500000000, "2.50s"},
+		{AllocSpace, 1023, "1023B"},
+		{AllocSpace, 3 * 1024 * 1024, "3.00MB"},
+		{AllocObjects, 123456, "123456"},
+	}
+
+	for _, tt := range tests {
+		if got := formatValue(tt.value, tt.v); got != tt.expected {
+			t.Errorf("formatValue(%s, %d) wrong. expected=%q, got=%q", tt.value, tt.v, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package lexer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/debugger"
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+const DEBUG_PROMPT = "(debug) "
+
+const debugHelp = `commands:
+  break [file:]line   stop at line (b)
+  delete [file:]line  remove a breakpoint
+  breakpoints         list breakpoints
+  continue            run to the next breakpoint (c)
+  step                run to the next statement, entering calls (s)
+  next                run to the next statement in this function (n)
+  out                 run until this function returns (finish)
+  bt                  show the call stack
+  print name          show a variable (p)
+  env                 show the environments in scope, innermost first
+  list                show the source around the current line (l)
+  quit                stop debugging and let the program finish (q)
+`
+
+// A debugSession is a command-line debugger driving one program.
+type debugSession struct {
+	d       *debugger.Debugger
+	scanner *bufio.Scanner
+	out     io.Writer
+
+	file    string              // where breakpoints without a file go
+	sources map[string][]string // source lines by file, "" for REPL input
+}
+
+func newDebugSession(scanner *bufio.Scanner, out io.Writer, file, source string) *debugSession {
+	s := &debugSession{
+		d:       debugger.New(),
+		scanner: scanner,
+		out:     out,
+		file:    file,
+		sources: map[string][]string{},
+	}
+	if source != "" {
+		s.sources[file] = strings.Split(source, "\n")
+	}
+	return s
+}
+
+// DebugFile runs the program at path under the command-line debugger,
+// reading commands from in. It stops before the first statement so that
+// breakpoints can be set.
+func DebugFile(path string, in io.Reader, out io.Writer) int {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	source, err := os.ReadFile(abs)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+
+	s := newDebugSession(bufio.NewScanner(in), out, abs, string(source))
+	evaluated := s.run(func() object.Object { return evaluator.EvalFile(abs) })
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(out, errObj.Inspect())
+		return 1
+	}
+	return 0
+}
+
+// run evaluates the program under the debugger and returns its result.
+func (s *debugSession) run(eval func() object.Object) object.Object {
+	s.d.Start(eval, true)
+	for ev := range s.d.Events() {
+		if ev.Exited() {
+			return ev.Result
+		}
+		s.stoppedAt(ev.Reason)
+		s.commands()
+	}
+	return nil
+}
+
+// commands reads commands until one of them resumes the program.
+func (s *debugSession) commands() {
+	for {
+		io.WriteString(s.out, DEBUG_PROMPT)
+		if !s.scanner.Scan() {
+			s.d.Detach()
+			return
+		}
+
+		fields := strings.Fields(s.scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		arg := ""
+		if len(fields) > 1 {
+			arg = fields[1]
+		}
+
+		switch fields[0] {
+		case "continue", "c":
+			s.d.Continue()
+			return
+		case "step", "s":
+			s.d.StepIn()
+			return
+		case "next", "n":
+			s.d.StepOver()
+			return
+		case "out", "finish":
+			s.d.StepOut()
+			return
+		case "quit", "q":
+			s.d.Detach()
+			return
+		case "break", "b":
+			s.setBreakpoint(arg, true)
+		case "delete":
+			s.setBreakpoint(arg, false)
+		case "breakpoints":
+			s.listBreakpoints()
+		case "bt", "backtrace":
+			s.backtrace()
+		case "print", "p":
+			s.print(arg)
+		case "env":
+			s.env()
+		case "list", "l":
+			s.list()
+		case "help", "h":
+			io.WriteString(s.out, debugHelp)
+		default:
+			fmt.Fprintf(s.out, "unknown command %q, type help for a list\n", fields[0])
+		}
+	}
+}
+
+func (s *debugSession) top() debugger.Frame {
+	return s.d.Stack()[0]
+}
+
+// frameSource returns the source lines of the innermost frame. Functions
+// called from REPL input may have been typed in earlier, so only the top
+// level of the input is known.
+func (s *debugSession) frameSource() (debugger.Frame, []string) {
+	stack := s.d.Stack()
+	f := stack[0]
+	if f.File == "" && len(stack) > 1 {
+		return f, nil
+	}
+
+	lines, ok := s.sources[f.File]
+	if !ok && f.File != "" {
+		if source, err := os.ReadFile(f.File); err == nil {
+			lines = strings.Split(string(source), "\n")
+			s.sources[f.File] = lines
+		}
+	}
+	return f, lines
+}
+
+func (s *debugSession) stoppedAt(reason string) {
+	f, lines := s.frameSource()
+	fmt.Fprintf(s.out, "stopped at %s:%d in %s (%s)\n", s.display(f.File), f.Line, f.Name, reason)
+	if f.Line >= 1 && f.Line <= len(lines) {
+		fmt.Fprintf(s.out, "%5d\t%s\n", f.Line, lines[f.Line-1])
+	}
+}
+
+// display shortens file for messages, relative to the program being
+// debugged.
+func (s *debugSession) display(file string) string {
+	if file == "" {
+		return "<input>"
+	}
+	if rel, err := filepath.Rel(filepath.Dir(s.file), file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return file
+}
+
+// location parses a breakpoint location, [file:]line, with files relative
+// to the program being debugged.
+func (s *debugSession) location(arg string) (string, int, bool) {
+	file, line := s.file, arg
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, line = arg[:i], arg[i+1:]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(s.file), file)
+		}
+	}
+
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 1 {
+		fmt.Fprintf(s.out, "invalid location %q, want [file:]line\n", arg)
+		return "", 0, false
+	}
+	return file, n, true
+}
+
+func (s *debugSession) setBreakpoint(arg string, set bool) {
+	file, n, ok := s.location(arg)
+	if !ok {
+		return
+	}
+
+	lines := []int{}
+	for _, line := range s.d.Breakpoints(file) {
+		if line != n {
+			lines = append(lines, line)
+		}
+	}
+	if set {
+		lines = append(lines, n)
+		fmt.Fprintf(s.out, "breakpoint at %s:%d\n", s.display(file), n)
+	}
+	s.d.SetBreakpoints(file, lines)
+}
+
+func (s *debugSession) listBreakpoints() {
+	files := []string{s.file}
+	for file := range s.sources {
+		if file != s.file {
+			files = append(files, file)
+		}
+	}
+	for _, file := range files {
+		for _, line := range s.d.Breakpoints(file) {
+			fmt.Fprintf(s.out, "%s:%d\n", s.display(file), line)
+		}
+	}
+}
+
+func (s *debugSession) backtrace() {
+	for i, f := range s.d.Stack() {
+		fmt.Fprintf(s.out, "#%d %s at %s:%d:%d\n", i, f.Name, s.display(f.File), f.Line, f.Column)
+	}
+}
+
+func (s *debugSession) print(name string) {
+	value, ok := s.top().Env.Get(name)
+	if !ok {
+		fmt.Fprintf(s.out, "%s is not defined here\n", name)
+		return
+	}
+	fmt.Fprintf(s.out, "%s = %s\n", name, debugger.Describe(value))
+}
+
+func (s *debugSession) env() {
+	for _, scope := range debugger.Scopes(s.top().Env) {
+		fmt.Fprintf(s.out, "%s:\n", scope.Name)
+		for _, v := range scope.Children() {
+			fmt.Fprintf(s.out, "  %s = %s\n", v.Name, v.Describe())
+		}
+	}
+}
+
+func (s *debugSession) list() {
+	f, lines := s.frameSource()
+	if len(lines) == 0 {
+		fmt.Fprintln(s.out, "no source for this frame")
+		return
+	}
+	for n := f.Line - 3; n <= f.Line+3; n++ {
+		if n < 1 || n > len(lines) {
+			continue
+		}
+		line := lines[n-1]
+		marker := " "
+		if n == f.Line {
+			marker = ">"
+		}
+		fmt.Fprintf(s.out, "%s%4d\t%s\n", marker, n, line)
+	}
+}
@@ -0,0 +1,60 @@
+package lexer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.mk")
+	source := `let add = fn(a, b) {
+  a + b
+};
+let n = add(1, 2);
+n * 2;
+`
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := "b 2\nb x\nc\nbt\np a\np n\nenv\nfinish\np n\nbogus\nc\n"
+	var out bytes.Buffer
+	if code := DebugFile(path, strings.NewReader(commands), &out); code != 0 {
+		t.Fatalf("wrong exit code. got=%d, output:\n%s", code, out.String())
+	}
+
+	expected := `stopped at main.mk:1 in main (entry)
+    1	let add = fn(a, b) {
+(debug) breakpoint at main.mk:2
+(debug) invalid location "x", want [file:]line
+(debug) stopped at main.mk:2 in add (breakpoint)
+    2	  a + b
+(debug) #0 add at main.mk:2:3
+#1 main at main.mk:4:9
+(debug) a = 1
+(debug) n is not defined here
+(debug) Locals:
+  a = 1
+  b = 2
+Globals:
+  add = fn(a, b)
+(debug) stopped at main.mk:5 in main (step)
+    5	n * 2;
+(debug) n = 3
+(debug) unknown command "bogus", type help for a list
+(debug) `
+	if out.String() != expected {
+		t.Errorf("wrong output. expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestDebugFileError(t *testing.T) {
+	var out bytes.Buffer
+	path := filepath.Join(t.TempDir(), "missing.mk")
+	if code := DebugFile(path, strings.NewReader(""), &out); code != 1 {
+		t.Errorf("wrong exit code for a missing file. got=%d", code)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
 	"github.com/OlyaIvanovs/interpreter_in_go/parser"
@@ -16,6 +17,7 @@ const PROMPT = ">>"
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
 	
 	for {
 		fmt.Printf(PROMPT)
@@ -25,6 +27,11 @@ func Start(in io.Reader, out io.Writer) {
 		}
 		
 		line := scanner.Text()
+		debug := strings.HasPrefix(line, ":debug ")
+		if debug {
+			line = strings.TrimPrefix(line, ":debug ")
+		}
+		
 		l := lexer.New(line)
 		p := parser.New(l)
 		
@@ -34,7 +41,21 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 		
-		evaluated := evaluator.Eval(program, env)
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, errObj := evaluator.ExpandMacros(program, macroEnv)
+		if errObj != nil {
+			io.WriteString(out, errObj.Inspect())
+			io.WriteString(out, "\n")
+			continue
+		}
+		
+		var evaluated object.Object
+		if debug {
+			s := newDebugSession(scanner, out, "", line)
+			evaluated = s.run(func() object.Object { return evaluator.Eval(expanded, env) })
+		} else {
+			evaluated = evaluator.Eval(expanded, env)
+		}
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -44,6 +65,6 @@ func Start(in io.Reader, out io.Writer) {
 
 func printParseErrors(out io.Writer, errors []string) {
 	for _, msg := range errors {
-		io.WriteString(out, fmt.Sprintf("\t%\n", msg))
+		io.WriteString(out, fmt.Sprintf("\t%s\n", msg))
 	}
 }
\ No newline at end of file
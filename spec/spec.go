@@ -0,0 +1,176 @@
+// Package spec checks implementations of Monkey against a corpus of
+// programs. Each program.mk in the corpus sits next to golden files
+// holding what it should do:
+//
+//	program.stdout  what it prints
+//	program.result  the printed form of its value
+//	program.error   the message of the error it stops with
+//
+// A missing golden file means nothing is expected. Directories named lib
+// hold modules for the programs to import and are not run themselves.
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// An Outcome is what running a program did.
+type Outcome struct {
+	Stdout string
+	Result string // empty if the program has no value
+	Error  string // empty unless the program failed
+}
+
+// A Backend runs the program in the file at path.
+type Backend func(path string) Outcome
+
+// Evaluator runs programs with the tree-walking evaluator, the reference
+// implementation the golden files are made with.
+func Evaluator(path string) Outcome {
+	var stdout bytes.Buffer
+	evaluator.Output = &stdout
+	defer func() { evaluator.Output = os.Stdout }()
+
+	var outcome Outcome
+	switch result := evaluator.EvalFile(path).(type) {
+	case nil:
+	case *object.Error:
+		outcome.Error = result.Message
+	case *object.Null:
+		// the value of a program ending in a statement without one
+	default:
+		outcome.Result = result.Inspect()
+	}
+	outcome.Stdout = stdout.String()
+	return outcome
+}
+
+// Programs returns the programs of the corpus in dir, sorted.
+func Programs(dir string) ([]string, error) {
+	programs := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "lib" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && filepath.Ext(path) == evaluator.ModuleExtension {
+			programs = append(programs, path)
+		}
+		return nil
+	})
+	sort.Strings(programs)
+	return programs, err
+}
+
+type goldenFile struct {
+	path string
+	text *string
+	line bool // whether the file ends in a newline not part of the text
+}
+
+// golden lists the golden files of a program with the part of outcome
+// each holds.
+func golden(program string, outcome *Outcome) []goldenFile {
+	base := strings.TrimSuffix(program, filepath.Ext(program))
+	return []goldenFile{
+		{base + ".stdout", &outcome.Stdout, false},
+		{base + ".result", &outcome.Result, true},
+		{base + ".error", &outcome.Error, true},
+	}
+}
+
+// Expected reads the golden files of program.
+func Expected(program string) (Outcome, error) {
+	var outcome Outcome
+	for _, g := range golden(program, &outcome) {
+		data, err := os.ReadFile(g.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return outcome, err
+		}
+		*g.text = string(data)
+		if g.line {
+			*g.text = strings.TrimSuffix(*g.text, "\n")
+		}
+	}
+	return outcome, nil
+}
+
+// Update rewrites the golden files of program to match outcome, removing
+// those that expect nothing.
+func Update(program string, outcome Outcome) error {
+	for _, g := range golden(program, &outcome) {
+		text := *g.text
+		if text == "" {
+			if err := os.Remove(g.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if g.line {
+			text += "\n"
+		}
+		if err := os.WriteFile(g.path, []byte(text), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check runs each program of the corpus in dir with run as a subtest,
+// failing those whose outcome differs from their golden files.
+func Check(t *testing.T, dir string, run Backend) {
+	programs, err := Programs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(programs) == 0 {
+		t.Fatalf("no programs in %s", dir)
+	}
+
+	for _, program := range programs {
+		name, _ := filepath.Rel(dir, program)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			expected, err := Expected(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := run(program)
+			for _, diff := range compare(expected, got) {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func compare(expected, got Outcome) []string {
+	diffs := []string{}
+	parts := []struct {
+		name          string
+		expected, got string
+	}{
+		{"stdout", expected.Stdout, got.Stdout},
+		{"result", expected.Result, got.Result},
+		{"error", expected.Error, got.Error},
+	}
+	for _, p := range parts {
+		if p.expected != p.got {
+			diffs = append(diffs, fmt.Sprintf("wrong %s.\nwant=%q\ngot= %q", p.name, p.expected, p.got))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,71 @@
+package spec
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the evaluator")
+
+func TestEvaluator(t *testing.T) {
+	if *update {
+		programs, err := Programs("testdata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, program := range programs {
+			if err := Update(program, Evaluator(program)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	Check(t, "testdata", Evaluator)
+}
+
+func TestGoldenFiles(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "p.mk")
+
+	outcomes := []Outcome{
+		{Stdout: "a\nb\n", Result: "multi\nline\n"},
+		{Stdout: "no newline", Error: "failed"},
+		{},
+	}
+	for _, outcome := range outcomes {
+		if err := Update(program, outcome); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Expected(program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, outcome) {
+			t.Errorf("golden files changed the outcome.\nwant=%q\ngot= %q", outcome, got)
+		}
+	}
+
+	// nothing is expected, so no golden files are left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected the golden files to be removed, found %d", len(entries))
+	}
+}
+
+func TestPrograms(t *testing.T) {
+	programs, err := Programs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, program := range programs {
+		if filepath.Base(filepath.Dir(program)) == "lib" {
+			t.Errorf("module %s should not be run as a program", program)
+		}
+	}
+}
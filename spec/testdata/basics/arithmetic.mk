@@ -0,0 +1,8 @@
+// Integer arithmetic follows the usual precedence.
+puts(1 + 2 * 3);
+puts((1 + 2) * 3);
+puts(10 / 3);
+puts(-7 / 2);
+puts(2 - -2);
+puts(50 / 2 * 2 + 10 - 5);
+3 * (3 * 3) + 10
@@ -0,0 +1,6 @@
+puts(1 < 2, 1 > 2, 1 == 1, 1 != 1);
+puts(true == true, true != false, !true, !!5);
+puts((1 < 2) == true);
+puts("a" < "b", "abc" == "abc");
+puts([1, [2]] == [1, [2]], {"a": 1} == {"a": 1}, [1] == [2]);
+[1, 2] != [2, 1]
@@ -0,0 +1,8 @@
+let sign = fn(x) {
+  if (x > 0) { 1 } else { if (x < 0) { -1 } else { 0 } }
+};
+puts(sign(5), sign(-3), sign(0));
+
+// an if without an else and a false condition has no value
+puts(if (false) { 1 });
+if (1) { "integers are true" }
@@ -0,0 +1,15 @@
+// const binds names that cannot be bound again in the same scope. Inner
+// scopes may still use the names for their own bindings.
+const limit = 3;
+const [low, high] = [0, limit * 10];
+let clamp = fn(n) {
+  let limit = high;
+  if (n < low) { low } else { if (n > limit) { limit } else { n } }
+};
+puts(clamp(-5), clamp(12), clamp(99));
+
+// the builtins make changed copies, leaving a constant as it was
+const config = {"name": "app", "tags": ["a"]};
+let renamed = set(config, "name", "other");
+puts(config, renamed);
+[limit, low, high]
@@ -0,0 +1,5 @@
+let a = 5;
+let b = a * 2;
+let a = a + b;
+puts(a, b);
+let c = a;
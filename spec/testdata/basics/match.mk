@@ -0,0 +1,24 @@
+// match takes the first arm whose pattern fits the value and whose
+// guard, if any, holds.
+let describe = fn(value) {
+  match (value) {
+    0 => "zero",
+    "" => "empty string",
+    true => "yes",
+    [] => "empty array",
+    [x] => "one element: " + to_string(x),
+    [x, ...rest] => "starts with " + to_string(x) + " then " + to_string(len(rest)) + " more",
+    {"type": "point", x, y = 0} => "point at " + to_string(x) + "," + to_string(y),
+    {name} => "named " + name,
+    n if n < 0 => "negative",
+    _ => "something else",
+  }
+};
+puts(describe(0), describe(-4), describe(""), describe(true));
+puts(describe([]), describe([7]), describe([1, 2, 3]));
+puts(describe({"type": "point", "x": 3}), describe({"name": "ann"}), describe(5));
+
+// names bound by an arm stay in the arm
+let n = "outer";
+let fib = fn(k) { match (k) { 0 => 0, 1 => 1, k => fib(k - 1) + fib(k - 2) } };
+[fib(10), n]
@@ -0,0 +1,8 @@
+puts("before");
+if (10 > 1) {
+  if (10 > 1) {
+    return 10;
+  }
+  return 1;
+}
+puts("never printed");
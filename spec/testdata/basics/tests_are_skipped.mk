@@ -0,0 +1,8 @@
+// test blocks only run under monkey test
+test "never runs here" {
+  puts("inside a test");
+  assert(false);
+}
+puts("tests skipped");
+assert_eq(1 + 1, 2);
+assert_error(fn() { 1 + true }, "type mismatch")
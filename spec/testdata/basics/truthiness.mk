@@ -0,0 +1,12 @@
+// false, null, zero and empty values are false as conditions; everything
+// else is true.
+let truth = fn(x) { if (x) { "true" } else { "false" } };
+puts(truth(0), truth(1), truth(""), truth("0"));
+puts(truth([]), truth([0]), truth({}), truth(first([])), truth(len));
+
+// && and || give booleans and skip their right operand once the left one
+// decides the result.
+puts(1 && "a", [] || {}, !0, !"a");
+puts(false && undefined, true || undefined);
+
+filter([0, 1, "", "b", [], [2]], fn(x) { x })
@@ -0,0 +1,6 @@
+let xs = [1, 2 * 2, 3 + 3];
+puts(xs, len(xs), xs[1], xs[10], xs[-1]);
+puts(first(xs), last(xs), rest(xs), rest([]), first([]));
+let ys = push(xs, "four");
+puts(xs, ys);
+[[1, 2], [3]][0][1]
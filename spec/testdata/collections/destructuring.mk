@@ -0,0 +1,19 @@
+// let binds the parts of arrays and hashes to names, with defaults for
+// the parts that are missing.
+let [first, second, ...others] = [1, 2, 3, 4];
+puts(first, second, others);
+
+let person = {"name": "ann", "age": 30, "city": "oslo"};
+let {name, age: years, email = "none", ...more} = person;
+puts(name, years, email, more);
+
+// patterns nest, and work as parameters too
+let {"points": [[x, y], last = [0, 0]]} = {"points": [[1, 2]]};
+puts(x + y, last);
+
+let swap = fn([a, b]) { [b, a] };
+let describe = fn({name, age = "?"}) { name + " is " + to_string(age) };
+puts(swap([1, 2]), describe(person), describe({"name": "bob"}));
+
+let [a, b] = swap([1, 2]);
+[a, b]
@@ -0,0 +1,11 @@
+let key = "two";
+let h = {"one": 1, key: 2, 3: "three", true: [1], [1, 2]: "array key"};
+puts(h["one"], h["two"], h[3], h[true], h[[1, 2]], h["missing"]);
+puts(keys(h));
+puts(values({"a": 1, "b": 2}), items({"a": 1}));
+puts(has(h, "one"), has(h, "nine"));
+
+let updated = set(h, "one", 100);
+puts(h["one"], updated["one"]);
+puts(delete({"a": 1, "b": 2}, "a"), merge({"a": 1}, {"b": 2}, {"a": 3}));
+{"z": 1, "a": 2}
@@ -0,0 +1,8 @@
+let text = json_stringify({"name": "monkey", "tags": ["a", "b"], "ok": true, "none": first([])});
+puts(text);
+let data = json_parse(text);
+puts(data["name"], data["tags"], data["ok"], data["none"]);
+
+let numbers = json_parse("[1, 0.5, -2e3]");
+puts(numbers[1] * 3, numbers[2]);
+json_stringify({"a": [1, 2]}, 2)
@@ -0,0 +1,3 @@
+let pair = fn(a, b = a) { [a, b] };
+puts(pair(1));
+pair(1, 2, 3)
@@ -0,0 +1,4 @@
+const answer = 42;
+puts(answer);
+let answer = 43;
+puts(answer);
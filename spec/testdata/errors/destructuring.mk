@@ -0,0 +1,2 @@
+let point = [1];
+let [x, y] = point;
@@ -0,0 +1,3 @@
+let sign = fn(x) { match (x) { n if n > 0 => 1, n if n < 0 => -1 } };
+puts(sign(3));
+sign(0)
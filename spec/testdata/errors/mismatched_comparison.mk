@@ -0,0 +1,2 @@
+// values of different types can't be compared
+1 == "1"
@@ -0,0 +1,3 @@
+puts("runs until the error");
+let x = 5 + true;
+puts("never printed");
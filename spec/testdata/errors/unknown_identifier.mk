@@ -0,0 +1,2 @@
+let f = fn() { missing + 1 };
+f()
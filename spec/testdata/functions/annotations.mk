@@ -0,0 +1,4 @@
+// type annotations are checked by monkey check, not when running
+let add = fn(a: int, b: int) -> int { a + b };
+let name: string = "monkey";
+puts(add(1, 2), name);
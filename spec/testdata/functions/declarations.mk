@@ -0,0 +1,14 @@
+// fn name() {} binds name for the whole block it is in, so functions can
+// be called before they are declared and can call each other.
+puts(is_even(10), is_odd(7));
+
+fn is_even(n) { if (n == 0) { true } else { is_odd(n - 1) } }
+fn is_odd(n) { if (n == 0) { false } else { is_even(n - 1) } }
+
+// a function expression with a name can call itself by it
+let factorial = fn fact(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+puts(factorial(5));
+
+// named functions show their names
+puts(is_even, factorial);
+is_odd(1, 2)
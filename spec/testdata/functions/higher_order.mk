@@ -0,0 +1,15 @@
+let map = fn(arr, f) {
+  let iter = fn(arr, acc) {
+    if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) }
+  };
+  iter(arr, [])
+};
+let reduce = fn(arr, initial, f) {
+  let iter = fn(arr, result) {
+    if (len(arr) == 0) { result } else { iter(rest(arr), f(result, first(arr))) }
+  };
+  iter(arr, initial)
+};
+let doubled = map([1, 2, 3, 4], fn(x) { x * 2 });
+puts(doubled);
+reduce(doubled, 0, fn(a, b) { a + b })
@@ -0,0 +1,20 @@
+// Parameters with defaults may be left out of a call, and a rest
+// parameter collects the arguments past the others into an array.
+let greet = fn(name, greeting = "hello") { greeting + ", " + name };
+puts(greet("ann"), greet("bob", "hi"));
+
+let sum = fn(first, ...others) {
+    let add = fn(total, items) {
+        if (len(items) == 0) { total } else { add(total + items[0], rest(items)) }
+    };
+    add(first, others)
+};
+puts(sum(1), sum(1, 2, 3));
+
+// ... spreads an array into the arguments of a call.
+let numbers = [4, 5, 6];
+puts(sum(...numbers), sum(1, ...numbers, 10));
+
+// a default can use the parameters before it
+let range = fn(from, to = from + 3) { [from, to] };
+range(2)
@@ -0,0 +1,10 @@
+let fib = fn(n) {
+  if (n < 2) { return n; }
+  fib(n - 1) + fib(n - 2)
+};
+puts(fib(15));
+
+// functions may call functions bound after them
+let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+puts(isEven(10), isOdd(7));
@@ -0,0 +1,5 @@
+let unless = macro(condition, consequence, alternative) {
+  quote(if (!(unquote(condition))) { unquote(consequence); } else { unquote(alternative); });
+};
+unless(10 > 5, puts("not greater"), puts("greater"));
+quote(1 + unquote(2 * 3))
@@ -0,0 +1,4 @@
+import "lib/shapes";
+import "lib/shapes" as s;
+puts(shapes.area(2, 3), s.squareArea(4), shapes.unit);
+shapes.square
@@ -0,0 +1,4 @@
+let square = fn(x) { x * x };
+export let area = fn(w, h) { w * h };
+export let squareArea = fn(side) { square(side) };
+export let unit = 1;
@@ -0,0 +1,5 @@
+puts(split("a,b,c", ","), split("  many   spaces "), join(["x", "y"], "-"));
+puts(trim("  pad  "), trim("xxhixx", "x"), upper("up"), lower("DOWN"));
+puts(contains("monkey", "key"), starts_with("monkey", "mon"), ends_with("monkey", "mon"));
+puts(replace("a-b-c", "-", "+"), repeat("ab", 3), substr("monkey", 1, 4), substr("monkey", -3));
+puts(chars("abc"), to_int("42") + 1, to_int(true), to_string([1, "a"]));
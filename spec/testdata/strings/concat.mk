@@ -0,0 +1,4 @@
+let greeting = "Hello" + ", " + "World!";
+puts(greeting, len(greeting), greeting[0], greeting[100]);
+puts(len("héllo"), "héllo"[1]);
+greeting
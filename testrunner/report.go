@@ -0,0 +1,185 @@
+package testrunner
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Failed counts the failed results.
+func Failed(results []Result) int {
+	failed := 0
+	for _, r := range results {
+		if r.Failed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// displayName shortens a file name, relative to the current directory.
+func displayName(name string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return name
+	}
+	if rel, err := filepath.Rel(wd, name); err == nil && len(rel) < len(name) {
+		return rel
+	}
+	return name
+}
+
+// location shows where a result failed, as "file:line".
+func (r Result) location() string {
+	if r.Line == 0 {
+		return displayName(r.File)
+	}
+	return fmt.Sprintf("%s:%d", displayName(r.File), r.Line)
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
+func tests(n int) string {
+	if n == 1 {
+		return "1 test"
+	}
+	return fmt.Sprintf("%d tests", n)
+}
+
+// WriteText writes the failed results, and with verbose the passed ones
+// too, the way go test does, ending with the total.
+func WriteText(w io.Writer, results []Result, verbose bool) error {
+	bw := bufio.NewWriter(w)
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+		if !r.Failed {
+			if verbose {
+				fmt.Fprintf(bw, "--- PASS: %s (%s)\n", r.Name, seconds(r.Duration))
+			}
+			continue
+		}
+		fmt.Fprintf(bw, "--- FAIL: %s (%s)\n", r.Name, seconds(r.Duration))
+		lines := strings.Split(r.Message, "\n")
+		fmt.Fprintf(bw, "    %s: %s\n", r.location(), lines[0])
+		for _, line := range lines[1:] {
+			fmt.Fprintf(bw, "        %s\n", line)
+		}
+	}
+
+	if failed := Failed(results); failed > 0 {
+		fmt.Fprintf(bw, "FAIL\t%d of %s failed (%s)\n", failed, tests(len(results)), seconds(total))
+	} else {
+		fmt.Fprintf(bw, "ok\t%s passed (%s)\n", tests(len(results)), seconds(total))
+	}
+	return bw.Flush()
+}
+
+// WriteTAP writes the results in the Test Anything Protocol, version 13,
+// with the message of each failure in a YAML block.
+func WriteTAP(w io.Writer, results []Result) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "TAP version 13\n1..%d\n", len(results))
+	for i, r := range results {
+		if !r.Failed {
+			fmt.Fprintf(bw, "ok %d - %s\n", i+1, r.Name)
+			continue
+		}
+		fmt.Fprintf(bw, "not ok %d - %s\n", i+1, r.Name)
+		fmt.Fprintf(bw, "  ---\n  message: |\n")
+		for _, line := range strings.Split(r.Message, "\n") {
+			fmt.Fprintf(bw, "    %s\n", line)
+		}
+		fmt.Fprintf(bw, "  at: %q\n  ...\n", r.location())
+	}
+	return bw.Flush()
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the results as JUnit XML, as read by most CI
+// services, with a test suite for each file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	doc := junitSuites{Tests: len(results)}
+	var total time.Duration
+	suites := map[string]int{} // file to index in doc.Suites
+	suiteTimes := []time.Duration{}
+	for _, r := range results {
+		name := displayName(r.File)
+		i, ok := suites[r.File]
+		if !ok {
+			i = len(doc.Suites)
+			suites[r.File] = i
+			doc.Suites = append(doc.Suites, junitSuite{Name: name})
+			suiteTimes = append(suiteTimes, 0)
+		}
+
+		c := junitCase{Name: r.Name, ClassName: name, File: name, Line: r.Line, Time: junitTime(r.Duration)}
+		if r.Failed {
+			c.Failure = &junitFailure{
+				Message: strings.SplitN(r.Message, "\n", 2)[0],
+				Text:    r.location() + ": " + r.Message,
+			}
+			doc.Failures++
+			doc.Suites[i].Failures++
+		}
+		doc.Suites[i].Tests++
+		doc.Suites[i].Cases = append(doc.Suites[i].Cases, c)
+		suiteTimes[i] += r.Duration
+		total += r.Duration
+	}
+	for i := range doc.Suites {
+		doc.Suites[i].Time = junitTime(suiteTimes[i])
+	}
+	doc.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,106 @@
+package testrunner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testResults(t *testing.T) []Result {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(wd, "math_test.mk")
+	return []Result{
+		{Name: "adds", File: file, Line: 3, Duration: 10 * time.Millisecond},
+		{Name: "fails", File: file, Line: 8, Failed: true, Duration: 20 * time.Millisecond,
+			Message: "assert_eq failed (-expected +actual)\n- 3\n+ 2"},
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	tests := []struct {
+		verbose  bool
+		expected string
+	}{
+		{false, "--- FAIL: fails (0.02s)\n" +
+			"    math_test.mk:8: assert_eq failed (-expected +actual)\n" +
+			"        - 3\n" +
+			"        + 2\n" +
+			"FAIL\t1 of 2 tests failed (0.03s)\n"},
+		{true, "--- PASS: adds (0.01s)\n" +
+			"--- FAIL: fails (0.02s)\n" +
+			"    math_test.mk:8: assert_eq failed (-expected +actual)\n" +
+			"        - 3\n" +
+			"        + 2\n" +
+			"FAIL\t1 of 2 tests failed (0.03s)\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := WriteText(&out, testResults(t), tt.verbose); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("wrong output with verbose=%t.\nwant=%q\ngot= %q", tt.verbose, tt.expected, out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	WriteText(&out, testResults(t)[:1], false)
+	if expected := "ok\t1 test passed (0.01s)\n"; out.String() != expected {
+		t.Errorf("wrong output for passing tests.\nwant=%q\ngot= %q", expected, out.String())
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteTAP(&out, testResults(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "TAP version 13\n" +
+		"1..2\n" +
+		"ok 1 - adds\n" +
+		"not ok 2 - fails\n" +
+		"  ---\n" +
+		"  message: |\n" +
+		"    assert_eq failed (-expected +actual)\n" +
+		"    - 3\n" +
+		"    + 2\n" +
+		"  at: \"math_test.mk:8\"\n" +
+		"  ...\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot= %q", expected, out.String())
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, testResults(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := xml.Header +
+		`<testsuites tests="2" failures="1" time="0.030">
+  <testsuite name="math_test.mk" tests="2" failures="1" time="0.030">
+    <testcase name="adds" classname="math_test.mk" file="math_test.mk" line="3" time="0.010"></testcase>
+    <testcase name="fails" classname="math_test.mk" file="math_test.mk" line="8" time="0.020">
+      <failure message="assert_eq failed (-expected +actual)">math_test.mk:8: assert_eq failed (-expected +actual)&#xA;- 3&#xA;+ 2</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%s\ngot= %s", expected, out.String())
+	}
+
+	var doc junitSuites
+	if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %s", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package testrunner runs tests written in Monkey. Tests live in files
+// named *_test.mk, either as test blocks:
+//
+//	test "adds numbers" {
+//	    assert_eq(1 + 1, 2);
+//	}
+//
+// or as functions without parameters bound to names starting with test_.
+// Each test runs in an environment of its own, in which the rest of its
+// file has been evaluated afresh.
+package testrunner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/evaluator"
+	"github.com/OlyaIvanovs/interpreter_in_go/object"
+)
+
+// Suffix ends the names of files holding tests.
+const Suffix = "_test" + evaluator.ModuleExtension
+
+// FunctionPrefix starts the names of test functions.
+const FunctionPrefix = "test_"
+
+// Find returns the test files among paths, looking through directories
+// and their subdirectories. Files named explicitly are kept whatever
+// their name.
+func Find(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found := []string{}
+		err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && name != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(name, Suffix) {
+				found = append(found, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// A Test is one test of a file.
+type Test struct {
+	Name string
+	Line int
+	body *ast.BlockStatement // for a test block
+}
+
+// A File is a loaded test file.
+type File struct {
+	Name    string // the absolute path of the file
+	Tests   []*Test
+	program *ast.Program
+}
+
+// Load reads the test file at path and lists its tests in source order.
+func Load(path string) (*File, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	program, errObj := evaluator.ParseFile(abs)
+	if errObj != nil {
+		return nil, fmt.Errorf("%s", errObj.Message)
+	}
+
+	f := &File{Name: abs, program: program}
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		switch stmt := stmt.(type) {
+		case *ast.TestStatement:
+			f.Tests = append(f.Tests, &Test{Name: stmt.Name.Value, Line: stmt.Token.Line, body: stmt.Body})
+		case *ast.LetStatement:
+			fn, ok := stmt.Value.(*ast.FunctionLiteral)
+			name, named := stmt.Name.(*ast.Identifier)
+			if ok && named && strings.HasPrefix(name.Value, FunctionPrefix) && len(fn.Parameters) == 0 {
+				f.Tests = append(f.Tests, &Test{Name: name.Value, Line: stmt.Token.Line})
+			}
+		}
+	}
+	return f, nil
+}
+
+// A Result is the outcome of running a test.
+type Result struct {
+	Name     string
+	File     string
+	Line     int // where the test failed, or where it starts if it passed
+	Failed   bool
+	Message  string
+	Duration time.Duration
+}
+
+// Run runs test t of f. The statements of f other than its tests are
+// evaluated first in a new environment, then the test in one enclosed by
+// it.
+func (f *File) Run(t *Test) (r Result) {
+	start := time.Now()
+	r = Result{Name: t.Name, File: f.Name, Line: t.Line}
+	defer func() { r.Duration = time.Since(start) }()
+
+	env := object.NewFileEnvironment(f.Name)
+	if line, errObj := evalStatements(f.program.Statements, env); errObj != nil {
+		r.Failed, r.Line, r.Message = true, line, errObj.Message
+		return r
+	}
+
+	var body *ast.BlockStatement
+	testEnv := object.NewEnclosedEnvironment(env)
+	if t.body != nil {
+		body = t.body
+	} else {
+		value, _ := env.Get(t.Name)
+		fn, ok := value.(*object.Function)
+		if !ok {
+			r.Failed, r.Message = true, fmt.Sprintf("%s is not a function", t.Name)
+			return r
+		}
+		body, testEnv = fn.Body, object.NewEnclosedEnvironment(fn.Env)
+	}
+
+	if line, errObj := evalStatements(body.Statements, testEnv); errObj != nil {
+		r.Failed, r.Line, r.Message = true, line, errObj.Message
+	}
+	return r
+}
+
+// evalStatements evaluates stmts in env until one returns, stopping at an
+// error to report it along with the line of the statement that made it.
+func evalStatements(stmts []ast.Statement, env *object.Environment) (int, *object.Error) {
+	if errObj := evaluator.Hoist(stmts, env); errObj != nil {
+		return 0, errObj
+	}
+	for _, stmt := range stmts {
+		switch result := evaluator.Eval(stmt, env).(type) {
+		case *object.Error:
+			tok, _ := ast.TokenOf(stmt)
+			return tok.Line, result
+		case *object.ReturnValue:
+			return 0, nil
+		}
+	}
+	return 0, nil
+}
+
+// Run runs the tests in the test files among paths whose names match
+// filter, or all of them if filter is nil. A file that can't be loaded
+// gives a failed result named after it.
+func Run(paths []string, filter *regexp.Regexp) ([]Result, error) {
+	files, err := Find(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, path := range files {
+		f, err := Load(path)
+		if err != nil {
+			abs, _ := filepath.Abs(path)
+			results = append(results, Result{Name: filepath.Base(path), File: abs, Failed: true, Message: err.Error()})
+			continue
+		}
+		for _, t := range f.Tests {
+			if filter == nil || filter.MatchString(t.Name) {
+				results = append(results, f.Run(t))
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,137 @@
+package testrunner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+const mathTest = `import "math";
+
+let counter = [];
+
+test "adds" {
+  assert_eq(math.add(1, 2), 3);
+}
+
+test "fails" {
+  let x = math.add(1, 1);
+  assert_eq(x, 3, "one and one");
+}
+
+test "isolated" {
+  // every test sees the file evaluated afresh
+  let counter = push(counter, 1);
+  assert_eq(len(counter), 1);
+}
+
+let test_function = fn() {
+  assert(math.add(2, 2) == 4);
+};
+
+let test_takes_arguments = fn(x) { x };
+let helper = fn() { assert(false) };
+fn test_declared() { assert_eq(math.add(1, 1), 2) }
+`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFind(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"b_test.mk":         "",
+		"a_test.mk":         "",
+		"lib.mk":            "",
+		"sub/c_test.mk":     "",
+		".hidden/d_test.mk": "",
+	})
+
+	files, err := Find([]string{dir, filepath.Join(dir, "lib.mk")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range files {
+		files[i], _ = filepath.Rel(dir, files[i])
+	}
+	expected := []string{"a_test.mk", "b_test.mk", "sub/c_test.mk", "lib.mk"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("wrong files. want=%q, got=%q", expected, files)
+	}
+
+	if _, err := Find([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("expected an error for a missing path")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"math.mk":       "export let add = fn(a, b) { a + b };",
+		"math_test.mk":  mathTest,
+		"bad_test.mk":   "test \"broken\" { let = 1 }",
+		"setup_test.mk": "let x = 1 + true;\ntest \"never runs\" { assert(true) }",
+	})
+
+	results, err := Run([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type outcome struct {
+		name    string
+		line    int
+		failed  bool
+		message string
+	}
+	var got []outcome
+	for _, r := range results {
+		got = append(got, outcome{r.Name, r.Line, r.Failed, r.Message})
+	}
+	expected := []outcome{
+		{"bad_test.mk", 0, true, "parse errors in " + displayName(filepath.Join(dir, "bad_test.mk")) +
+			": expected next token to be IDENT, got '=' instead; no prefix parse function for = found"},
+		{"adds", 5, false, ""},
+		{"fails", 11, true, "assert_eq failed: one and one (-expected +actual)\n- 3\n+ 2"},
+		{"isolated", 14, false, ""},
+		{"test_function", 20, false, ""},
+		{"test_declared", 26, false, ""},
+		{"never runs", 1, true, "type mismatch: INTEGER + BOOLEAN"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong results.\nwant=%+v\ngot= %+v", expected, got)
+	}
+	if Failed(results) != 3 {
+		t.Errorf("wrong number failed. want=3, got=%d", Failed(results))
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"math.mk":      "export let add = fn(a, b) { a + b };",
+		"math_test.mk": mathTest,
+	})
+
+	results, err := Run([]string{dir}, regexp.MustCompile("^(adds|test_)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	if expected := []string{"adds", "test_function", "test_declared"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong tests run. want=%q, got=%q", expected, names)
+	}
+}
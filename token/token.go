@@ -5,12 +5,16 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // 1-based line of the first character
+	Column  int // 1-based byte column of the first character
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
+	COMMENT = "COMMENT"
+
 	IDENT  = "IDENT"
 	INT    = "INT"
 	STRING = "STRING"
@@ -26,11 +30,17 @@ const (
 	GT   = ">"
 	EQ   = "=="
 	NOT_EQ = "!="
+	AND    = "&&"
+	OR     = "||"
+	ARROW  = "->"
+	FAT_ARROW = "=>"
+	ELLIPSIS = "..."
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON 	  = ":"
+	DOT       = "."
 
 	LPAREN 	 = "("
 	RPAREN   = ")"
@@ -47,6 +57,11 @@ const (
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	ELSE     = "ELSE"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	MACRO    = "MACRO"
+	MATCH    = "MATCH"
+	CONST    = "CONST"
 )
 
 var keywords = map[string]TokenType{
@@ -57,6 +72,11 @@ var keywords = map[string]TokenType{
     "false": FALSE,
     "true": TRUE,
     "else": ELSE,
+    "import": IMPORT,
+    "export": EXPORT,
+    "macro": MACRO,
+    "match": MATCH,
+    "const": CONST,
 }
 
 
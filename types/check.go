@@ -0,0 +1,797 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+// An Error is a type mismatch found in a program.
+type Error struct {
+	Line, Column int
+	Message      string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// A Result holds what was learnt by checking a program.
+type Result struct {
+	Errors []Error
+
+	// Types records the type of every expression that was checked.
+	Types map[ast.Expression]Type
+}
+
+// Source parses src and checks it.
+func Source(src []byte) ([]Error, error) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	return Check(program).Errors, nil
+}
+
+// Check infers the types in program and reports where they do not match
+// its annotations or the operators and builtins they are used with.
+func Check(program *ast.Program) *Result {
+	c := &checker{types: map[ast.Expression]Type{}}
+	c.block(program.Statements, newScope(nil))
+
+	for e, t := range c.types {
+		c.types[e] = resolve(t)
+	}
+	return &Result{Errors: c.errors, Types: c.types}
+}
+
+// builtins gives the types of the builtin functions, with a and b fresh
+// variables for the polymorphic ones. Builtins taking any number of
+// arguments are left out and have type any.
+var builtins = map[string]func(a, b Type) *Function{
+	"len":         func(a, b Type) *Function { return fn(Int, Any) },
+	"first":       func(a, b Type) *Function { return fn(a, &Array{Element: a}) },
+	"last":        func(a, b Type) *Function { return fn(a, &Array{Element: a}) },
+	"rest":        func(a, b Type) *Function { return fn(&Array{Element: a}, &Array{Element: a}) },
+	"push":        func(a, b Type) *Function { return fn(&Array{Element: a}, &Array{Element: a}, a) },
+	"split":       func(a, b Type) *Function { return optional(fn(&Array{Element: String}, String, String), 1) },
+	"join":        func(a, b Type) *Function { return optional(fn(String, &Array{Element: String}, String), 1) },
+	"trim":        func(a, b Type) *Function { return optional(fn(String, String, String), 1) },
+	"upper":       func(a, b Type) *Function { return fn(String, String) },
+	"lower":       func(a, b Type) *Function { return fn(String, String) },
+	"contains":    func(a, b Type) *Function { return fn(Bool, String, String) },
+	"starts_with": func(a, b Type) *Function { return fn(Bool, String, String) },
+	"ends_with":   func(a, b Type) *Function { return fn(Bool, String, String) },
+	"replace":     func(a, b Type) *Function { return fn(String, String, String, String) },
+	"repeat":      func(a, b Type) *Function { return fn(String, String, Int) },
+	"substr":      func(a, b Type) *Function { return optional(fn(String, String, Int, Int), 1) },
+	"chars":       func(a, b Type) *Function { return fn(&Array{Element: String}, String) },
+	"to_int":      func(a, b Type) *Function { return fn(Int, Any) },
+	"to_string":   func(a, b Type) *Function { return fn(String, Any) },
+	"keys":        func(a, b Type) *Function { return fn(&Array{Element: a}, &Hash{Key: a, Value: b}) },
+	"values":      func(a, b Type) *Function { return fn(&Array{Element: b}, &Hash{Key: a, Value: b}) },
+	"items": func(a, b Type) *Function {
+		return fn(&Array{Element: &Array{Element: Any}}, &Hash{Key: a, Value: b})
+	},
+	"has": func(a, b Type) *Function { return fn(Bool, &Hash{Key: a, Value: b}, a) },
+	"set": func(a, b Type) *Function {
+		return fn(&Hash{Key: a, Value: b}, &Hash{Key: a, Value: b}, a, b)
+	},
+	"delete": func(a, b Type) *Function {
+		return fn(&Hash{Key: a, Value: b}, &Hash{Key: a, Value: b}, a)
+	},
+	"json_parse": func(a, b Type) *Function { return fn(Any, String) },
+	"filter": func(a, b Type) *Function {
+		return fn(&Array{Element: a}, &Array{Element: a}, fn(Any, a))
+	},
+}
+
+func fn(ret Type, params ...Type) *Function {
+	return &Function{Params: params, Return: ret}
+}
+
+func optional(f *Function, n int) *Function {
+	f.optional = n
+	return f
+}
+
+// A scope maps names to their types. As at runtime, blocks share the
+// scope of the function they are in.
+type scope struct {
+	names map[string]*scheme
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]*scheme{}, outer: outer}
+}
+
+func (s *scope) lookup(name string) (*scheme, bool) {
+	for ; s != nil; s = s.outer {
+		if sc, ok := s.names[name]; ok {
+			return sc, true
+		}
+	}
+	return nil, false
+}
+
+// function is what the checker tracks about the function it is in.
+type function struct {
+	ret      Type // the declared return type, or nil
+	returned []Type
+}
+
+type checker struct {
+	unifier
+	errors []Error
+	types  map[ast.Expression]Type
+	fn     *function
+}
+
+func (c *checker) errorf(node ast.Node, format string, args ...interface{}) {
+	tok, _ := ast.TokenOf(node)
+	c.errors = append(c.errors, Error{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// generalize turns t into a scheme over the variables that are not used
+// by the names in s.
+func (c *checker) generalize(t Type, s *scope) *scheme {
+	free := map[*Variable]bool{}
+	freeVariables(t, free)
+	if len(free) == 0 {
+		return &scheme{typ: t}
+	}
+
+	bound := map[*Variable]bool{}
+	for ; s != nil; s = s.outer {
+		for _, sc := range s.names {
+			freeVariables(sc.typ, bound)
+			for _, v := range sc.vars {
+				delete(bound, v)
+			}
+		}
+	}
+
+	sc := &scheme{typ: t}
+	for v := range free {
+		if !bound[v] {
+			sc.vars = append(sc.vars, v)
+		}
+	}
+	return sc
+}
+
+// block checks statements and returns the type of the value they produce,
+// or nil if they always return from the function.
+func (c *checker) block(statements []ast.Statement, s *scope) Type {
+	// Declared functions can be called before their declarations, which
+	// are left unchecked.
+	for _, stmt := range statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+		if let, ok := stmt.(*ast.LetStatement); ok && let.IsDeclaration() {
+			s.names[let.Value.(*ast.FunctionLiteral).Name.Value] = &scheme{typ: Any}
+		}
+	}
+
+	var result Type = Null
+	for _, stmt := range statements {
+		var t Type = Null
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			c.let(stmt, s)
+		case *ast.ExportStatement:
+			c.let(stmt.Statement, s)
+		case *ast.ImportStatement:
+			name := filepath.Base(stmt.Path.Value)
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if stmt.Alias != nil {
+				name = stmt.Alias.Value
+			}
+			s.names[name] = &scheme{typ: Any}
+		case *ast.TestStatement:
+			c.test(stmt, s)
+		case *ast.ReturnStatement:
+			c.returnStatement(stmt, s)
+			t = nil
+		case *ast.ExpressionStatement:
+			t = c.value(stmt.Expression, s)
+		}
+
+		if result != nil {
+			result = t
+		}
+	}
+	return result
+}
+
+func (c *checker) let(stmt *ast.LetStatement, s *scope) {
+	ident, ok := stmt.Name.(*ast.Identifier)
+	if !ok {
+		c.pattern(stmt.Name, c.expr(stmt.Value, s), s)
+		return
+	}
+	name := ident.Value
+
+	var declared Type
+	if ident.Type != nil {
+		declared = c.typeOf(ident.Type)
+	}
+
+	// A function may call itself, so its name is bound before its body is
+	// checked.
+	_, recursive := stmt.Value.(*ast.FunctionLiteral)
+	self := declared
+	if recursive {
+		if self == nil {
+			self = c.fresh()
+		}
+		s.names[name] = &scheme{typ: self}
+	}
+
+	t := c.expr(stmt.Value, s)
+	c.types[ident] = t
+	if declared != nil {
+		if !c.unify(declared, t) {
+			c.errorf(stmt.Value, "cannot use %s as %s in let %s", t, declared, name)
+		}
+		s.names[name] = &scheme{typ: declared}
+		return
+	}
+
+	if recursive {
+		c.unify(self, t)
+		delete(s.names, name)
+	}
+	s.names[name] = c.generalize(t, s)
+}
+
+// pattern binds the names in p to the types of the parts of a value of
+// type t they stand for, reporting values that cannot have the shape of
+// p. The parts of a value of an unknown type have unknown types.
+func (c *checker) pattern(p ast.Pattern, t Type, s *scope) {
+	if def := ast.DefaultOf(p); def != nil {
+		if dt := c.expr(def, s); !c.unify(t, dt) {
+			c.errorf(def, "cannot use %s as %s in default of %s", dt, t, patternName(p))
+		}
+	}
+
+	switch p := p.(type) {
+	case *ast.Identifier:
+		if p.Type != nil {
+			declared := c.typeOf(p.Type)
+			if !c.unify(declared, t) {
+				c.errorf(p, "cannot use %s as %s in pattern for %s", t, declared, p.Value)
+			}
+			t = declared
+		}
+		s.names[p.Value] = &scheme{typ: t}
+		c.types[p] = t
+
+	case *ast.LiteralPattern:
+		if lt := c.expr(p.Value, s); !c.comparable(t, lt) {
+			c.errorf(p, "cannot match %s against %s, of type %s", t, p, lt)
+		}
+
+	case *ast.ArrayPattern:
+		var element Type = Any
+		switch v := prune(t).(type) {
+		case *Array:
+			element = v.Element
+		case *Variable:
+		default:
+			if v != Any {
+				c.errorf(p, "cannot bind %s to %s, which takes an array", v, p)
+			}
+		}
+		for _, el := range p.Elements {
+			c.pattern(el, element, s)
+		}
+		if p.Rest != nil {
+			rest := &Array{Element: element}
+			s.names[p.Rest.Value] = &scheme{typ: rest}
+			c.types[p.Rest] = rest
+		}
+
+	case *ast.HashPattern:
+		var value Type = Any
+		rest := &Hash{Key: Any, Value: Any}
+		switch v := prune(t).(type) {
+		case *Hash:
+			if !c.unify(v.Key, String) {
+				c.errorf(p, "cannot bind %s to %s, whose keys are not strings", v, p)
+			}
+			value, rest = v.Value, v
+		case *Variable:
+		default:
+			if v != Any {
+				c.errorf(p, "cannot bind %s to %s, which takes a hash", v, p)
+			}
+		}
+		for _, pair := range p.Pairs {
+			c.pattern(pair.Value, value, s)
+		}
+		if p.Rest != nil {
+			s.names[p.Rest.Value] = &scheme{typ: rest}
+			c.types[p.Rest] = rest
+		}
+	}
+}
+
+// patternName describes p in errors about its default.
+func patternName(p ast.Pattern) string {
+	switch p := p.(type) {
+	case *ast.Identifier:
+		return p.Value
+	case *ast.ArrayPattern:
+		return "an array pattern"
+	}
+	return "a hash pattern"
+}
+
+// test checks the body of a test, which runs like a function without
+// parameters.
+func (c *checker) test(stmt *ast.TestStatement, s *scope) {
+	outer := c.fn
+	c.fn = &function{}
+	defer func() { c.fn = outer }()
+
+	c.block(stmt.Body.Statements, newScope(s))
+}
+
+func (c *checker) returnStatement(stmt *ast.ReturnStatement, s *scope) {
+	t := c.expr(stmt.ReturnValue, s)
+	if c.fn == nil {
+		return
+	}
+
+	if c.fn.ret != nil {
+		if !c.unify(c.fn.ret, t) {
+			c.errorf(stmt.ReturnValue, "cannot use %s as %s in return statement", t, c.fn.ret)
+		}
+		return
+	}
+	c.fn.returned = append(c.fn.returned, t)
+}
+
+// value checks an expression whose value may be the result of a block,
+// returning nil if it always returns from the function.
+func (c *checker) value(e ast.Expression, s *scope) Type {
+	ife, ok := e.(*ast.IfExpression)
+	if !ok {
+		return c.expr(e, s)
+	}
+
+	t := c.ifExpression(ife, s)
+	if t == nil {
+		c.types[e] = Any
+	} else {
+		c.types[e] = t
+	}
+	return t
+}
+
+func (c *checker) expr(e ast.Expression, s *scope) Type {
+	if e == nil {
+		return Any
+	}
+
+	t := c.exprType(e, s)
+	c.types[e] = t
+	return t
+}
+
+func (c *checker) exprType(e ast.Expression, s *scope) Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		if sc, ok := s.lookup(e.Value); ok {
+			return c.instantiate(sc)
+		}
+		if builtin, ok := builtins[e.Value]; ok {
+			return builtin(c.fresh(), c.fresh())
+		}
+		return Any
+	case *ast.ArrayLiteral:
+		ts := []Type{c.fresh()}
+		for _, el := range e.Elements {
+			ts = append(ts, c.expr(el, s))
+		}
+		return &Array{Element: c.join(ts...)}
+	case *ast.HashLiteral:
+		keys, values := []Type{c.fresh()}, []Type{c.fresh()}
+		for _, key := range e.Keys {
+			keys = append(keys, c.expr(key, s))
+			values = append(values, c.expr(e.Pairs[key], s))
+		}
+		return &Hash{Key: c.join(keys...), Value: c.join(values...)}
+	case *ast.PrefixExpression:
+		return c.prefix(e, s)
+	case *ast.InfixExpression:
+		return c.infix(e, s)
+	case *ast.IfExpression:
+		if t := c.ifExpression(e, s); t != nil {
+			return t
+		}
+		return Any
+	case *ast.MatchExpression:
+		if t := c.matchExpression(e, s); t != nil {
+			return t
+		}
+		return Any
+	case *ast.FunctionLiteral:
+		return c.function(e, s)
+	case *ast.CallExpression:
+		return c.call(e, s)
+	case *ast.IndexExpression:
+		return c.index(e, s)
+	case *ast.MemberExpression:
+		c.expr(e.Object, s)
+		return Any
+	case *ast.MacroLiteral:
+		return macro
+	default:
+		return Any
+	}
+}
+
+func (c *checker) prefix(e *ast.PrefixExpression, s *scope) Type {
+	right := c.expr(e.Right, s)
+	switch e.Operator {
+	case "!":
+		return Bool
+	case "-":
+		switch t := prune(right); {
+		case isNumber(t):
+			return t
+		case t == Any:
+			return Any
+		}
+		if _, ok := prune(right).(*Variable); ok {
+			return Any
+		}
+		c.errorf(e, "invalid operation: -%s", right)
+		return Any
+	default:
+		return Any
+	}
+}
+
+func (c *checker) infix(e *ast.InfixExpression, s *scope) Type {
+	left := c.expr(e.Left, s)
+	right := c.expr(e.Right, s)
+
+	switch e.Operator {
+	case "==", "!=":
+		if !c.comparable(left, right) {
+			c.errorf(e, "invalid operation: %s %s %s (mismatched types)", left, e.Operator, right)
+		}
+		return Bool
+	case "+", "-", "*", "/", "<", ">":
+		t, ok := c.arithmetic(e.Operator, left, right)
+		if !ok {
+			c.errorf(e, "invalid operation: %s %s %s", left, e.Operator, right)
+			t = Any
+		}
+		if e.Operator == "<" || e.Operator == ">" {
+			return Bool
+		}
+		return t
+	case "&&", "||":
+		return Bool
+	default:
+		return Any
+	}
+}
+
+// arithmetic returns the type of left op right for the operators that
+// work on numbers and, except for - * and /, strings.
+func (c *checker) arithmetic(op string, left, right Type) (Type, bool) {
+	left, right = prune(left), prune(right)
+	if left == Any || right == Any {
+		return Any, true
+	}
+
+	if _, ok := left.(*Variable); ok {
+		if _, ok := right.(*Variable); ok {
+			return Any, true
+		}
+		left, right = right, left
+	}
+	if v, ok := right.(*Variable); ok {
+		if !isNumber(left) && !(left == String && acceptsStrings(op)) {
+			return nil, false
+		}
+		c.unify(v, left)
+		return left, true
+	}
+
+	switch {
+	case left == Int && right == Int:
+		return Int, true
+	case isNumber(left) && isNumber(right):
+		return Float, true
+	case left == String && right == String && acceptsStrings(op):
+		return String, true
+	default:
+		return nil, false
+	}
+}
+
+func acceptsStrings(op string) bool {
+	return op == "+" || op == "<" || op == ">"
+}
+
+// comparable reports whether == may compare values of types a and b.
+func (c *checker) comparable(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == Null || b == Null || (isNumber(a) && isNumber(b)) {
+		return true
+	}
+
+	switch a.(type) {
+	case *Array:
+		_, ok := b.(*Array)
+		return ok || b == Any || isVariable(b)
+	case *Hash:
+		_, ok := b.(*Hash)
+		return ok || b == Any || isVariable(b)
+	}
+	return c.compatible(a, b)
+}
+
+func isVariable(t Type) bool {
+	_, ok := t.(*Variable)
+	return ok
+}
+
+// ifExpression returns the type of the branch taken, Any if the branches
+// disagree, or nil if both always return from the function.
+func (c *checker) ifExpression(e *ast.IfExpression, s *scope) Type {
+	c.expr(e.Condition, s)
+
+	consequence := c.block(e.Consequence.Statements, s)
+	if e.Alternative == nil {
+		return c.join(consequence, Null)
+	}
+	return c.join(consequence, c.block(e.Alternative.Statements, s))
+}
+
+// matchExpression returns the type of the arm taken, Any if the arms
+// disagree, or nil if they all always return from the function. Each arm
+// binds its names in a scope of its own.
+func (c *checker) matchExpression(e *ast.MatchExpression, s *scope) Type {
+	t := c.expr(e.Value, s)
+
+	arms := make([]Type, len(e.Arms))
+	for i, arm := range e.Arms {
+		inner := newScope(s)
+		c.pattern(arm.Pattern, t, inner)
+		if arm.Guard != nil {
+			c.expr(arm.Guard, inner)
+		}
+		arms[i] = c.expr(arm.Body, inner)
+	}
+	return c.join(arms...)
+}
+
+func (c *checker) function(f *ast.FunctionLiteral, s *scope) Type {
+	inner := newScope(s)
+	var self Type
+	if f.Name != nil {
+		// a named function can call itself by its name
+		self = c.fresh()
+		inner.names[f.Name.Value] = &scheme{typ: self}
+	}
+	params := make([]Type, len(f.Parameters))
+	optional := 0
+	for i, param := range f.Parameters {
+		params[i] = Any
+		p, ok := param.(*ast.Identifier)
+		if !ok {
+			// an argument bound to a pattern has an unknown type, and
+			// so do its parts
+			if ast.DefaultOf(param) != nil {
+				optional++
+			}
+			c.pattern(param, Any, inner)
+			continue
+		}
+		if p.Type != nil {
+			params[i] = c.typeOf(p.Type)
+		}
+		if p.Default != nil {
+			// defaults are evaluated when called, seeing the parameters
+			// before them
+			optional++
+			if t := c.expr(p.Default, inner); !c.unify(params[i], t) {
+				c.errorf(p.Default, "cannot use %s as %s in default of %s", t, params[i], p.Value)
+			}
+		}
+		inner.names[p.Value] = &scheme{typ: params[i]}
+		c.types[p] = params[i]
+	}
+	var rest Type
+	if f.Rest != nil {
+		array := &Array{Element: Any}
+		if f.Rest.Type != nil {
+			t := c.typeOf(f.Rest.Type)
+			if a, ok := t.(*Array); ok {
+				array = a
+			} else {
+				c.errorf(f.Rest.Type, "cannot use %s as the type of rest parameter %s, which is an array", t, f.Rest.Value)
+			}
+		}
+		rest = array.Element
+		inner.names[f.Rest.Value] = &scheme{typ: array}
+		c.types[f.Rest] = array
+	}
+
+	outer := c.fn
+	c.fn = &function{}
+	defer func() { c.fn = outer }()
+
+	if f.ReturnType != nil {
+		c.fn.ret = c.typeOf(f.ReturnType)
+	}
+
+	body := c.block(f.Body.Statements, inner)
+	if c.fn.ret == nil {
+		ret := c.join(append(c.fn.returned, body)...)
+		if ret == nil {
+			ret = Any
+		}
+		return c.named(f, self, &Function{Params: params, Return: ret, optional: optional, rest: rest})
+	}
+
+	if body != nil && !c.unify(c.fn.ret, body) {
+		var at ast.Node = f
+		if n := len(f.Body.Statements); n > 0 {
+			if stmt, ok := f.Body.Statements[n-1].(*ast.ExpressionStatement); ok {
+				at = stmt.Expression
+			}
+		}
+		c.errorf(at, "cannot use %s as %s in return value", body, c.fn.ret)
+	}
+	return c.named(f, self, &Function{Params: params, Return: c.fn.ret, optional: optional, rest: rest})
+}
+
+// named unifies self, the type the name of f has inside it, with t, the
+// type of f.
+func (c *checker) named(f *ast.FunctionLiteral, self Type, t *Function) Type {
+	if f.Name != nil {
+		c.unify(self, t)
+		c.types[f.Name] = t
+	}
+	return t
+}
+
+func (c *checker) call(e *ast.CallExpression, s *scope) Type {
+	name := e.Function.String()
+	if name == "quote" || name == "unquote" {
+		if _, ok := s.lookup(name); !ok {
+			return Any
+		}
+	}
+
+	callee := prune(c.expr(e.Function, s))
+	if callee == macro {
+		return Any
+	}
+
+	// arguments from a spread array can't be matched to parameters, so
+	// only those before the first are checked
+	args := []Type{}
+	spread := false
+	for _, arg := range e.Arguments {
+		if sp, ok := arg.(*ast.SpreadExpression); ok {
+			t := c.expr(sp.Value, s)
+			if !c.unify(&Array{Element: c.fresh()}, t) {
+				c.errorf(sp.Value, "cannot spread %s of type %s in call to %s", sp.Value, t, name)
+			}
+			spread = true
+			continue
+		}
+		t := c.expr(arg, s)
+		if !spread {
+			args = append(args, t)
+		}
+	}
+
+	switch f := callee.(type) {
+	case *Variable:
+		ret := c.fresh()
+		if !spread {
+			c.unify(f, &Function{Params: args, Return: ret})
+		}
+		return ret
+	case *Function:
+		tooFew := !spread && len(args) < len(f.Params)-f.optional
+		if tooFew || (f.rest == nil && len(args) > len(f.Params)) {
+			c.errorf(e.Function, "wrong number of arguments in call to %s: got %d, want %d", name, len(args), len(f.Params))
+			return f.Return
+		}
+		for i, arg := range args {
+			param := f.rest
+			if i < len(f.Params) {
+				param = f.Params[i]
+			}
+			if !c.unify(param, arg) {
+				c.errorf(e.Arguments[i], "cannot use %s as %s in argument %d to %s", arg, param, i+1, name)
+			}
+		}
+		return f.Return
+	default:
+		if callee != Any {
+			c.errorf(e.Function, "cannot call %s of type %s", name, callee)
+		}
+		return Any
+	}
+}
+
+func (c *checker) index(e *ast.IndexExpression, s *scope) Type {
+	left := prune(c.expr(e.Left, s))
+	index := c.expr(e.Index, s)
+
+	switch l := left.(type) {
+	case *Array:
+		if !c.unify(Int, index) {
+			c.errorf(e.Index, "cannot use %s as int in index of %s", index, e.Left)
+		}
+		return l.Element
+	case *Hash:
+		if !c.unify(l.Key, index) {
+			c.errorf(e.Index, "cannot use %s as %s in index of %s", index, l.Key, e.Left)
+		}
+		return l.Value
+	case *Variable:
+		return Any
+	}
+
+	if left == String {
+		if !c.unify(Int, index) {
+			c.errorf(e.Index, "cannot use %s as int in index of %s", index, e.Left)
+		}
+		return String
+	}
+	if left != Any {
+		c.errorf(e.Left, "cannot index %s of type %s", e.Left, left)
+	}
+	return Any
+}
+
+// typeOf returns the type an annotation stands for.
+func (c *checker) typeOf(te ast.TypeExpression) Type {
+	switch te := te.(type) {
+	case *ast.NamedType:
+		if t, ok := basicTypes[te.Name]; ok {
+			return t
+		}
+		c.errorf(te, "unknown type %s", te.Name)
+		return Any
+	case *ast.ArrayType:
+		return &Array{Element: c.typeOf(te.Element)}
+	case *ast.HashType:
+		return &Hash{Key: c.typeOf(te.Key), Value: c.typeOf(te.Value)}
+	case *ast.FunctionType:
+		params := make([]Type, len(te.Parameters))
+		for i, p := range te.Parameters {
+			params[i] = c.typeOf(p)
+		}
+		return &Function{Params: params, Return: c.typeOf(te.ReturnType)}
+	default:
+		return Any
+	}
+}
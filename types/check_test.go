@@ -0,0 +1,198 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OlyaIvanovs/interpreter_in_go/ast"
+	"github.com/OlyaIvanovs/interpreter_in_go/lexer"
+	"github.com/OlyaIvanovs/interpreter_in_go/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x: int = 5; x + 1;", nil},
+		{`let x: int = "five";`, []string{"1:14: cannot use string as int in let x"}},
+		{`1 + "a";`, []string{"1:3: invalid operation: int + string"}},
+		{`"a" - "b";`, []string{"1:5: invalid operation: string - string"}},
+		{`"a" + "b"; "a" < "b"; 1 + 2 * 3 / 4; 1 - -2;`, nil},
+		{"-true;", []string{"1:1: invalid operation: -bool"}},
+		{"true < false;", []string{"1:6: invalid operation: bool < bool"}},
+		{`1 == "a";`, []string{"1:3: invalid operation: int == string (mismatched types)"}},
+		{"[1] == [2]; {} != {}; 1 == null;", nil},
+
+		// unannotated code is dynamic
+		{`let f = fn(a, b) { a + b }; f(1, "a"); f("a", true);`, nil},
+		{`let f = fn(a) { a }; f(1) + "a";`, nil},
+		{`import "lib" as l; l.x + 1; l.f("a") - 1;`, nil},
+
+		// annotated parameters and return values
+		{`let f = fn(a: string, b: [int]) -> bool { len(b) > len(a) }; f("a", [1, 2]);`, nil},
+		{`let f = fn(a: string) { a }; f(1);`, []string{"1:32: cannot use int as string in argument 1 to f"}},
+		{`let f = fn(a: string) { a }; f("a") + 1;`, []string{"1:37: invalid operation: string + int"}},
+		{"let f = fn(a: int, b: int) { a + b }; f(1);",
+			[]string{"1:39: wrong number of arguments in call to f: got 1, want 2"}},
+		{`let f = fn(a: int, b: int = a) { a + b }; f(1); f(1, "x");`,
+			[]string{"1:54: cannot use string as int in argument 2 to f"}},
+		{`fn(a: int = "x") { a };`, []string{"1:13: cannot use string as int in default of a"}},
+		{`let f = fn(a: string, ...r: [int]) { r }; f("a", 1, 2, "b");`,
+			[]string{"1:56: cannot use string as int in argument 4 to f"}},
+		{"fn(...r: int) { r };", []string{"1:10: cannot use int as the type of rest parameter r, which is an array"}},
+		{"let f = fn(a: int, b: int) { a + b }; f(...[1, 2]); f(...1);",
+			[]string{"1:58: cannot spread 1 of type int in call to f"}},
+		{`fn() -> int { "a" };`, []string{"1:15: cannot use string as int in return value"}},
+		{`fn(x) -> int { if (x) { return "a"; } 1 };`,
+			[]string{"1:32: cannot use string as int in return statement"}},
+		{"fn(x) -> int { if (x) { return 1; } else { return 2; } };", nil},
+		{"fn() -> int { let x = 1; };", []string{"1:1: cannot use null as int in return value"}},
+		{"let f: fn(int) -> int = fn(x: int) -> int { x }; f(1) + 1;", nil},
+		{`let f: fn(int) -> int = fn(x: string) { x };`,
+			[]string{"1:25: cannot use fn(string) -> string as fn(int) -> int in let f"}},
+		{"let x: number = 1;", []string{"1:8: unknown type number"}},
+
+		// inference
+		{`let f = fn() { 1 }; f() + "a";`, []string{"1:25: invalid operation: int + string"}},
+		{`let f = fn(x) { if (x) { return "a"; } "b" }; f(1) - 1;`,
+			[]string{"1:52: invalid operation: string - int"}},
+		{`let f = fn(x) { if (x) { 1 } else { "b" } }; f(1) - 1;`, nil},
+		{`let xs = [1, 2]; xs[0] + "a";`, []string{"1:24: invalid operation: int + string"}},
+		{`let xs = [1, "a"]; xs[0] + 1;`, nil},
+		{`let h = {"a": 1}; h["a"] + 1; h[1];`, []string{"1:33: cannot use int as string in index of h"}},
+		{`"abc"[0] + "d"; "abc"["a"];`, []string{`1:23: cannot use string as int in index of "abc"`}},
+		{"1[0];", []string{"1:1: cannot index 1 of type int"}},
+		{`test "adds" { let x: int = "a"; return 1; }`, []string{"1:28: cannot use string as int in let x"}},
+		{"let x = 1; x(2);", []string{"1:12: cannot call x of type int"}},
+
+		// polymorphism
+		{`let xs = []; push(xs, 1); push(xs, "a");`, nil},
+		{`let id = fn(x: any) { x }; let pair = fn(a: int, b: string) { a }; pair(first([1]), first(["a"]));`, nil},
+		{`first([1]) + "a";`, []string{"1:12: invalid operation: int + string"}},
+		{`push([1], "a");`, []string{"1:11: cannot use string as int in argument 2 to push"}},
+		{`keys({"a": 1})[0] + 1;`, []string{"1:19: invalid operation: string + int"}},
+		{`split("a b"); split("a,b", ","); split(1);`,
+			[]string{"1:40: cannot use int as string in argument 1 to split"}},
+		{"substr();", []string{"1:1: wrong number of arguments in call to substr: got 0, want 3"}},
+		{`puts(1, "a"); merge({}, {}, {});`, nil},
+		{`filter(["a"], fn(x: int) { x > 0 });`, []string{"1:15: cannot use fn(int) -> bool as fn(string) -> any in argument 2 to filter"}},
+
+		// recursion
+		{"let fact = fn(n: int) -> int { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(3);", nil},
+		{`let fact = fn(n: int) -> int { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact("a");`,
+			[]string{"1:82: cannot use string as int in argument 1 to fact"}},
+		{"let loop = fn(n) { if (n > 0) { loop(n - 1) } else { 0 } }; loop(3);", nil},
+		{`fn fact(n: int) -> int { if (n < 2) { 1 } else { n * fact(n - 1) } } fact("a");`,
+			[]string{"1:75: cannot use string as int in argument 1 to fact"}},
+		{"twice(1); fn twice(x: int) -> int { x * 2 }", nil},
+
+		// patterns
+		{`let [a, b] = [1, 2]; a + "x";`, []string{"1:24: invalid operation: int + string"}},
+		{`let [a, ...r] = ["x"]; r[0] - 1;`, []string{"1:29: invalid operation: string - int"}},
+		{`let {name, age: years} = {"name": 1}; name + years;`, nil},
+		{`let {a, ...h} = {"a": "x"}; h["b"] - 1;`, []string{"1:36: invalid operation: string - int"}},
+		{"let [a] = 1;", []string{"1:5: cannot bind int to [a], which takes an array"}},
+		{"let {a} = [1];", []string{"1:5: cannot bind [int] to {a}, which takes a hash"}},
+		{"let {a} = {1: 2};", []string{"1:5: cannot bind {int: int} to {a}, whose keys are not strings"}},
+		{`let [a = "x"] = [1];`, []string{`1:10: cannot use string as int in default of a`}},
+		{"let [[a] = 1] = [[2]];", []string{"1:12: cannot use int as [int] in default of an array pattern"}},
+		{`let [a: string] = [1];`, []string{"1:6: cannot use int as string in pattern for a"}},
+		{`let f = fn([a, b], {c} = {}) { a + b + c }; f([1, 2]); f(1, 2, 3);`,
+			[]string{"1:56: wrong number of arguments in call to f: got 3, want 2"}},
+
+		// match
+		{`match (1) { 0 => "a", n => "b" } + 1;`, []string{"1:34: invalid operation: string + int"}},
+		{`match (1) { "a" => 1, _ => 2 };`, []string{`1:13: cannot match int against "a", of type string`}},
+		{`let f = fn(x) { match (x) { 1 => 1, "a" => 2, [y] => y, _ => 3 } }; f(1);`, nil},
+		{`match ([1]) { [a] => a + "x", [a, ...r] if r => a, {k} => k };`, []string{
+			"1:24: invalid operation: int + string",
+			"1:52: cannot bind [int] to {k}, which takes a hash",
+		}},
+		{`match ({"a": 1}) { {a: "x"} => 1, {b} => b - 1, _ => 0 };`,
+			[]string{`1:24: cannot match int against "x", of type string`}},
+
+		// macros are left alone
+		{`let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) }; unless(1 + "a", 2);`, nil},
+		{`quote(1 + "a");`, nil},
+	}
+
+	for _, tt := range tests {
+		errs, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: %s", tt.input, err)
+			continue
+		}
+
+		var got []string
+		for _, e := range errs {
+			got = append(got, e.Error())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong errors for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1", "int"},
+		{`"a" + "b"`, "string"},
+		{"[1, 2]", "[int]"},
+		{`[1, "a"]`, "[any]"},
+		{`{"a": [true]}`, "{string: [bool]}"},
+		{"fn(a: int, b) { a }", "fn(int, any) -> int"},
+		{"fn(x) { if (x) { 1 } }", "fn(any) -> any"},
+		{"fn(x) { if (x) { return 1; } 2 }", "fn(any) -> int"},
+		{"rest([[1]])", "[[int]]"},
+		{"len", "fn(any) -> int"},
+		{`1 && "a" || []`, "bool"},
+		{"filter([1, 2], fn(x) { x > 1 })", "[int]"},
+		{"fn(n) { n + 1 }", "fn(any) -> any"},
+		{"fn(a: int, b = 1, ...c: [string]) { c }", "fn(int, any, ...string) -> [string]"},
+		{"(fn go(n: int) { if (n < 1) { 0 } else { go(n - 1) } })", "fn(int) -> int"},
+		{"match ([1]) { [a, ...r] if a > 0 => r, _ => [] }", "[int]"},
+		{`match (1) { 1 => 1, _ => "a" }`, "any"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		result := Check(program)
+		if len(result.Errors) != 0 {
+			t.Errorf("unexpected errors for %q: %v", tt.input, result.Errors)
+			continue
+		}
+
+		e := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if got := result.Types[e].String(); got != tt.expected {
+			t.Errorf("wrong type for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCheckLetTypes(t *testing.T) {
+	program := parse(t, "let xs = []; let ys = push(xs, 1); let f = fn(x: string) { [x] };")
+	result := Check(program)
+
+	expected := []string{"[t1]", "[int]", "fn(string) -> [string]"}
+	for i, stmt := range program.Statements {
+		name := stmt.(*ast.LetStatement).Name.(*ast.Identifier)
+		if got := result.Types[name].String(); got != expected[i] {
+			t.Errorf("wrong type for %s. expected=%q, got=%q", name, expected[i], got)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
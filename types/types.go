@@ -0,0 +1,342 @@
+// Package types checks Monkey programs against their optional type
+// annotations before they are run.
+//
+// Checking is gradual: unannotated parameters and anything the checker
+// cannot see through, such as imported modules, have type any, which is
+// compatible with every other type. Everything else is inferred in the
+// style of Hindley-Milner, with let bindings generalized so that, for
+// example, an empty array can be pushed to with values of any type.
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A Type is the type of a Monkey value.
+type Type interface {
+	String() string
+}
+
+// Basic is a type without components, such as int or any.
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+// The basic types. Any is the type of values that are only known at
+// runtime.
+var (
+	Int    = &Basic{Name: "int"}
+	Float  = &Basic{Name: "float"}
+	String = &Basic{Name: "string"}
+	Bool   = &Basic{Name: "bool"}
+	Null   = &Basic{Name: "null"}
+	Any    = &Basic{Name: "any"}
+)
+
+// macro is the type of macro literals, which can only be called.
+var macro = &Basic{Name: "macro"}
+
+// basicTypes maps the names usable in annotations to their types.
+var basicTypes = map[string]*Basic{
+	"int":    Int,
+	"float":  Float,
+	"string": String,
+	"bool":   Bool,
+	"null":   Null,
+	"any":    Any,
+}
+
+// Array is the type of arrays, e.g. [int].
+type Array struct {
+	Element Type
+}
+
+func (a *Array) String() string { return "[" + a.Element.String() + "]" }
+
+// Hash is the type of hashes, e.g. {string: int}.
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string {
+	return "{" + h.Key.String() + ": " + h.Value.String() + "}"
+}
+
+// Function is the type of functions, e.g. fn(int, string) -> bool.
+type Function struct {
+	Params []Type
+	Return Type
+
+	// optional is the number of trailing parameters that may be left out,
+	// which builtins and parameters with defaults have.
+	optional int
+
+	// rest is the type of each argument after the parameters, or nil if
+	// the function takes no more.
+	rest Type
+}
+
+func (f *Function) String() string {
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+	if f.rest != nil {
+		params = append(params, "..."+f.rest.String())
+	}
+
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.Return.String()
+}
+
+// A Variable stands for a type that has not been inferred yet.
+type Variable struct {
+	id       int
+	instance Type
+}
+
+func (v *Variable) String() string {
+	if v.instance != nil {
+		return v.instance.String()
+	}
+	return "t" + strconv.Itoa(v.id)
+}
+
+// prune follows bound variables to the type they stand for.
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Variable)
+		if !ok || v.instance == nil {
+			return t
+		}
+		t = v.instance
+	}
+}
+
+// resolve returns t with all bound variables replaced by their types.
+func resolve(t Type) Type {
+	switch t := prune(t).(type) {
+	case *Array:
+		return &Array{Element: resolve(t.Element)}
+	case *Hash:
+		return &Hash{Key: resolve(t.Key), Value: resolve(t.Value)}
+	case *Function:
+		params := make([]Type, len(t.Params))
+		for i, p := range t.Params {
+			params[i] = resolve(p)
+		}
+		f := &Function{Params: params, Return: resolve(t.Return), optional: t.optional}
+		if t.rest != nil {
+			f.rest = resolve(t.rest)
+		}
+		return f
+	default:
+		return t
+	}
+}
+
+// occurs reports whether v appears in t.
+func occurs(v *Variable, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Variable:
+		return t == v
+	case *Array:
+		return occurs(v, t.Element)
+	case *Hash:
+		return occurs(v, t.Key) || occurs(v, t.Value)
+	case *Function:
+		for _, p := range t.Params {
+			if occurs(v, p) {
+				return true
+			}
+		}
+		if t.rest != nil && occurs(v, t.rest) {
+			return true
+		}
+		return occurs(v, t.Return)
+	default:
+		return false
+	}
+}
+
+// freeVariables adds the unbound variables in t to free.
+func freeVariables(t Type, free map[*Variable]bool) {
+	switch t := prune(t).(type) {
+	case *Variable:
+		free[t] = true
+	case *Array:
+		freeVariables(t.Element, free)
+	case *Hash:
+		freeVariables(t.Key, free)
+		freeVariables(t.Value, free)
+	case *Function:
+		for _, p := range t.Params {
+			freeVariables(p, free)
+		}
+		if t.rest != nil {
+			freeVariables(t.rest, free)
+		}
+		freeVariables(t.Return, free)
+	}
+}
+
+// substitute returns t with the variables in m replaced.
+func substitute(t Type, m map[*Variable]Type) Type {
+	switch t := prune(t).(type) {
+	case *Variable:
+		if r, ok := m[t]; ok {
+			return r
+		}
+		return t
+	case *Array:
+		return &Array{Element: substitute(t.Element, m)}
+	case *Hash:
+		return &Hash{Key: substitute(t.Key, m), Value: substitute(t.Value, m)}
+	case *Function:
+		params := make([]Type, len(t.Params))
+		for i, p := range t.Params {
+			params[i] = substitute(p, m)
+		}
+		f := &Function{Params: params, Return: substitute(t.Return, m), optional: t.optional}
+		if t.rest != nil {
+			f.rest = substitute(t.rest, m)
+		}
+		return f
+	default:
+		return t
+	}
+}
+
+func isNumber(t Type) bool { return t == Int || t == Float }
+
+// A scheme is a type that is polymorphic in some of its variables, which
+// are replaced by fresh ones each time a name bound to it is used.
+type scheme struct {
+	vars []*Variable
+	typ  Type
+}
+
+// unifier makes types equal by binding variables, keeping a trail of the
+// bindings so that a failed attempt can be undone.
+type unifier struct {
+	trail  []*Variable
+	nextID int
+}
+
+func (u *unifier) fresh() *Variable {
+	u.nextID++
+	return &Variable{id: u.nextID}
+}
+
+// unify makes a and b the same type if they are compatible, leaving them
+// untouched otherwise.
+func (u *unifier) unify(a, b Type) bool {
+	mark := len(u.trail)
+	if u.unifyTypes(a, b) {
+		return true
+	}
+	u.undo(mark)
+	return false
+}
+
+// compatible reports whether a and b could be unified, without binding
+// anything.
+func (u *unifier) compatible(a, b Type) bool {
+	mark := len(u.trail)
+	ok := u.unifyTypes(a, b)
+	u.undo(mark)
+	return ok
+}
+
+func (u *unifier) unifyTypes(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == b {
+		return true
+	}
+	if v, ok := a.(*Variable); ok {
+		return u.bind(v, b)
+	}
+	if v, ok := b.(*Variable); ok {
+		return u.bind(v, a)
+	}
+	if a == Any || b == Any {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && u.unifyTypes(a.Element, b.Element)
+	case *Hash:
+		b, ok := b.(*Hash)
+		return ok && u.unifyTypes(a.Key, b.Key) && u.unifyTypes(a.Value, b.Value)
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok || len(a.Params) != len(b.Params) || (a.rest == nil) != (b.rest == nil) {
+			return false
+		}
+		for i := range a.Params {
+			if !u.unifyTypes(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		if a.rest != nil && !u.unifyTypes(a.rest, b.rest) {
+			return false
+		}
+		return u.unifyTypes(a.Return, b.Return)
+	default:
+		return false
+	}
+}
+
+func (u *unifier) bind(v *Variable, t Type) bool {
+	if occurs(v, t) {
+		return false
+	}
+	v.instance = t
+	u.trail = append(u.trail, v)
+	return true
+}
+
+func (u *unifier) undo(mark int) {
+	for _, v := range u.trail[mark:] {
+		v.instance = nil
+	}
+	u.trail = u.trail[:mark]
+}
+
+// join returns the type that all of ts can be given, or Any if they
+// disagree. Nil entries, from code that never completes, are skipped and
+// the result is nil if there are no others.
+func (u *unifier) join(ts ...Type) Type {
+	var result Type
+	for _, t := range ts {
+		if t == nil {
+			continue
+		}
+		if prune(t) == Any {
+			return Any
+		}
+		if result == nil {
+			result = t
+		} else if !u.unify(result, t) {
+			return Any
+		}
+	}
+	return result
+}
+
+func (u *unifier) instantiate(s *scheme) Type {
+	if len(s.vars) == 0 {
+		return s.typ
+	}
+
+	m := map[*Variable]Type{}
+	for _, v := range s.vars {
+		m[v] = u.fresh()
+	}
+	return substitute(s.typ, m)
+}
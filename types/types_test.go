@@ -0,0 +1,88 @@
+package types
+
+import "testing"
+
+func TestTypeString(t *testing.T) {
+	tests := []struct {
+		typ      Type
+		expected string
+	}{
+		{Int, "int"},
+		{&Array{Element: String}, "[string]"},
+		{&Hash{Key: String, Value: &Array{Element: Bool}}, "{string: [bool]}"},
+		{&Function{Params: []Type{Int, Any}, Return: Null}, "fn(int, any) -> null"},
+		{&Function{Return: &Function{Return: Float}}, "fn() -> fn() -> float"},
+		{&Variable{id: 3}, "t3"},
+		{&Variable{id: 3, instance: Int}, "int"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.expected {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestUnify(t *testing.T) {
+	u := &unifier{}
+	a, b := u.fresh(), u.fresh()
+
+	tests := []struct {
+		left, right Type
+		expected    bool
+	}{
+		{Int, Int, true},
+		{Int, String, false},
+		{Int, Any, true},
+		{&Array{Element: Any}, &Array{Element: Bool}, true},
+		{&Array{Element: Int}, &Hash{Key: Int, Value: Int}, false},
+		{&Function{Params: []Type{Int}, Return: Int}, &Function{Return: Int}, false},
+		{a, &Array{Element: a}, false},
+		{&Hash{Key: a, Value: b}, &Hash{Key: String, Value: Int}, true},
+		{&Array{Element: a}, &Array{Element: Int}, false},
+		{b, Int, true},
+	}
+
+	for i, tt := range tests {
+		if got := u.unify(tt.left, tt.right); got != tt.expected {
+			t.Errorf("tests[%d] - unify(%s, %s) wrong. expected=%t, got=%t", i, tt.left, tt.right, tt.expected, got)
+		}
+	}
+
+	if prune(a) != String || prune(b) != Int {
+		t.Errorf("wrong bindings. expected=string, int, got=%s, %s", a, b)
+	}
+}
+
+func TestUnifyUndo(t *testing.T) {
+	u := &unifier{}
+	a := u.fresh()
+
+	if u.unify(&Hash{Key: a, Value: Int}, &Hash{Key: String, Value: Bool}) {
+		t.Fatalf("unify succeeded on mismatched values")
+	}
+	if prune(a) != a {
+		t.Errorf("failed unify left a bound to %s", prune(a))
+	}
+}
+
+func TestJoin(t *testing.T) {
+	u := &unifier{}
+
+	tests := []struct {
+		types    []Type
+		expected Type
+	}{
+		{[]Type{Int, Int}, Int},
+		{[]Type{Int, String}, Any},
+		{[]Type{Int, nil, Int}, Int},
+		{[]Type{nil, nil}, nil},
+		{[]Type{Int, Any}, Any},
+	}
+
+	for i, tt := range tests {
+		if got := u.join(tt.types...); got != tt.expected {
+			t.Errorf("tests[%d] - wrong join. expected=%v, got=%v", i, tt.expected, got)
+		}
+	}
+}